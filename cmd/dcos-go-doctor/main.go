@@ -0,0 +1,256 @@
+// Command dcos-go-doctor exercises the dcos-go library end-to-end on a DC/OS
+// node and prints a pass/fail report. It is meant for debugging misconfigured
+// nodes and for validating the library against a real cluster.
+//
+// Usage:
+//
+//	dcos-go-doctor -iam-config /run/dcos/etc/3dt/master_service_account.json
+//
+// Configuration not given via flags is resolved with dcos/config, so the
+// DC/OS environment variables and node defaults apply.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-go/dcos"
+	"github.com/dcos/dcos-go/dcos/config"
+	"github.com/dcos/dcos-go/dcos/http/transport"
+	"github.com/dcos/dcos-go/dcos/nodeutil"
+	"github.com/dcos/dcos-go/zkstore"
+)
+
+var (
+	flagIAMConfig      = flag.String("iam-config", "", "Path to IAM config")
+	flagCACertPath     = flag.String("ca-cert-path", "", "Path to CA certificate")
+	flagRole           = flag.String("role", "", "Node role, detected if empty")
+	flagDetectIP       = flag.String("detect-ip", dcos.GetFileDetectIPLocation(), "Path to the detect_ip script")
+	flagAdminRouterURL = flag.String("adminrouter-url", "", "Admin Router URL, defaults to the local node")
+	flagZKAddr         = flag.String("zk-addr", "", "ZooKeeper address, defaults to the local node on masters")
+	flagTimeout        = flag.Duration("timeout", 10*time.Second, "Timeout for every check")
+)
+
+// result is the outcome of a single check.
+type result struct {
+	name   string
+	status string
+	detail string
+}
+
+const (
+	statusPass = "PASS"
+	statusFail = "FAIL"
+	statusSkip = "SKIP"
+)
+
+// doctor runs the checks and collects their results. Later checks use the state
+// gathered by earlier ones and are skipped if it is missing.
+type doctor struct {
+	results []result
+
+	cfg    *config.Config
+	client *http.Client
+	node   nodeutil.NodeInfo
+	ip     net.IP
+}
+
+func (d *doctor) pass(name, format string, args ...interface{}) {
+	d.results = append(d.results, result{name, statusPass, fmt.Sprintf(format, args...)})
+}
+
+func (d *doctor) fail(name string, err error) {
+	d.results = append(d.results, result{name, statusFail, err.Error()})
+}
+
+func (d *doctor) skip(name, reason string) {
+	d.results = append(d.results, result{name, statusSkip, reason})
+}
+
+func main() {
+	flag.Parse()
+
+	d := &doctor{}
+	d.checkConfig()
+	d.checkTransport()
+	d.checkNode()
+	d.checkAdminRouter()
+	d.checkMesos()
+	d.checkZK()
+
+	failed := false
+	for _, r := range d.results {
+		fmt.Printf("[%s] %-12s %s\n", r.status, r.name, r.detail)
+		failed = failed || r.status == statusFail
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func (d *doctor) checkConfig() {
+	var opts []config.Option
+	if *flagIAMConfig != "" {
+		opts = append(opts, config.OptionIAMConfigPath(*flagIAMConfig))
+	}
+	if *flagCACertPath != "" {
+		opts = append(opts, config.OptionCACertificatePath(*flagCACertPath))
+	}
+	if *flagRole != "" {
+		opts = append(opts, config.OptionRole(*flagRole))
+	}
+
+	cfg, err := config.Load(opts...)
+	if err != nil {
+		d.fail("config", err)
+		return
+	}
+	if cfg.Role == "" {
+		d.fail("config", fmt.Errorf("could not detect the node role, use -role"))
+		return
+	}
+	d.cfg = cfg
+	d.pass("config", "role=%s ca=%q iam=%q statsd=%q", cfg.Role, cfg.CACertificatePath, cfg.IAMConfigPath,
+		cfg.StatsdAddr)
+}
+
+func (d *doctor) checkTransport() {
+	if d.cfg == nil {
+		d.skip("login", "no configuration")
+		return
+	}
+
+	// NewTransport logs in with the service account right away, if one is configured.
+	tr, err := transport.NewTransport(d.cfg.TransportOptions()...)
+	if err != nil {
+		d.fail("login", err)
+		return
+	}
+	d.client = &http.Client{Transport: tr, Timeout: *flagTimeout}
+
+	if d.cfg.IAMConfigPath == "" {
+		d.skip("login", "no IAM config, requests are unauthenticated")
+		return
+	}
+	d.pass("login", "obtained a token for the service account in %s", d.cfg.IAMConfigPath)
+}
+
+func (d *doctor) checkNode() {
+	if d.client == nil {
+		d.skip("detect_ip", "no HTTP client")
+		return
+	}
+
+	node, err := nodeutil.NewNodeInfo(d.client, d.cfg.Role, nodeutil.OptionDetectIP(*flagDetectIP))
+	if err != nil {
+		d.fail("detect_ip", err)
+		return
+	}
+	d.node = node
+
+	ip, err := node.DetectIP()
+	if err != nil {
+		d.fail("detect_ip", err)
+		return
+	}
+	d.ip = ip
+	d.pass("detect_ip", "%s", ip)
+
+	if d.cfg.Role != dcos.RoleMaster {
+		return
+	}
+	leader, err := node.IsLeader()
+	if err != nil {
+		d.fail("leader", err)
+		return
+	}
+	d.pass("leader", "leading master: %t", leader)
+}
+
+func (d *doctor) checkAdminRouter() {
+	url := *flagAdminRouterURL
+	if url == "" {
+		if d.ip == nil {
+			d.skip("adminrouter", "node IP unknown, use -adminrouter-url")
+			return
+		}
+		port := dcos.PortAdminrouterAgentHTTP
+		if d.cfg.Role == dcos.RoleMaster {
+			port = dcos.PortAdminrouterHTTP
+		}
+		url = "http://" + net.JoinHostPort(d.ip.String(), strconv.Itoa(port))
+	}
+	if d.client == nil {
+		d.skip("adminrouter", "no HTTP client")
+		return
+	}
+
+	url = strings.TrimSuffix(url, "/") + "/system/health/v1"
+	resp, err := d.client.Get(url)
+	if err != nil {
+		d.fail("adminrouter", err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		d.fail("adminrouter", fmt.Errorf("GET %s returned response code %d", url, resp.StatusCode))
+		return
+	}
+	d.pass("adminrouter", "GET %s returned %d", url, resp.StatusCode)
+}
+
+func (d *doctor) checkMesos() {
+	if d.node == nil || d.ip == nil {
+		d.skip("mesos", "node info unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *flagTimeout)
+	defer cancel()
+	id, err := d.node.MesosID(ctx)
+	if err != nil {
+		d.fail("mesos", err)
+		return
+	}
+	d.pass("mesos", "mesos id %s", id)
+}
+
+func (d *doctor) checkZK() {
+	addr := *flagZKAddr
+	if addr == "" {
+		if d.cfg == nil || d.cfg.Role != dcos.RoleMaster || d.ip == nil {
+			d.skip("zookeeper", "not a master, use -zk-addr")
+			return
+		}
+		addr = net.JoinHostPort(d.ip.String(), "2181")
+	}
+
+	connector := zkstore.NewConnection([]string{addr}, zkstore.ConnectionOpts{
+		ConnectTimeout:        *flagTimeout,
+		InitialSessionTimeout: *flagTimeout,
+	})
+	conn, err := connector.Connect()
+	if err != nil {
+		d.fail("zookeeper", err)
+		return
+	}
+	defer connector.Close()
+
+	children, _, err := conn.Children("/")
+	if err != nil {
+		d.fail("zookeeper", err)
+		return
+	}
+	d.pass("zookeeper", "connected to %s, %d top level znodes", addr, len(children))
+}
@@ -0,0 +1,330 @@
+// Command zkstore inspects and manages data stored with the zkstore package.
+//
+// Usage:
+//
+//	zkstore [flags] <command> [arguments]
+//
+// Commands:
+//
+//	categories                         list all categories under the base path
+//	list <category>                    list the item names of a category
+//	get <category> <name> [variant]    write item data to stdout
+//	put <category> <name> [variant]    store data read from stdin
+//	delete <category> <name> [variant] delete an item, or one of its variants
+//	variants <category> <name>         list the variants of an item
+//	export <category>                  write all items and variants of a category to stdout as JSON
+//	import                             store the items of an export read from stdin
+//	rebalance <category> <buckets>     move the items of a category into a new number of buckets
+//
+// The store flags (-base-path, -buckets, -buckets-znode-name) must match the
+// configuration of the service that owns the data, otherwise items will not be
+// found. With -dry-run, put, delete and import print the modifications they
+// would make instead of making them.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dcos/dcos-go/dcoslog"
+	"github.com/dcos/dcos-go/zkstore"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+var (
+	flagZK               = flag.String("zk", "127.0.0.1:2181", "Comma separated list of ZooKeeper addresses")
+	flagZKAuth           = flag.String("zk-auth", "", "ZooKeeper digest credentials in the form user:password")
+	flagBasePath         = flag.String("base-path", "", "Store base path")
+	flagBuckets          = flag.Int("buckets", zkstore.DefaultNumHashBuckets, "Number of hash buckets")
+	flagBucketsZnodeName = flag.String("buckets-znode-name", zkstore.DefaultBucketsZnodeName, "Name of the buckets znode")
+	flagDryRun           = flag.Bool("dry-run", false, "Print modifications instead of performing them")
+)
+
+// exportedItem is the JSON representation of an item used by export and import.
+type exportedItem struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Variant  string `json:"variant,omitempty"`
+	Data     []byte `json:"data"`
+}
+
+type command struct {
+	usage string
+	nargs []int
+	run   func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"categories": {"categories", []int{0}, (*cli).categories},
+	"list":       {"list <category>", []int{1}, (*cli).list},
+	"get":        {"get <category> <name> [variant]", []int{2, 3}, (*cli).get},
+	"put":        {"put <category> <name> [variant]", []int{2, 3}, (*cli).put},
+	"delete":     {"delete <category> <name> [variant]", []int{2, 3}, (*cli).delete},
+	"variants":   {"variants <category> <name>", []int{2}, (*cli).variants},
+	"export":     {"export <category>", []int{1}, (*cli).export},
+	"import":     {"import", []int{0}, (*cli).importItems},
+	"rebalance":  {"rebalance <category> <buckets>", []int{2}, (*cli).rebalance},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [arguments]\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	args := flag.Args()[1:]
+	if !ok || !validArgs(cmd, args) {
+		usage()
+		os.Exit(2)
+	}
+
+	c, err := newCLI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer c.close()
+
+	if err := cmd.run(c, args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func validArgs(cmd command, args []string) bool {
+	for _, n := range cmd.nargs {
+		if len(args) == n {
+			return true
+		}
+	}
+	return false
+}
+
+type cli struct {
+	connector zkstore.Connector
+	conn      *zk.Conn
+	store     *zkstore.Store
+}
+
+func newCLI() (*cli, error) {
+	opts := zkstore.ConnectionOpts{}
+	if *flagZKAuth != "" {
+		opts.Auth.Schema = "digest"
+		opts.Auth.Secret = []byte(*flagZKAuth)
+	}
+	connector := zkstore.NewConnection(strings.Split(*flagZK, ","), opts)
+	conn, err := connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	storeOpts := []zkstore.StoreOpt{
+		zkstore.OptBasePath(*flagBasePath),
+		zkstore.OptNumHashBuckets(*flagBuckets),
+		zkstore.OptBucketsZnodeName(*flagBucketsZnodeName),
+	}
+	if *flagDryRun {
+		storeOpts = append(storeOpts,
+			zkstore.OptLogger(dcoslog.NewStdLogger(log.New(os.Stderr, "", 0), false)),
+			zkstore.OptDryRun())
+	}
+	store, err := zkstore.NewStore(zkstore.ExistingConnection(conn), storeOpts...)
+	if err != nil {
+		connector.Close()
+		return nil, err
+	}
+	return &cli{connector: connector, conn: conn, store: store}, nil
+}
+
+func (c *cli) close() {
+	c.store.Close()
+	c.connector.Close()
+}
+
+func ident(args []string) zkstore.Ident {
+	id := zkstore.Ident{Location: zkstore.Location{Category: args[0], Name: args[1]}}
+	if len(args) > 2 {
+		id.Variant = args[2]
+	}
+	return id
+}
+
+// categories walks the znodes under the base path and prints every path that
+// contains a buckets znode. The Store API has no way to enumerate categories.
+func (c *cli) categories(args []string) error {
+	root := path.Join("/", *flagBasePath)
+	var walk func(p string) error
+	walk = func(p string) error {
+		children, _, err := c.conn.Children(p)
+		if err == zk.ErrNoNode {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sort.Strings(children)
+		for _, child := range children {
+			if child == *flagBucketsZnodeName {
+				fmt.Println(strings.TrimPrefix(strings.TrimPrefix(p, root), "/"))
+				continue
+			}
+			if strings.HasPrefix(child, ".") {
+				// chunks, locks and elections; not categories
+				continue
+			}
+			if err := walk(path.Join(p, child)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
+
+func (c *cli) list(args []string) error {
+	locations, err := c.store.List(args[0])
+	if err != nil {
+		return err
+	}
+	sort.Slice(zkstore.LocationsByName(locations))
+	for _, l := range locations {
+		fmt.Println(l.Name)
+	}
+	return nil
+}
+
+func (c *cli) get(args []string) error {
+	item, err := c.store.Get(ident(args))
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(item.Data)
+	return err
+}
+
+func (c *cli) put(args []string) error {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	id, err := c.store.Put(zkstore.Item{Ident: ident(args), Data: data})
+	if err != nil {
+		return err
+	}
+	version, _ := id.Version.Value()
+	fmt.Fprintf(os.Stderr, "stored %v at version %d\n", id.Location, version)
+	return nil
+}
+
+func (c *cli) delete(args []string) error {
+	return c.store.Delete(ident(args))
+}
+
+func (c *cli) variants(args []string) error {
+	variants, err := c.store.Variants(zkstore.Location{Category: args[0], Name: args[1]})
+	if err != nil {
+		return err
+	}
+	sort.Strings(variants)
+	for _, v := range variants {
+		fmt.Println(v)
+	}
+	return nil
+}
+
+func (c *cli) export(args []string) error {
+	locations, err := c.store.List(args[0])
+	if err != nil {
+		return err
+	}
+	sort.Slice(zkstore.LocationsByName(locations))
+
+	items := []exportedItem{}
+	for _, l := range locations {
+		variants, err := c.store.Variants(l)
+		if err == zkstore.ErrNotFound {
+			continue // deleted while exporting
+		}
+		if err != nil {
+			return err
+		}
+		sort.Strings(variants)
+		for _, v := range append([]string{""}, variants...) {
+			item, err := c.store.Get(zkstore.Ident{Location: l, Variant: v})
+			if err == zkstore.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			items = append(items, exportedItem{Category: l.Category, Name: l.Name, Variant: v, Data: item.Data})
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
+
+func (c *cli) importItems(args []string) error {
+	var items []exportedItem
+	if err := json.NewDecoder(os.Stdin).Decode(&items); err != nil {
+		return fmt.Errorf("could not parse export: %s", err)
+	}
+	if len(items) == 0 {
+		return errors.New("export contains no items")
+	}
+	for _, item := range items {
+		_, err := c.store.Put(zkstore.Item{
+			Ident: zkstore.Ident{
+				Location: zkstore.Location{Category: item.Category, Name: item.Name},
+				Variant:  item.Variant,
+			},
+			Data: item.Data,
+		})
+		if err != nil {
+			return fmt.Errorf("could not import %s/%s %s: %s", item.Category, item.Name, item.Variant, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "imported %d items\n", len(items))
+	return nil
+}
+
+func (c *cli) rebalance(args []string) error {
+	buckets, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid bucket count: %s", args[1])
+	}
+	if err := c.store.Rebalance(args[0], buckets); err != nil {
+		return err
+	}
+	if !*flagDryRun {
+		fmt.Fprintf(os.Stderr, "rebalanced %s into %d buckets, use -buckets %d from now on\n", args[0], buckets, buckets)
+	}
+	return nil
+}
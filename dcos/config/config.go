@@ -0,0 +1,300 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dcos/dcos-go/dcos"
+	"github.com/dcos/dcos-go/dcos/http/transport"
+	"github.com/pkg/errors"
+)
+
+// DC/OS-conventional environment variables read by Load.
+const (
+	// EnvIAMConfigPath points to a service account JSON file.
+	EnvIAMConfigPath = "DCOS_IAM_CONFIG_PATH"
+
+	// EnvCACertificatePath points to the CA bundle used to verify TLS connections.
+	EnvCACertificatePath = "DCOS_CA_CERTIFICATE_PATH"
+
+	// EnvStatsdUDPHost and EnvStatsdUDPPort are set by DC/OS for every task and
+	// point to the local statsd endpoint.
+	EnvStatsdUDPHost = "STATSD_UDP_HOST"
+	EnvStatsdUDPPort = "STATSD_UDP_PORT"
+
+	// EnvNodeRole overrides the node role detected from DefaultRolesDir.
+	EnvNodeRole = "DCOS_NODE_ROLE"
+)
+
+const (
+	// DefaultCACertificatePath is the location of the cluster CA bundle on DC/OS nodes.
+	DefaultCACertificatePath = "/run/dcos/pki/CA/ca-bundle.crt"
+
+	// DefaultRolesDir contains an empty file named after every role of the node.
+	DefaultRolesDir = "/etc/mesosphere/roles"
+)
+
+// roleFiles maps the file names in DefaultRolesDir to DC/OS roles.
+var roleFiles = []struct {
+	file string
+	role string
+}{
+	{"master", dcos.RoleMaster},
+	{"slave_public", dcos.RoleAgentPublic},
+	{"slave", dcos.RoleAgent},
+}
+
+// ErrInvalidConfig is the cause of the errors returned by Load and Validate if a resolved value fails validation,
+// see errors.Cause of github.com/pkg/errors.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Config is the resolved bootstrap configuration of a DC/OS component.
+// Fields which could not be resolved from any source are left empty.
+type Config struct {
+	// IAMConfigPath is the path to a service account JSON file.
+	IAMConfigPath string `json:"iam_config_path"`
+
+	// CACertificatePath is the path to the CA bundle.
+	CACertificatePath string `json:"ca_certificate_path"`
+
+	// StatsdAddr is the host:port of the statsd endpoint.
+	StatsdAddr string `json:"statsd_addr"`
+
+	// Role is one of dcos.RoleMaster, dcos.RoleAgent or dcos.RoleAgentPublic.
+	Role string `json:"role"`
+}
+
+// Option is a functional option that configures Load.
+type Option func(*loader) error
+
+type loader struct {
+	explicit   Config
+	configFile string
+	rolesDir   string
+	caPath     string
+	lookupEnv  func(string) (string, bool)
+}
+
+// OptionIAMConfigPath sets the service account path, overriding any other source.
+func OptionIAMConfigPath(path string) Option {
+	return func(l *loader) error {
+		if path == "" {
+			return errEmpty("iam config path")
+		}
+		l.explicit.IAMConfigPath = path
+		return nil
+	}
+}
+
+// OptionCACertificatePath sets the CA bundle path, overriding any other source.
+func OptionCACertificatePath(path string) Option {
+	return func(l *loader) error {
+		if path == "" {
+			return errEmpty("ca certificate path")
+		}
+		l.explicit.CACertificatePath = path
+		return nil
+	}
+}
+
+// OptionStatsdAddr sets the statsd host:port, overriding any other source.
+func OptionStatsdAddr(addr string) Option {
+	return func(l *loader) error {
+		if addr == "" {
+			return errEmpty("statsd address")
+		}
+		l.explicit.StatsdAddr = addr
+		return nil
+	}
+}
+
+// OptionRole sets the node role, overriding any other source.
+func OptionRole(role string) Option {
+	return func(l *loader) error {
+		if role == "" {
+			return errEmpty("role")
+		}
+		l.explicit.Role = role
+		return nil
+	}
+}
+
+// OptionConfigFile sets a JSON file holding Config fields. Values from the file
+// take precedence over detected values only. A missing file is an error.
+func OptionConfigFile(path string) Option {
+	return func(l *loader) error {
+		if path == "" {
+			return errEmpty("config file")
+		}
+		l.configFile = path
+		return nil
+	}
+}
+
+// OptionRolesDir overrides DefaultRolesDir.
+func OptionRolesDir(dir string) Option {
+	return func(l *loader) error {
+		if dir == "" {
+			return errEmpty("roles dir")
+		}
+		l.rolesDir = dir
+		return nil
+	}
+}
+
+// OptionDefaultCACertificatePath overrides DefaultCACertificatePath, the CA bundle
+// used when no other source sets one and the file exists.
+func OptionDefaultCACertificatePath(path string) Option {
+	return func(l *loader) error {
+		if path == "" {
+			return errEmpty("default ca certificate path")
+		}
+		l.caPath = path
+		return nil
+	}
+}
+
+// OptionLookupEnv replaces os.LookupEnv as the source of environment variables.
+func OptionLookupEnv(lookupEnv func(string) (string, bool)) Option {
+	return func(l *loader) error {
+		if lookupEnv == nil {
+			return errEmpty("lookup func")
+		}
+		l.lookupEnv = lookupEnv
+		return nil
+	}
+}
+
+// Load resolves and validates the configuration.
+func Load(options ...Option) (*Config, error) {
+	l := &loader{
+		rolesDir:  DefaultRolesDir,
+		caPath:    DefaultCACertificatePath,
+		lookupEnv: os.LookupEnv,
+	}
+	for _, opt := range options {
+		if opt != nil {
+			if err := opt(l); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var file Config
+	if l.configFile != "" {
+		b, err := ioutil.ReadFile(l.configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &file); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %s", l.configFile, err)
+		}
+	}
+
+	env, err := l.env()
+	if err != nil {
+		return nil, err
+	}
+
+	detected := Config{Role: l.detectRole()}
+	if _, err := os.Stat(l.caPath); err == nil {
+		detected.CACertificatePath = l.caPath
+	}
+
+	cfg := &Config{
+		IAMConfigPath:     first(l.explicit.IAMConfigPath, env.IAMConfigPath, file.IAMConfigPath),
+		CACertificatePath: first(l.explicit.CACertificatePath, env.CACertificatePath, file.CACertificatePath, detected.CACertificatePath),
+		StatsdAddr:        first(l.explicit.StatsdAddr, env.StatsdAddr, file.StatsdAddr),
+		Role:              first(l.explicit.Role, env.Role, file.Role, detected.Role),
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// env reads the Config fields set by environment variables.
+func (l *loader) env() (cfg Config, err error) {
+	cfg.IAMConfigPath, _ = l.lookupEnv(EnvIAMConfigPath)
+	cfg.CACertificatePath, _ = l.lookupEnv(EnvCACertificatePath)
+	cfg.Role, _ = l.lookupEnv(EnvNodeRole)
+
+	host, _ := l.lookupEnv(EnvStatsdUDPHost)
+	port, _ := l.lookupEnv(EnvStatsdUDPPort)
+	switch {
+	case host != "" && port != "":
+		cfg.StatsdAddr = net.JoinHostPort(host, port)
+	case host != "" || port != "":
+		return cfg, errors.Wrapf(ErrInvalidConfig, "%s and %s must be set together", EnvStatsdUDPHost, EnvStatsdUDPPort)
+	}
+	return cfg, nil
+}
+
+// detectRole returns the role found in the roles directory, or "" if none was found.
+func (l *loader) detectRole() string {
+	for _, rf := range roleFiles {
+		if _, err := os.Stat(filepath.Join(l.rolesDir, rf.file)); err == nil {
+			return rf.role
+		}
+	}
+	return ""
+}
+
+// Validate checks the values set on the Config.
+func (c *Config) Validate() error {
+	for _, path := range []string{c.IAMConfigPath, c.CACertificatePath} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return errors.Wrap(ErrInvalidConfig, err.Error())
+		}
+	}
+
+	if c.StatsdAddr != "" {
+		_, port, err := net.SplitHostPort(c.StatsdAddr)
+		if err != nil {
+			return errors.Wrapf(ErrInvalidConfig, "statsd address: %s", err)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return errors.Wrapf(ErrInvalidConfig, "statsd address: invalid port %q", port)
+		}
+	}
+
+	switch c.Role {
+	case "", dcos.RoleMaster, dcos.RoleAgent, dcos.RoleAgentPublic:
+	default:
+		return errors.Wrapf(ErrInvalidConfig, "unknown role %q", c.Role)
+	}
+	return nil
+}
+
+// TransportOptions returns the dcos/http/transport options matching the Config.
+func (c *Config) TransportOptions() []transport.OptionTransportFunc {
+	var opts []transport.OptionTransportFunc
+	if c.CACertificatePath != "" {
+		opts = append(opts, transport.OptionCaCertificatePath(c.CACertificatePath))
+	}
+	if c.IAMConfigPath != "" {
+		opts = append(opts, transport.OptionIAMConfigPath(c.IAMConfigPath))
+	}
+	return opts
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func errEmpty(name string) error {
+	return fmt.Errorf("%s cannot be empty", name)
+}
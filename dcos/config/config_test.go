@@ -0,0 +1,150 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dcos/dcos-go/dcos"
+	"github.com/pkg/errors"
+)
+
+func env(vars map[string]string) Option {
+	return OptionLookupEnv(func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	})
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "dcos-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func touch(t *testing.T, path, content string) string {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadEmpty(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	cfg, err := Load(env(nil), OptionRolesDir(dir), OptionDefaultCACertificatePath(filepath.Join(dir, "missing.crt")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cfg != (Config{}) {
+		t.Fatalf("Expect empty config. Got %+v", cfg)
+	}
+	if len(cfg.TransportOptions()) != 0 {
+		t.Fatal("Expect no transport options for an empty config")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	fileCA := touch(t, filepath.Join(dir, "file.crt"), "")
+	envCA := touch(t, filepath.Join(dir, "env.crt"), "")
+	optionCA := touch(t, filepath.Join(dir, "option.crt"), "")
+	detectedCA := touch(t, filepath.Join(dir, "detected.crt"), "")
+	iamConfig := touch(t, filepath.Join(dir, "iam.json"), "")
+	touch(t, filepath.Join(dir, "slave"), "")
+	configFile := touch(t, filepath.Join(dir, "config.json"),
+		`{"ca_certificate_path": "`+fileCA+`", "iam_config_path": "`+iamConfig+`", "statsd_addr": "10.0.0.1:8125"}`)
+
+	base := []Option{OptionRolesDir(dir), OptionDefaultCACertificatePath(detectedCA)}
+
+	cfg, err := Load(append(base, env(nil))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CACertificatePath != detectedCA || cfg.Role != dcos.RoleAgent {
+		t.Fatalf("Expect detected values. Got %+v", cfg)
+	}
+
+	cfg, err = Load(append(base, env(nil), OptionConfigFile(configFile))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Config{IAMConfigPath: iamConfig, CACertificatePath: fileCA, StatsdAddr: "10.0.0.1:8125", Role: dcos.RoleAgent}
+	if *cfg != expected {
+		t.Fatalf("Expect %+v. Got %+v", expected, cfg)
+	}
+
+	environment := env(map[string]string{
+		EnvCACertificatePath: envCA,
+		EnvStatsdUDPHost:     "127.0.0.1",
+		EnvStatsdUDPPort:     "61825",
+		EnvNodeRole:          dcos.RoleMaster,
+	})
+	cfg, err = Load(append(base, environment, OptionConfigFile(configFile))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = Config{IAMConfigPath: iamConfig, CACertificatePath: envCA, StatsdAddr: "127.0.0.1:61825", Role: dcos.RoleMaster}
+	if *cfg != expected {
+		t.Fatalf("Expect %+v. Got %+v", expected, cfg)
+	}
+
+	cfg, err = Load(append(base, environment, OptionConfigFile(configFile), OptionCACertificatePath(optionCA),
+		OptionRole(dcos.RoleAgentPublic))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CACertificatePath != optionCA || cfg.Role != dcos.RoleAgentPublic {
+		t.Fatalf("Expect explicit options to win. Got %+v", cfg)
+	}
+	if len(cfg.TransportOptions()) != 2 {
+		t.Fatalf("Expect 2 transport options. Got %d", len(cfg.TransportOptions()))
+	}
+}
+
+func TestDetectRole(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	touch(t, filepath.Join(dir, "slave_public"), "")
+	cfg, err := Load(env(nil), OptionRolesDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Role != dcos.RoleAgentPublic {
+		t.Fatalf("Expect role %s. Got %s", dcos.RoleAgentPublic, cfg.Role)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		opts    []Option
+		invalid bool // whether ErrInvalidConfig is the cause
+	}{
+		{[]Option{env(map[string]string{EnvStatsdUDPHost: "127.0.0.1"})}, true},
+		{[]Option{env(map[string]string{EnvNodeRole: "leader"})}, true},
+		{[]Option{env(nil), OptionStatsdAddr("127.0.0.1")}, true},
+		{[]Option{env(nil), OptionStatsdAddr("127.0.0.1:0")}, true},
+		{[]Option{env(nil), OptionIAMConfigPath(filepath.Join(dir, "missing.json"))}, true},
+		{[]Option{env(nil), OptionConfigFile(filepath.Join(dir, "missing.json"))}, false},
+		{[]Option{env(nil), OptionConfigFile(touch(t, filepath.Join(dir, "bad.json"), "{"))}, false},
+		{[]Option{OptionRole("")}, false},
+	} {
+		_, err := Load(append(tc.opts, OptionRolesDir(dir))...)
+		if err == nil {
+			t.Fatalf("Expect error for options %v", tc.opts)
+		}
+		if invalid := errors.Cause(err) == ErrInvalidConfig; invalid != tc.invalid {
+			t.Fatalf("Expect ErrInvalidConfig to be the cause: %v. Got %v", tc.invalid, err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// Package config loads the bootstrap configuration shared by DC/OS components:
+// the service account (IAM config) path, the cluster CA bundle path, the statsd
+// endpoint and the node role.
+//
+// Every value is resolved with the same precedence, highest first:
+//
+//  1. values passed explicitly via an Option
+//  2. DC/OS-conventional environment variables (see the Env* constants)
+//  3. an optional JSON configuration file (OptionConfigFile)
+//  4. values detected on the node (CA bundle location, /etc/mesosphere/roles)
+//
+// Usage:
+//
+//	cfg, err := config.Load(config.OptionConfigFile("/opt/mesosphere/etc/my-service.json"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	rt, err := transport.NewTransport(cfg.TransportOptions()...)
+package config
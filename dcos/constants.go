@@ -25,3 +25,13 @@ func GetFileDetectIPLocation() string {
 		return "/opt/mesosphere/bin/detect_ip"
 	}
 }
+
+// GetFileDetectFaultDomainLocation is a shell script on every DC/OS node which provides the fault domain of the node.
+func GetFileDetectFaultDomainLocation() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "/opt/mesosphere/bin/detect_fault_domain.ps1"
+	default:
+		return "/opt/mesosphere/bin/detect_fault_domain"
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for requests to a host whose circuit breaker is
+// open, see OptionCircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// breakerConfig configures the circuit breaker, see OptionCircuitBreaker.
+type breakerConfig struct {
+	failures    int
+	openTimeout time.Duration
+}
+
+// OptionCircuitBreaker stops sending requests to a host after the given
+// number of consecutive failures, i.e. errors and 5xx responses. Requests fail
+// with ErrCircuitOpen without being sent for openTimeout, then a single probe
+// request is let through: the circuit closes again if it succeeds, and stays
+// open for another openTimeout if it fails.
+func OptionCircuitBreaker(failures int, openTimeout time.Duration) Option {
+	return func(c *Client) error {
+		if failures <= 0 || openTimeout <= 0 {
+			return fmt.Errorf("invalid circuit breaker: failures and open timeout must be positive")
+		}
+		c.breaker = &breakerConfig{failures: failures, openTimeout: openTimeout}
+		return nil
+	}
+}
+
+// circuit is the state of the circuit breaker of a host.
+type circuit struct {
+	failures  int       // consecutive failures
+	openUntil time.Time // zero if the circuit is closed
+	probing   bool      // whether a probe request is in flight
+}
+
+// breakerRoundTripper fails requests fast to hosts that keep failing, see
+// OptionCircuitBreaker.
+type breakerRoundTripper struct {
+	next http.RoundTripper
+	cfg  breakerConfig
+
+	sync.Mutex
+	circuits map[string]*circuit
+}
+
+func newBreakerRoundTripper(next http.RoundTripper, cfg *breakerConfig) *breakerRoundTripper {
+	return &breakerRoundTripper{
+		next:     next,
+		cfg:      *cfg,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// allow returns whether a request may be sent to the host, and whether it is
+// the probe of a half-open circuit.
+func (rt *breakerRoundTripper) allow(host string) (ok, probe bool) {
+	rt.Lock()
+	defer rt.Unlock()
+
+	c, exists := rt.circuits[host]
+	if !exists {
+		c = &circuit{}
+		rt.circuits[host] = c
+	}
+	if c.openUntil.IsZero() {
+		return true, false
+	}
+	if c.probing || time.Now().Before(c.openUntil) {
+		return false, false
+	}
+	c.probing = true
+	return true, true
+}
+
+// record updates the circuit of the host with the outcome of a request.
+func (rt *breakerRoundTripper) record(host string, probe, failed bool) {
+	rt.Lock()
+	defer rt.Unlock()
+
+	c := rt.circuits[host]
+	if probe {
+		c.probing = false
+	}
+	if !failed {
+		c.failures = 0
+		c.openUntil = time.Time{}
+		return
+	}
+
+	c.failures++
+	if probe || c.failures >= rt.cfg.failures {
+		c.openUntil = time.Now().Add(rt.cfg.openTimeout)
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *breakerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	ok, probe := rt.allow(host)
+	if !ok {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := rt.next.RoundTrip(req)
+	// requests canceled by the caller say nothing about the host.
+	if err != nil && req.Context().Err() != nil {
+		if probe {
+			rt.Lock()
+			rt.circuits[host].probing = false
+			rt.Unlock()
+		}
+		return nil, err
+	}
+	rt.record(host, probe, err != nil || resp.StatusCode >= 500)
+	return resp, err
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var requests, failing int32 = 0, 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	openTimeout := 50 * time.Millisecond
+	c, err := NewDCOSClient(ts.URL, OptionHTTPClient(ts.Client()), OptionCircuitBreaker(3, openTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func() error {
+		var out struct{}
+		return c.GetJSON(context.Background(), c.URLs().Path(""), &out)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := get(); isCircuitOpen(err) {
+			t.Fatalf("expect the circuit to be closed after %d failures", i)
+		}
+	}
+	if err := get(); !isCircuitOpen(err) {
+		t.Fatalf("expect ErrCircuitOpen. Got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("expect requests not to be sent while the circuit is open. Got %d requests", n)
+	}
+
+	// a failed probe opens the circuit again.
+	time.Sleep(openTimeout)
+	if err := get(); err == nil || isCircuitOpen(err) {
+		t.Fatalf("expect the probe to be sent and fail. Got %v", err)
+	}
+	if err := get(); !isCircuitOpen(err) {
+		t.Fatalf("expect ErrCircuitOpen after a failed probe. Got %v", err)
+	}
+
+	// a successful probe closes the circuit.
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(openTimeout)
+	for i := 0; i < 3; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("expect the circuit to be closed. Got %v", err)
+		}
+	}
+}
+
+func TestOptionCircuitBreakerInvalid(t *testing.T) {
+	for _, option := range []Option{OptionCircuitBreaker(0, time.Second), OptionCircuitBreaker(1, 0)} {
+		if _, err := NewDCOSClient("", option); err == nil {
+			t.Fatal("expect an error")
+		}
+	}
+}
+
+// isCircuitOpen returns true if the request was rejected by the circuit
+// breaker. http.Client wraps errors of the transport into a *url.Error.
+func isCircuitOpen(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	return err == ErrCircuitOpen
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-go/store"
+)
+
+// cacheConfig configures the response cache, see OptionResponseCache.
+type cacheConfig struct {
+	maxEntries   int
+	maxEntrySize int64
+	ttl          time.Duration
+}
+
+// OptionResponseCache caches the responses to GET requests that carry an ETag
+// or a Last-Modified header. Cached responses are revalidated with a
+// conditional request, and served from the cache if the server replies 304 Not
+// Modified, which saves transferring and decoding large documents like the
+// Mesos state again. At most maxEntries responses of at most maxEntrySize
+// bytes each are kept, for at most ttl.
+func OptionResponseCache(maxEntries int, maxEntrySize int64, ttl time.Duration) Option {
+	return func(c *Client) error {
+		if maxEntries <= 0 || maxEntrySize <= 0 || ttl <= 0 {
+			return fmt.Errorf("invalid response cache: max entries, max entry size and ttl must be positive")
+		}
+		c.cache = &cacheConfig{maxEntries: maxEntries, maxEntrySize: maxEntrySize, ttl: ttl}
+		return nil
+	}
+}
+
+// cachedResponse is a response stored in the cache.
+type cachedResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// cachingRoundTripper serves GET requests from a cache of responses, see
+// OptionResponseCache.
+type cachingRoundTripper struct {
+	next         http.RoundTripper
+	entries      store.ExpiringStore
+	maxEntrySize int64
+	ttl          time.Duration
+}
+
+func newCachingRoundTripper(next http.RoundTripper, cfg *cacheConfig) *cachingRoundTripper {
+	return &cachingRoundTripper{
+		next:         next,
+		entries:      store.New(store.WithMaxEntries(cfg.maxEntries)).(store.ExpiringStore),
+		maxEntrySize: cfg.maxEntrySize,
+		ttl:          cfg.ttl,
+	}
+}
+
+// cacheKey returns the key of the request in the cache. The credentials are
+// part of the key, so that responses are never shared across identities.
+func cacheKey(req *http.Request) string {
+	return strings.Join([]string{
+		req.URL.String(),
+		req.Header.Get("Authorization"),
+		req.Header.Get("Accept"),
+	}, "\x00")
+}
+
+// cloneHeader returns a deep copy of the header.
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// cacheable returns whether the request or response allows caching.
+func cacheable(header http.Header) bool {
+	return !strings.Contains(header.Get("Cache-Control"), "no-store")
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *cachingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" || req.Header.Get("Range") != "" || !cacheable(req.Header) {
+		return rt.next.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	var cached *cachedResponse
+	if v, ok := rt.entries.Get(key); ok {
+		cached = v.(*cachedResponse)
+
+		// the request must not be modified by a RoundTripper, clone it to add
+		// the validators.
+		r := req.WithContext(req.Context())
+		r.Header = cloneHeader(req.Header)
+		if etag := cached.header.Get("ETag"); etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.header.Get("Last-Modified"); lastModified != "" {
+			r.Header.Set("If-Modified-Since", lastModified)
+		}
+		req = r
+	}
+
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		rt.entries.SetWithTTL(key, cached, rt.ttl)
+		return cached.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || !cacheable(resp.Header) ||
+		(resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+	return rt.save(key, resp)
+}
+
+// save reads the body of the response and caches it, unless it is larger
+// than the maximum entry size. It returns a response with an equivalent body.
+func (rt *cachingRoundTripper) save(key string, resp *http.Response) (*http.Response, error) {
+	if resp.ContentLength > rt.maxEntrySize {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, rt.maxEntrySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > rt.maxEntrySize {
+		// too large, stream the rest of the body without caching it.
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	rt.entries.SetWithTTL(key, &cachedResponse{
+		statusCode: resp.StatusCode,
+		header:     cloneHeader(resp.Header),
+		body:       body,
+	}, rt.ttl)
+	return resp, nil
+}
+
+// response returns a response for the request from the cache.
+func (c *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.statusCode, http.StatusText(c.statusCode)),
+		StatusCode:    c.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cloneHeader(c.header),
+		Body:          ioutil.NopCloser(bytes.NewReader(c.body)),
+		ContentLength: int64(len(c.body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	var requests, notModified int32
+	etag := `"v1"`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/etag":
+			if r.Header.Get("If-None-Match") == etag {
+				atomic.AddInt32(&notModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			fmt.Fprintf(w, `{"id": %s}`, etag)
+		case "/large":
+			w.Header().Set("ETag", etag)
+			fmt.Fprintf(w, `{"id": "%s"}`, strings.Repeat("x", 100))
+		default:
+			fmt.Fprint(w, `{"id": "uncached"}`)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDCOSClient(ts.URL, OptionHTTPClient(ts.Client()), OptionResponseCache(10, 64, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ts.Client().Transport.(*cachingRoundTripper); ok {
+		t.Fatal("expect the given http client not to be modified")
+	}
+
+	get := func(path string) string {
+		var out struct{ ID string }
+		if err := c.GetJSON(context.Background(), c.URLs().Path(path), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out.ID
+	}
+
+	if id := get("etag"); id != "v1" {
+		t.Fatalf("expect v1. Got %s", id)
+	}
+	if id := get("etag"); id != "v1" || atomic.LoadInt32(&notModified) != 1 {
+		t.Fatalf("expect the cached response to be revalidated. Got %s, %d not modified", id, notModified)
+	}
+
+	// the server changed the resource.
+	etag = `"v2"`
+	if id := get("etag"); id != "v2" {
+		t.Fatalf("expect v2. Got %s", id)
+	}
+	if id := get("etag"); id != "v2" || atomic.LoadInt32(&notModified) != 2 {
+		t.Fatalf("expect the new response to be cached. Got %s, %d not modified", id, notModified)
+	}
+
+	// responses larger than the max entry size are returned but not cached.
+	for i := 0; i < 2; i++ {
+		if id := get("large"); len(id) != 100 {
+			t.Fatalf("expect the full body. Got %d bytes", len(id))
+		}
+	}
+	if n := atomic.LoadInt32(&notModified); n != 2 {
+		t.Fatalf("expect large responses not to be cached. Got %d not modified", n)
+	}
+
+	// responses without validators are not cached.
+	get("uncached")
+	get("uncached")
+	if n := atomic.LoadInt32(&requests); n != 8 {
+		t.Fatalf("expect 8 requests. Got %d", n)
+	}
+}
+
+func TestOptionResponseCacheInvalid(t *testing.T) {
+	for _, option := range []Option{
+		OptionResponseCache(0, 1, time.Minute),
+		OptionResponseCache(1, 0, time.Minute),
+		OptionResponseCache(1, 1, 0),
+	} {
+		if _, err := NewDCOSClient("", option); err == nil {
+			t.Fatal("expect an error")
+		}
+	}
+}
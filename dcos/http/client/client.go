@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/dcos/dcos-go/dcos"
+	"github.com/dcos/dcos-go/dcos/http/transport"
+)
+
+// DefaultBaseURL is Admin Router on the leading master, as seen from within a
+// cluster.
+var DefaultBaseURL = (&url.URL{
+	Scheme: "http",
+	Host:   net.JoinHostPort(dcos.DNSRecordLeader, strconv.Itoa(dcos.PortAdminrouterHTTP)),
+}).String()
+
+// Client is a DC/OS API client talking to the services of a cluster through
+// Admin Router. It is safe for concurrent use.
+type Client struct {
+	urls       *URLBuilder
+	httpClient *http.Client
+
+	rateLimit *rateLimitConfig
+	breaker   *breakerConfig
+	cache     *cacheConfig
+}
+
+// Option configures a Client.
+type Option func(*Client) error
+
+// OptionHTTPClient sets the HTTP client used to send requests. By default a
+// client returned by transport.NewClient is used, which does not authenticate
+// requests.
+func OptionHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) error {
+		if httpClient == nil {
+			return fmt.Errorf("http client cannot be nil")
+		}
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// NewDCOSClient returns a Client for the cluster at baseURL, the URL of Admin
+// Router, e.g. https://dcos.example.com. DefaultBaseURL is used if it is
+// empty.
+func NewDCOSClient(baseURL string, options ...Option) (*Client, error) {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	urls, err := NewURLBuilder(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{urls: urls}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.httpClient == nil {
+		if c.httpClient, err = transport.NewClient(); err != nil {
+			return nil, err
+		}
+	}
+
+	c.wrapTransport()
+	return c, nil
+}
+
+// wrapTransport wraps the transport of the HTTP client with the rate limiter,
+// the circuit breaker and the response cache, if configured. Requests to an
+// open circuit fail without waiting for the rate limiter, and responses served
+// from the cache still count against both.
+func (c *Client) wrapTransport() {
+	if c.rateLimit == nil && c.breaker == nil && c.cache == nil {
+		return
+	}
+
+	// wrap a copy, the given client may be shared.
+	httpClient := *c.httpClient
+	rt := httpClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if c.rateLimit != nil {
+		rt = newRateLimitRoundTripper(rt, c.rateLimit)
+	}
+	if c.breaker != nil {
+		rt = newBreakerRoundTripper(rt, c.breaker)
+	}
+	if c.cache != nil {
+		rt = newCachingRoundTripper(rt, c.cache)
+	}
+	httpClient.Transport = rt
+	c.httpClient = &httpClient
+}
+
+// URLs returns the builder of the URLs of the cluster.
+func (c *Client) URLs() *URLBuilder {
+	return c.urls
+}
+
+// GetJSON sends a GET request to the given URL, usually built with URLs, and
+// decodes the JSON response into out, see DecodeJSON.
+func (c *Client) GetJSON(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if ctx != nil {
+		req = req.WithContext(ctx)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return DecodeJSON(resp, out)
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T, responses map[string]string) (*Client, *httptest.Server) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "not found"}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+
+	c, err := NewDCOSClient(ts.URL+"/", OptionHTTPClient(ts.Client()))
+	if err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+	return c, ts
+}
+
+func TestNewDCOSClient(t *testing.T) {
+	c, err := NewDCOSClient("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := c.URLs().Mesos("master/state"); u != "http://leader.mesos:80/mesos/master/state" {
+		t.Fatalf("expect the default base URL. Got %s", u)
+	}
+
+	for _, invalid := range []string{"leader.mesos", "://", "/path"} {
+		if _, err := NewDCOSClient(invalid); err == nil {
+			t.Fatalf("expect an error with base URL %q", invalid)
+		}
+	}
+	if _, err := NewDCOSClient("", OptionHTTPClient(nil)); err == nil {
+		t.Fatal("expect an error with a nil http client")
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	c, ts := newTestClient(t, map[string]string{
+		"/system/health/v1":               `{"units": [{"id": "dcos-mesos-master.service", "health": 1}], "node_role": "master"}`,
+		"/mesos/master/state":             `{"id": "master-id", "slaves": [{"id": "agent-id"}]}`,
+		"/agent/agent-id/slave(1)/state":  `{"id": "agent-id", "attributes": {"public_ip": "true"}, "resources": {"cpus": 4}}`,
+		"/marathon/v2/apps":               `{"apps": [{"id": "/app", "instances": 2, "tasksRunning": 2}]}`,
+		"/marathon/v2/apps/group/app":     `{"app": {"id": "/group/app", "labels": {"a": "b"}}}`,
+		"/service/metronome/v1/jobs":      `[{"id": "job", "run": {"cmd": "sleep 10", "cpus": 0.1}}]`,
+		"/acs/api/v1/users":               `{"array": [{"uid": "bootstrapuser"}, {"uid": "dcos_marathon", "is_service": true}]}`,
+		"/acs/api/v1/users/bootstrapuser": `{"uid": "bootstrapuser", "description": "Bootstrap superuser"}`,
+	})
+	defer ts.Close()
+	ctx := context.TODO()
+
+	health, err := c.SystemHealth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Role != "master" || len(health.Units) != 1 || health.Units[0].Healthy() {
+		t.Fatalf("unexpected system health %+v", health)
+	}
+
+	state, err := c.MesosState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.ID != "master-id" || len(state.Slaves) != 1 {
+		t.Fatalf("unexpected mesos state %+v", state)
+	}
+
+	agent, err := c.AgentState(ctx, "agent-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent.Attributes["public_ip"] != "true" || agent.Resources.CPUs != 4 {
+		t.Fatalf("unexpected agent state %+v", agent)
+	}
+
+	apps, err := c.MarathonApps(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 1 || apps[0].Instances != 2 || apps[0].TasksRunning != 2 {
+		t.Fatalf("unexpected marathon apps %+v", apps)
+	}
+
+	app, err := c.MarathonApp(ctx, "/group/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.ID != "/group/app" || app.Labels["a"] != "b" {
+		t.Fatalf("unexpected marathon app %+v", app)
+	}
+
+	jobs, err := c.MetronomeJobs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Run.Cmd != "sleep 10" {
+		t.Fatalf("unexpected metronome jobs %+v", jobs)
+	}
+
+	users, err := c.IAMUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || !users[1].IsService {
+		t.Fatalf("unexpected IAM users %+v", users)
+	}
+
+	user, err := c.IAMUser(ctx, "bootstrapuser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Description != "Bootstrap superuser" {
+		t.Fatalf("unexpected IAM user %+v", user)
+	}
+
+	_, err = c.MarathonApp(ctx, "missing")
+	apiErr, ok := err.(APIError)
+	if !ok || apiErr.StatusCode != http.StatusNotFound || apiErr.Description != "not found" {
+		t.Fatalf("expect an APIError. Got %v", err)
+	}
+}
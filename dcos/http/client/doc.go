@@ -0,0 +1,43 @@
+// Package client contains helpers for talking to DC/OS HTTP APIs through
+// Admin Router.
+//
+// Client exposes common endpoints with typed responses:
+//
+//	c, err := client.NewDCOSClient("https://dcos.example.com", client.OptionHTTPClient(httpClient))
+//	if err != nil {
+//		return err
+//	}
+//
+//	apps, err := c.MarathonApps(ctx)
+//
+// Endpoints without a dedicated method can be queried with GetJSON, building
+// their URL with URLs:
+//
+//	err := c.GetJSON(ctx, c.URLs().Service("my-service", "v1/status"), &status)
+//
+// Outside of a cluster, DiscoverClusterURL returns the URL of the cluster the
+// DC/OS CLI is configured for.
+//
+// Components polling large documents like the Mesos state can enable
+// OptionResponseCache to revalidate them with ETag and Last-Modified instead
+// of fetching them again. OptionRateLimit and OptionCircuitBreaker protect a
+// degraded Admin Router from being flooded with requests.
+//
+// DecodeJSON and AsAPIError understand the error bodies returned by Admin
+// Router, the IAM service, Mesos and Marathon and turn non-2xx responses into
+// typed APIError values:
+//
+//	resp, err := c.Do(req)
+//	if err != nil {
+//		return err
+//	}
+//	defer resp.Body.Close()
+//
+//	var state nodeutil.State
+//	if err := client.DecodeJSON(resp, &state); err != nil {
+//		if apiErr, ok := err.(client.APIError); ok && apiErr.StatusCode == http.StatusNotFound {
+//			...
+//		}
+//		return err
+//	}
+package client
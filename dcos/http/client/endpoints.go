@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dcos/dcos-go/dcos/nodeutil"
+)
+
+// UnitHealth is the health of a DC/OS component, as reported by
+// dcos-diagnostics.
+type UnitHealth struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Health      int    `json:"health"` // 0 if healthy, 1 otherwise
+	Description string `json:"description"`
+}
+
+// Healthy returns whether the unit is healthy.
+func (u UnitHealth) Healthy() bool {
+	return u.Health == 0
+}
+
+// SystemHealth is the health of the components of the node serving the
+// request, usually the leading master.
+type SystemHealth struct {
+	Units       []UnitHealth `json:"units"`
+	Hostname    string       `json:"hostname"`
+	IP          string       `json:"ip"`
+	DCOSVersion string       `json:"dcos_version"`
+	Role        string       `json:"node_role"`
+	MesosID     string       `json:"mesos_id"`
+}
+
+// SystemHealth returns the health of the DC/OS components.
+func (c *Client) SystemHealth(ctx context.Context) (*SystemHealth, error) {
+	var health SystemHealth
+	if err := c.GetJSON(ctx, c.urls.SystemHealth(""), &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// MesosState returns the state of the leading mesos master.
+func (c *Client) MesosState(ctx context.Context) (*nodeutil.State, error) {
+	var state nodeutil.State
+	if err := c.GetJSON(ctx, c.urls.Mesos("master/state"), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// AgentState is the state of a mesos agent.
+type AgentState struct {
+	ID         string                 `json:"id"`
+	Hostname   string                 `json:"hostname"`
+	Attributes map[string]interface{} `json:"attributes"`
+	Resources  nodeutil.Resources     `json:"resources"`
+}
+
+// AgentState returns the state of the mesos agent with the given ID, proxied
+// by Admin Router.
+func (c *Client) AgentState(ctx context.Context, agentID string) (*AgentState, error) {
+	var state AgentState
+	if err := c.GetJSON(ctx, c.urls.Agent(agentID, "slave(1)/state"), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// MarathonApp is a Marathon application.
+type MarathonApp struct {
+	ID             string            `json:"id"`
+	Cmd            string            `json:"cmd"`
+	Instances      int               `json:"instances"`
+	CPUs           float64           `json:"cpus"`
+	Mem            float64           `json:"mem"`
+	Disk           float64           `json:"disk"`
+	Labels         map[string]string `json:"labels"`
+	TasksRunning   int               `json:"tasksRunning"`
+	TasksStaged    int               `json:"tasksStaged"`
+	TasksHealthy   int               `json:"tasksHealthy"`
+	TasksUnhealthy int               `json:"tasksUnhealthy"`
+}
+
+// MarathonApps returns the applications run by the root Marathon.
+func (c *Client) MarathonApps(ctx context.Context) ([]MarathonApp, error) {
+	var apps struct {
+		Apps []MarathonApp `json:"apps"`
+	}
+	if err := c.GetJSON(ctx, c.urls.Marathon("v2/apps"), &apps); err != nil {
+		return nil, err
+	}
+	return apps.Apps, nil
+}
+
+// MarathonApp returns the Marathon application with the given ID, e.g.
+// /group/app.
+func (c *Client) MarathonApp(ctx context.Context, appID string) (*MarathonApp, error) {
+	var app struct {
+		App MarathonApp `json:"app"`
+	}
+	if err := c.GetJSON(ctx, c.urls.Marathon("v2/apps/"+strings.TrimPrefix(appID, "/")), &app); err != nil {
+		return nil, err
+	}
+	return &app.App, nil
+}
+
+// MetronomeJob is a Metronome job.
+type MetronomeJob struct {
+	ID          string            `json:"id"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+	Run         struct {
+		Cmd  string  `json:"cmd"`
+		CPUs float64 `json:"cpus"`
+		Mem  float64 `json:"mem"`
+		Disk float64 `json:"disk"`
+	} `json:"run"`
+}
+
+// MetronomeJobs returns the jobs defined in Metronome.
+func (c *Client) MetronomeJobs(ctx context.Context) ([]MetronomeJob, error) {
+	var jobs []MetronomeJob
+	if err := c.GetJSON(ctx, c.urls.Service("metronome", "v1/jobs"), &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// IAMUser is a user or service account of the DC/OS IAM.
+type IAMUser struct {
+	UID         string `json:"uid"`
+	Description string `json:"description"`
+	IsRemote    bool   `json:"is_remote"`
+	IsService   bool   `json:"is_service"`
+}
+
+// IAMUsers returns the users of the DC/OS IAM.
+func (c *Client) IAMUsers(ctx context.Context) ([]IAMUser, error) {
+	var users struct {
+		Array []IAMUser `json:"array"`
+	}
+	if err := c.GetJSON(ctx, c.urls.Path("acs/api/v1/users"), &users); err != nil {
+		return nil, err
+	}
+	return users.Array, nil
+}
+
+// IAMUser returns the IAM user with the given UID.
+func (c *Client) IAMUser(ctx context.Context, uid string) (*IAMUser, error) {
+	var user IAMUser
+	if err := c.GetJSON(ctx, c.urls.Path("acs/api/v1/users", uid), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize caps how much of an error response body is read.
+const maxErrorBodySize = 64 * 1024
+
+// APIError is returned for responses with a non-2xx status code.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// URL is the URL of the request, if known.
+	URL string
+
+	// Title is a short summary of the error, e.g. "Bad Request".
+	Title string
+
+	// Description is the detailed error message found in the response body.
+	Description string
+
+	// Code is the machine readable error code, e.g. "ERR_INVALID_DATA", if the
+	// service returned one.
+	Code string
+
+	// Body is the (possibly truncated) raw response body.
+	Body []byte
+}
+
+func (e APIError) Error() string {
+	msg := fmt.Sprintf("request to %s returned response code %d", e.URL, e.StatusCode)
+	if e.URL == "" {
+		msg = fmt.Sprintf("request returned response code %d", e.StatusCode)
+	}
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// errorBody covers the JSON error formats of the DC/OS services:
+//
+//	Admin Router and IAM: {"title": ..., "description": ..., "code": ...}
+//	Marathon:             {"message": ...}
+//	other services:       {"error": ...}
+type errorBody struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	Error       string `json:"error"`
+}
+
+// AsAPIError returns nil if the response has a 2xx status code, otherwise it
+// reads the response body and returns an APIError. The body is not closed.
+func AsAPIError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	apiErr := APIError{
+		StatusCode: resp.StatusCode,
+		Title:      http.StatusText(resp.StatusCode),
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		apiErr.URL = resp.Request.URL.String()
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	apiErr.Body = body
+
+	var eb errorBody
+	if err := json.Unmarshal(body, &eb); err == nil {
+		if eb.Title != "" {
+			apiErr.Title = eb.Title
+		}
+		apiErr.Code = eb.Code
+		apiErr.Description = first(eb.Description, eb.Message, eb.Error)
+		return apiErr
+	}
+
+	// Mesos and nginx answer with plain text or HTML. Only plain text makes a
+	// useful description.
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		apiErr.Description = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
+
+// DecodeJSON decodes the JSON body of a 2xx response into out. For any other
+// status code the APIError built by AsAPIError is returned. The body is not
+// closed.
+func DecodeJSON(resp *http.Response, out interface{}) error {
+	if err := AsAPIError(resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if resp.Request != nil && resp.Request.URL != nil {
+			return fmt.Errorf("could not decode response from %s: %s", resp.Request.URL, err)
+		}
+		return fmt.Errorf("could not decode response: %s", err)
+	}
+	return nil
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
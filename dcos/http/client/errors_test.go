@@ -0,0 +1,126 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, contentType string, code int, body string) *http.Response {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(code)
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// the server is closed on return, buffer the body.
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return resp
+}
+
+func TestAsAPIError(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		code        int
+		body        string
+		title       string
+		description string
+		errCode     string
+	}{
+		{
+			name:        "iam",
+			contentType: "application/json",
+			code:        http.StatusBadRequest,
+			body:        `{"title": "Bad Request", "description": "invalid uid", "code": "ERR_INVALID_DATA"}`,
+			title:       "Bad Request",
+			description: "invalid uid",
+			errCode:     "ERR_INVALID_DATA",
+		},
+		{
+			name:        "marathon",
+			contentType: "application/json",
+			code:        http.StatusNotFound,
+			body:        `{"message": "App '/foo' does not exist"}`,
+			title:       "Not Found",
+			description: "App '/foo' does not exist",
+		},
+		{
+			name:        "mesos",
+			contentType: "text/plain",
+			code:        http.StatusServiceUnavailable,
+			body:        "No leader elected\n",
+			title:       "Service Unavailable",
+			description: "No leader elected",
+		},
+		{
+			name:        "nginx",
+			contentType: "text/html",
+			code:        http.StatusBadGateway,
+			body:        "<html><body>502 Bad Gateway</body></html>",
+			title:       "Bad Gateway",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := get(t, tc.contentType, tc.code, tc.body)
+			err := AsAPIError(resp)
+			apiErr, ok := err.(APIError)
+			if !ok {
+				t.Fatalf("Expect APIError. Got %T: %v", err, err)
+			}
+			if apiErr.StatusCode != tc.code || apiErr.Title != tc.title || apiErr.Description != tc.description ||
+				apiErr.Code != tc.errCode {
+				t.Fatalf("Unexpected error %+v", apiErr)
+			}
+			if !strings.HasSuffix(apiErr.URL, "/path") {
+				t.Fatalf("Expect request URL in error. Got %s", apiErr.URL)
+			}
+			if string(apiErr.Body) != tc.body {
+				t.Fatalf("Expect body %q. Got %q", tc.body, apiErr.Body)
+			}
+		})
+	}
+}
+
+func TestAsAPIErrorSuccess(t *testing.T) {
+	if err := AsAPIError(get(t, "application/json", http.StatusNoContent, "")); err != nil {
+		t.Fatalf("Expect nil error. Got %s", err)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var out struct {
+		ID string `json:"id"`
+	}
+	if err := DecodeJSON(get(t, "application/json", http.StatusOK, `{"id": "abc"}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != "abc" {
+		t.Fatalf("Expect id abc. Got %s", out.ID)
+	}
+
+	err := DecodeJSON(get(t, "application/json", http.StatusOK, `{"id":`), &out)
+	if err == nil || !strings.Contains(err.Error(), "could not decode response") {
+		t.Fatalf("Expect decode error. Got %v", err)
+	}
+
+	err = DecodeJSON(get(t, "application/json", http.StatusUnauthorized, `{"code": "ERR_INVALID_TOKEN"}`), &out)
+	if apiErr, ok := err.(APIError); !ok || apiErr.Code != "ERR_INVALID_TOKEN" {
+		t.Fatalf("Expect APIError with code. Got %v", err)
+	}
+}
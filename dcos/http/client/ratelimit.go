@@ -0,0 +1,106 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimitConfig configures the rate limiter, see OptionRateLimit.
+type rateLimitConfig struct {
+	rate  float64
+	burst int
+}
+
+// OptionRateLimit limits the requests sent to every host to rate requests per
+// second on average, with bursts of up to burst requests. Requests over the
+// limit wait for their turn, or until their context is done.
+func OptionRateLimit(rate float64, burst int) Option {
+	return func(c *Client) error {
+		if rate <= 0 || burst <= 0 {
+			return fmt.Errorf("invalid rate limit: rate and burst must be positive")
+		}
+		c.rateLimit = &rateLimitConfig{rate: rate, burst: burst}
+		return nil
+	}
+}
+
+// tokenBucket is a token bucket holding up to burst tokens, refilled at rate
+// tokens per second.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token from the bucket and returns how long the caller must
+// wait before using it. The caller must hold the lock of the limiter.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that was not used.
+func (b *tokenBucket) cancel() {
+	b.tokens++
+}
+
+// rateLimitRoundTripper limits the rate of requests per host, see
+// OptionRateLimit.
+type rateLimitRoundTripper struct {
+	next http.RoundTripper
+	cfg  rateLimitConfig
+
+	sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimitRoundTripper(next http.RoundTripper, cfg *rateLimitConfig) *rateLimitRoundTripper {
+	return &rateLimitRoundTripper{
+		next:    next,
+		cfg:     *cfg,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *rateLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.Lock()
+	b, ok := rt.buckets[req.URL.Host]
+	if !ok {
+		b = &tokenBucket{
+			rate:   rt.cfg.rate,
+			burst:  float64(rt.cfg.burst),
+			tokens: float64(rt.cfg.burst),
+			last:   time.Now(),
+		}
+		rt.buckets[req.URL.Host] = b
+	}
+	wait := b.reserve(time.Now())
+	rt.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			rt.Lock()
+			b.cancel()
+			rt.Unlock()
+			return nil, req.Context().Err()
+		}
+	}
+	return rt.next.RoundTrip(req)
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c, err := NewDCOSClient(ts.URL, OptionHTTPClient(ts.Client()), OptionRateLimit(20, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(ctx context.Context) error {
+		req, err := http.NewRequest("GET", ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := c.httpClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	// the burst is sent right away, the next request waits for a token.
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := get(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("expect the third request to wait for ~50ms. Got %s", elapsed)
+	}
+
+	// waiting requests give up when their context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	get(context.Background())
+	if err := get(ctx); err == nil {
+		t.Fatal("expect an error for a request canceled while waiting")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := &tokenBucket{rate: 10, burst: 2, tokens: 2, last: now}
+
+	for i := 0; i < 2; i++ {
+		if wait := b.reserve(now); wait != 0 {
+			t.Fatalf("expect no wait within the burst. Got %s", wait)
+		}
+	}
+	if wait := b.reserve(now); wait != 100*time.Millisecond {
+		t.Fatalf("expect to wait 100ms. Got %s", wait)
+	}
+	b.cancel()
+
+	// the bucket never holds more than burst tokens.
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if wait := b.reserve(now); wait != 0 {
+			t.Fatalf("expect no wait within the burst. Got %s", wait)
+		}
+	}
+	if wait := b.reserve(now); wait == 0 {
+		t.Fatal("expect to wait after the burst")
+	}
+}
+
+func TestOptionRateLimitInvalid(t *testing.T) {
+	for _, option := range []Option{OptionRateLimit(0, 1), OptionRateLimit(1, 0)} {
+		if _, err := NewDCOSClient("", option); err == nil {
+			t.Fatal("expect an error")
+		}
+	}
+}
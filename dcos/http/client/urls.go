@@ -0,0 +1,172 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables used by DiscoverClusterURL, as understood by the DC/OS
+// CLI.
+const (
+	// EnvClusterURL is the URL of the cluster.
+	EnvClusterURL = "DCOS_URL"
+
+	// EnvCLIDir is the configuration directory of the DC/OS CLI, ~/.dcos by
+	// default.
+	EnvCLIDir = "DCOS_DIR"
+)
+
+// ErrClusterURLNotFound is returned by DiscoverClusterURL if no cluster URL is
+// configured.
+var ErrClusterURLNotFound = errors.New("cluster URL not found")
+
+// URLBuilder builds the URLs of DC/OS services behind Admin Router.
+type URLBuilder struct {
+	base *url.URL
+}
+
+// NewURLBuilder returns a URLBuilder for the cluster at baseURL, the URL of
+// Admin Router.
+func NewURLBuilder(baseURL string) (*URLBuilder, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+	return &URLBuilder{base: u}, nil
+}
+
+// Path returns the URL of the given Admin Router path.
+func (b *URLBuilder) Path(elem ...string) string {
+	u := *b.base
+	parts := []string{strings.TrimSuffix(u.Path, "/")}
+	for _, e := range elem {
+		if e = strings.Trim(e, "/"); e != "" {
+			parts = append(parts, e)
+		}
+	}
+	u.Path = strings.Join(parts, "/")
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
+
+// Mesos returns the URL of a path of the leading mesos master, e.g.
+// Mesos("master/state").
+func (b *URLBuilder) Mesos(path string) string {
+	return b.Path("mesos", path)
+}
+
+// Marathon returns the URL of a path of the root Marathon, e.g.
+// Marathon("v2/apps").
+func (b *URLBuilder) Marathon(path string) string {
+	return b.Path("marathon", path)
+}
+
+// SystemHealth returns the URL of a path of the system health API, e.g.
+// SystemHealth("nodes").
+func (b *URLBuilder) SystemHealth(path string) string {
+	return b.Path("system/health/v1", path)
+}
+
+// Service returns the URL of a path of a service proxied by Admin Router, e.g.
+// Service("metronome", "v1/jobs").
+func (b *URLBuilder) Service(name, path string) string {
+	return b.Path("service", name, path)
+}
+
+// Agent returns the URL of a path of the mesos agent with the given ID,
+// proxied by Admin Router, e.g. Agent(id, "slave(1)/state").
+func (b *URLBuilder) Agent(agentID, path string) string {
+	return b.Path("agent", agentID, path)
+}
+
+// DiscoverClusterURL returns the URL of the cluster the DC/OS CLI is
+// configured for: the DCOS_URL environment variable if set, or else the
+// dcos_url of the attached cluster in the configuration of the CLI.
+func DiscoverClusterURL() (string, error) {
+	return discoverClusterURL(os.Getenv, homeDir())
+}
+
+// homeDir returns the home directory of the current user, or an empty string
+// if it cannot be determined.
+func homeDir() string {
+	if home := os.Getenv("HOME"); home != "" {
+		return home
+	}
+	if u, err := user.Current(); err == nil {
+		return u.HomeDir
+	}
+	return ""
+}
+
+func discoverClusterURL(getenv func(string) string, home string) (string, error) {
+	if u := getenv(EnvClusterURL); u != "" {
+		return u, nil
+	}
+
+	dir := getenv(EnvCLIDir)
+	if dir == "" {
+		if home == "" {
+			return "", ErrClusterURLNotFound
+		}
+		dir = filepath.Join(home, ".dcos")
+	}
+
+	// the CLI keeps a configuration per cluster and marks the one it is
+	// attached to. Older versions have a single configuration.
+	config := filepath.Join(dir, "dcos.toml")
+	if clusters, err := ioutil.ReadDir(filepath.Join(dir, "clusters")); err == nil {
+		for _, cluster := range clusters {
+			clusterDir := filepath.Join(dir, "clusters", cluster.Name())
+			if _, err := os.Stat(filepath.Join(clusterDir, "attached")); err == nil {
+				config = filepath.Join(clusterDir, "dcos.toml")
+				break
+			}
+		}
+	}
+
+	u, err := readCLIClusterURL(config)
+	if os.IsNotExist(err) {
+		return "", ErrClusterURLNotFound
+	}
+	return u, err
+}
+
+// readCLIClusterURL returns core.dcos_url from a DC/OS CLI configuration.
+func readCLIClusterURL(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[]")
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if section == "core" && len(kv) == 2 && strings.TrimSpace(kv[0]) == "dcos_url" {
+			return strings.Trim(strings.TrimSpace(kv[1]), `"'`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", ErrClusterURLNotFound
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestURLBuilder(t *testing.T) {
+	b, err := NewURLBuilder("https://dcos.example.com/prefix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		got, expected string
+	}{
+		{b.Path(""), "https://dcos.example.com/prefix"},
+		{b.Path("/acs/api/v1/users/", "bob"), "https://dcos.example.com/prefix/acs/api/v1/users/bob"},
+		{b.Mesos("master/state"), "https://dcos.example.com/prefix/mesos/master/state"},
+		{b.Marathon("/v2/apps"), "https://dcos.example.com/prefix/marathon/v2/apps"},
+		{b.SystemHealth(""), "https://dcos.example.com/prefix/system/health/v1"},
+		{b.Service("metronome", "v1/jobs"), "https://dcos.example.com/prefix/service/metronome/v1/jobs"},
+		{b.Agent("agent-1", "slave(1)/state"), "https://dcos.example.com/prefix/agent/agent-1/slave%281%29/state"},
+	} {
+		if tc.got != tc.expected {
+			t.Fatalf("expect %s. Got %s", tc.expected, tc.got)
+		}
+	}
+
+	if _, err := NewURLBuilder("dcos.example.com"); err == nil {
+		t.Fatal("expect an error for a URL without scheme")
+	}
+}
+
+func writeCLIConfig(t *testing.T, dir, dcosURL string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := "[core]\ndcos_acs_token = \"token\"\ndcos_url = \"" + dcosURL + "\"\n\n[marathon]\nurl = \"http://marathon\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "dcos.toml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverClusterURL(t *testing.T) {
+	home, err := ioutil.TempDir("", "dcos-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	if _, err := discoverClusterURL(getenv, home); err != ErrClusterURLNotFound {
+		t.Fatalf("expect ErrClusterURLNotFound without configuration. Got %v", err)
+	}
+
+	// single configuration of older CLI versions.
+	writeCLIConfig(t, filepath.Join(home, ".dcos"), "https://legacy.example.com")
+	if u, err := discoverClusterURL(getenv, home); err != nil || u != "https://legacy.example.com" {
+		t.Fatalf("expect the URL of the legacy configuration. Got %s, %v", u, err)
+	}
+
+	// configuration of the attached cluster.
+	writeCLIConfig(t, filepath.Join(home, ".dcos", "clusters", "a"), "https://a.example.com")
+	writeCLIConfig(t, filepath.Join(home, ".dcos", "clusters", "b"), "https://b.example.com")
+	if err := ioutil.WriteFile(filepath.Join(home, ".dcos", "clusters", "b", "attached"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := discoverClusterURL(getenv, home); err != nil || u != "https://b.example.com" {
+		t.Fatalf("expect the URL of the attached cluster. Got %s, %v", u, err)
+	}
+
+	// DCOS_DIR overrides the configuration directory.
+	env[EnvCLIDir] = filepath.Join(home, "other")
+	writeCLIConfig(t, env[EnvCLIDir], "https://other.example.com")
+	if u, err := discoverClusterURL(getenv, home); err != nil || u != "https://other.example.com" {
+		t.Fatalf("expect the URL in DCOS_DIR. Got %s, %v", u, err)
+	}
+
+	// DCOS_URL takes precedence.
+	env[EnvClusterURL] = "https://env.example.com"
+	if u, err := discoverClusterURL(getenv, home); err != nil || u != "https://env.example.com" {
+		t.Fatalf("expect the URL in DCOS_URL. Got %s, %v", u, err)
+	}
+}
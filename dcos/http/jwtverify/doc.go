@@ -0,0 +1,22 @@
+// Package jwtverify validates DC/OS authentication tokens on the server side.
+//
+// Services receiving requests authenticated by the IAM service verify the
+// signature of the token against the public keys of the IAM service, fetched
+// from its JWKS endpoint, and check its expiry and, optionally, its audience
+// and issuer:
+//
+//	v, err := jwtverify.NewVerifier(jwtverify.OptionJWKSURL("https://leader.mesos" + jwtverify.DefaultJWKSPath))
+//	if err != nil {
+//		return err
+//	}
+//
+//	claims, err := v.Verify(r.Context(), jwtverify.TokenFromRequest(r))
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusUnauthorized)
+//		return
+//	}
+//
+// When the IAM service rotates its keys, tokens signed with an unknown key
+// make the verifier fetch the keys again, at most once per
+// OptionMinRefreshInterval.
+package jwtverify
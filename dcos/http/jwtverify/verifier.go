@@ -0,0 +1,369 @@
+package jwtverify
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// DefaultJWKSPath is the path of the JWKS endpoint of the IAM service, which
+// returns the public keys tokens are signed with.
+const DefaultJWKSPath = "/acs/api/v1/auth/jwks"
+
+// authCookie is the cookie holding the token of users logged in to the UI.
+const authCookie = "dcos-acs-auth-cookie"
+
+const defaultMinRefreshInterval = 10 * time.Second
+
+// defaultFetchTimeout is the timeout of the default HTTP client fetching the
+// keys.
+const defaultFetchTimeout = 10 * time.Second
+
+var (
+	// ErrNoToken is returned by Verify for an empty token.
+	ErrNoToken = errors.New("no authentication token")
+
+	// ErrUnknownKey is returned by Verify if the token is not signed by any of
+	// the known keys.
+	ErrUnknownKey = errors.New("token is not signed by a known key")
+
+	// ErrNoExpiry is returned by Verify for tokens without an exp claim.
+	ErrNoExpiry = errors.New("token has no exp claim")
+
+	// ErrNoKeySource is returned by NewVerifier if neither OptionJWKSURL nor
+	// OptionPublicKey is set.
+	ErrNoKeySource = errors.New("a JWKS URL or a public key is required")
+)
+
+// algorithms are the signature algorithms of DC/OS tokens.
+var algorithms = map[jose.SignatureAlgorithm]bool{
+	jose.RS256: true,
+	jose.ES256: true,
+	jose.ES384: true,
+	jose.ES512: true,
+}
+
+// Claims are the claims of a DC/OS authentication token.
+type Claims struct {
+	jwt.Claims
+
+	// UID is the ID of the user or service account the token was issued to.
+	UID string `json:"uid"`
+}
+
+// Verifier verifies DC/OS authentication tokens. It is safe for concurrent
+// use.
+type Verifier struct {
+	jwksURL            string
+	httpClient         *http.Client
+	audience           string
+	issuer             string
+	leeway             time.Duration
+	minRefreshInterval time.Duration
+	static             []jose.JSONWebKey
+
+	sync.Mutex
+	keys      []jose.JSONWebKey // fetched from the JWKS URL
+	attempted time.Time         // when the last completed fetch started, successful or not
+	call      *refreshCall
+}
+
+// refreshCall is a fetch of the keys shared by all the callers that need them
+// at the same time.
+type refreshCall struct {
+	done chan struct{}
+	keys []jose.JSONWebKey
+	err  error
+}
+
+// Option configures a Verifier.
+type Option func(*Verifier) error
+
+// OptionJWKSURL sets the URL of the JWKS endpoint the keys are fetched from,
+// e.g. https://leader.mesos/acs/api/v1/auth/jwks, see DefaultJWKSPath.
+func OptionJWKSURL(u string) Option {
+	return func(v *Verifier) error {
+		if u == "" {
+			return errors.New("JWKS URL cannot be empty")
+		}
+		v.jwksURL = u
+		return nil
+	}
+}
+
+// OptionPublicKey adds a static RSA or ECDSA public key with the given key ID,
+// which may be empty. It can be combined with OptionJWKSURL.
+func OptionPublicKey(keyID string, key crypto.PublicKey) Option {
+	return func(v *Verifier) error {
+		jwk := jose.JSONWebKey{Key: key, KeyID: keyID}
+		if !jwk.Valid() || !jwk.IsPublic() {
+			return errors.New("key must be an RSA or ECDSA public key")
+		}
+		v.static = append(v.static, jwk)
+		return nil
+	}
+}
+
+// OptionHTTPClient sets the HTTP client used to fetch the keys. By default, a
+// client with a timeout of 10s is used. Fetches are shared by concurrent
+// callers and are not canceled with their context, the client should have a
+// timeout.
+func OptionHTTPClient(c *http.Client) Option {
+	return func(v *Verifier) error {
+		if c == nil {
+			return errors.New("http client cannot be nil")
+		}
+		v.httpClient = c
+		return nil
+	}
+}
+
+// OptionAudience requires tokens to have the given audience in their aud
+// claim.
+func OptionAudience(audience string) Option {
+	return func(v *Verifier) error {
+		v.audience = audience
+		return nil
+	}
+}
+
+// OptionIssuer requires tokens to have the given iss claim.
+func OptionIssuer(issuer string) Option {
+	return func(v *Verifier) error {
+		v.issuer = issuer
+		return nil
+	}
+}
+
+// OptionLeeway sets the clock skew tolerated when checking the exp and nbf
+// claims. Defaults to 1m.
+func OptionLeeway(leeway time.Duration) Option {
+	return func(v *Verifier) error {
+		if leeway < 0 {
+			return errors.New("leeway cannot be negative")
+		}
+		v.leeway = leeway
+		return nil
+	}
+}
+
+// OptionMinRefreshInterval sets how often at most the keys are fetched again
+// when a token is signed by an unknown key. Defaults to 10s.
+func OptionMinRefreshInterval(interval time.Duration) Option {
+	return func(v *Verifier) error {
+		if interval < 0 {
+			return errors.New("refresh interval cannot be negative")
+		}
+		v.minRefreshInterval = interval
+		return nil
+	}
+}
+
+// NewVerifier returns a Verifier. A key source is required, see OptionJWKSURL
+// and OptionPublicKey.
+func NewVerifier(options ...Option) (*Verifier, error) {
+	v := &Verifier{
+		httpClient:         &http.Client{Timeout: defaultFetchTimeout},
+		leeway:             jwt.DefaultLeeway,
+		minRefreshInterval: defaultMinRefreshInterval,
+	}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		if err := option(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v.jwksURL == "" && len(v.static) == 0 {
+		return nil, ErrNoKeySource
+	}
+	return v, nil
+}
+
+// TokenFromRequest returns the token of the request, from the Authorization
+// header, in either the "token=" form used by DC/OS or the Bearer form, or
+// else from the cookie set by the UI. It returns an empty string if there is
+// none.
+func TokenFromRequest(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	for _, prefix := range []string{"token=", "Bearer "} {
+		if strings.HasPrefix(auth, prefix) {
+			return strings.TrimPrefix(auth, prefix)
+		}
+	}
+	if c, err := r.Cookie(authCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// Verify verifies the signature and the claims of the token and returns its
+// claims. If the token is signed by an unknown key and the keys are fetched
+// from a JWKS URL, they are fetched again in case they were rotated.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	if token == "" {
+		return nil, ErrNoToken
+	}
+
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.Headers) != 1 {
+		return nil, errors.New("token must have a single signature")
+	}
+	header := parsed.Headers[0]
+	if alg := jose.SignatureAlgorithm(header.Algorithm); !algorithms[alg] {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", header.Algorithm)
+	}
+
+	claims, err := v.verifySignature(parsed, v.knownKeys(header.KeyID))
+	if err == ErrUnknownKey && v.jwksURL != "" {
+		var keys []jose.JSONWebKey
+		if keys, err = v.refresh(ctx, header.KeyID); err != nil {
+			return nil, err
+		}
+		claims, err = v.verifySignature(parsed, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Expiry == 0 {
+		return nil, ErrNoExpiry
+	}
+	expected := jwt.Expected{Issuer: v.issuer, Time: time.Now()}
+	if v.audience != "" {
+		expected.Audience = jwt.Audience{v.audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, v.leeway); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verifySignature returns the claims of the token if it is signed by one of
+// the keys, or ErrUnknownKey.
+func (v *Verifier) verifySignature(token *jwt.JSONWebToken, keys []jose.JSONWebKey) (*Claims, error) {
+	for _, key := range keys {
+		var claims Claims
+		if err := token.Claims(key.Key, &claims); err == nil {
+			return &claims, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// matchKeys returns the keys with the given key ID, or all keys if it is empty.
+func matchKeys(keys []jose.JSONWebKey, keyID string) []jose.JSONWebKey {
+	var matched []jose.JSONWebKey
+	for _, key := range keys {
+		if keyID == "" || key.KeyID == "" || key.KeyID == keyID {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+// knownKeys returns the static and fetched keys matching the key ID.
+func (v *Verifier) knownKeys(keyID string) []jose.JSONWebKey {
+	v.Lock()
+	defer v.Unlock()
+	return append(matchKeys(v.static, keyID), matchKeys(v.keys, keyID)...)
+}
+
+// refresh fetches the keys from the JWKS URL and returns the ones matching
+// the key ID. Concurrent callers wait for a single fetch, which does not run
+// with the context of any caller and is only bounded by the timeout of the
+// HTTP client; callers give up waiting when their own context is done. The
+// keys are not fetched again if they were fetched less than the minimum
+// refresh interval ago, whether that succeeded or not.
+func (v *Verifier) refresh(ctx context.Context, keyID string) ([]jose.JSONWebKey, error) {
+	v.Lock()
+	call := v.call
+	if call == nil {
+		if !v.attempted.IsZero() && time.Since(v.attempted) < v.minRefreshInterval {
+			// the keys may have been fetched since the caller looked them up.
+			keys := matchKeys(v.keys, keyID)
+			v.Unlock()
+			return keys, nil
+		}
+
+		call = &refreshCall{done: make(chan struct{})}
+		v.call = call
+		go v.refreshKeys(call)
+	}
+	v.Unlock()
+
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	select {
+	case <-call.done:
+	case <-done:
+		return nil, ctx.Err()
+	}
+
+	if call.err != nil {
+		return nil, call.err
+	}
+	return matchKeys(call.keys, keyID), nil
+}
+
+// refreshKeys runs the fetch of the call and stores the keys if it succeeds.
+// The fetch counts toward the minimum refresh interval once it completed.
+func (v *Verifier) refreshKeys(call *refreshCall) {
+	start := time.Now()
+	call.keys, call.err = v.fetch(context.Background())
+
+	v.Lock()
+	if call.err == nil {
+		v.keys = call.keys
+	}
+	v.attempted = start
+	v.call = nil
+	v.Unlock()
+	close(call.done)
+}
+
+// fetch returns the public keys of the JWKS endpoint.
+func (v *Verifier) fetch(ctx context.Context) ([]jose.JSONWebKey, error) {
+	req, err := http.NewRequest("GET", v.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s failed, expect response code 200. Got %d", v.jwksURL, resp.StatusCode)
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	var keys []jose.JSONWebKey
+	for _, key := range set.Keys {
+		if key.IsPublic() && (key.Use == "" || key.Use == "sig") {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,342 @@
+package jwtverify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// sign returns a token with the given claims signed by the key.
+func sign(t *testing.T, alg jose.SignatureAlgorithm, keyID string, key interface{}, claims interface{}) string {
+	opts := (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", keyID)
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(sig).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func validClaims(uid string) Claims {
+	return Claims{
+		UID:    uid,
+		Claims: jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}
+}
+
+// jwksServer serves the public keys of a JWKS endpoint, which can be replaced
+// to simulate a key rotation. The caller must close the server.
+type jwksServer struct {
+	*httptest.Server
+	sync.Mutex
+	keys     []jose.JSONWebKey
+	requests int32
+}
+
+func newJWKSServer() *jwksServer {
+	s := &jwksServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		s.Lock()
+		defer s.Unlock()
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: s.keys})
+	}))
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...jose.JSONWebKey) {
+	s.Lock()
+	defer s.Unlock()
+	s.keys = keys
+}
+
+func TestVerifyJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newJWKSServer()
+	defer s.Close()
+	s.setKeys(jose.JSONWebKey{Key: &rsaKey.PublicKey, KeyID: "rsa", Algorithm: "RS256", Use: "sig"})
+
+	v, err := NewVerifier(OptionJWKSURL(s.URL+DefaultJWKSPath), OptionMinRefreshInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := v.Verify(context.Background(), sign(t, jose.RS256, "rsa", rsaKey, validClaims("alice")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UID != "alice" {
+		t.Fatalf("expect uid alice. Got %s", claims.UID)
+	}
+
+	// known keys are not fetched again.
+	if _, err := v.Verify(context.Background(), sign(t, jose.RS256, "rsa", rsaKey, validClaims("alice"))); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&s.requests); n != 1 {
+		t.Fatalf("expect the keys to be fetched once. Got %d", n)
+	}
+
+	// the keys were rotated.
+	s.setKeys(jose.JSONWebKey{Key: &ecKey.PublicKey, KeyID: "ec", Algorithm: "ES256", Use: "sig"})
+	if _, err := v.Verify(context.Background(), sign(t, jose.ES256, "ec", ecKey, validClaims("bob"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, jose.RS256, "rsa", rsaKey, validClaims("alice"))); err != ErrUnknownKey {
+		t.Fatalf("expect ErrUnknownKey for a removed key. Got %v", err)
+	}
+}
+
+func TestVerifyMinRefreshInterval(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newJWKSServer()
+	defer s.Close()
+
+	v, err := NewVerifier(OptionJWKSURL(s.URL), OptionMinRefreshInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(context.Background(), sign(t, jose.ES256, "unknown", key, validClaims("bob"))); err != ErrUnknownKey {
+			t.Fatalf("expect ErrUnknownKey. Got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&s.requests); n != 1 {
+		t.Fatalf("expect the keys to be fetched at most once per interval. Got %d", n)
+	}
+}
+
+func TestVerifyFailedRefresh(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	v, err := NewVerifier(OptionJWKSURL(ts.URL), OptionMinRefreshInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jose.ES256, "ec", key, validClaims("bob"))
+	if _, err := v.Verify(context.Background(), token); err == nil || err == ErrUnknownKey {
+		t.Fatalf("expect the fetch to fail. Got %v", err)
+	}
+	if _, err := v.Verify(context.Background(), token); err != ErrUnknownKey {
+		t.Fatalf("expect ErrUnknownKey. Got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expect failed fetches to be rate limited. Got %d requests", n)
+	}
+}
+
+func TestVerifyConcurrentRefresh(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newJWKSServer()
+	defer s.Close()
+	s.setKeys(jose.JSONWebKey{Key: &key.PublicKey, KeyID: "ec", Use: "sig"})
+
+	v, err := NewVerifier(OptionJWKSURL(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jose.ES256, "ec", key, validClaims("bob"))
+
+	// the server blocks until it is unlocked.
+	s.Lock()
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := v.Verify(context.Background(), token)
+			errs <- err
+		}()
+	}
+	for atomic.LoadInt32(&s.requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the verifier is not locked while the keys are fetched.
+	known := make(chan struct{})
+	go func() {
+		v.knownKeys("ec")
+		close(known)
+	}()
+	select {
+	case <-known:
+	case <-time.After(5 * time.Second):
+		s.Unlock()
+		t.Fatal("expect the known keys to be available during a fetch")
+	}
+	s.Unlock()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&s.requests); n != 1 {
+		t.Fatalf("expect concurrent callers to share a fetch. Got %d requests", n)
+	}
+}
+
+func TestVerifyCanceledRefresh(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newJWKSServer()
+	defer s.Close()
+	s.setKeys(jose.JSONWebKey{Key: &key.PublicKey, KeyID: "ec", Use: "sig"})
+
+	v, err := NewVerifier(OptionJWKSURL(s.URL), OptionMinRefreshInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jose.ES256, "ec", key, validClaims("bob"))
+
+	// the first caller is canceled while the server blocks the fetch.
+	s.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := v.Verify(ctx, token)
+		errc <- err
+	}()
+	for atomic.LoadInt32(&s.requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		s.Unlock()
+		t.Fatalf("expect %v. Got %v", context.Canceled, err)
+	}
+
+	// a second caller still gets the keys of the shared fetch.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.Unlock()
+	}()
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&s.requests); n != 1 {
+		t.Fatalf("expect the fetch to survive the canceled caller. Got %d requests", n)
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(OptionPublicKey("", &key.PublicKey), OptionAudience("my-service"), OptionLeeway(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := validClaims("bob")
+	valid.Audience = jwt.Audience{"my-service"}
+	if _, err := v.Verify(context.Background(), sign(t, jose.ES256, "", key, valid)); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := valid
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExpiry := valid
+	noExpiry.Expiry = 0
+	otherAudience := valid
+	otherAudience.Audience = jwt.Audience{"other"}
+
+	for _, tc := range []struct {
+		claims Claims
+		err    error
+	}{
+		{expired, jwt.ErrExpired},
+		{noExpiry, ErrNoExpiry},
+		{otherAudience, jwt.ErrInvalidAudience},
+	} {
+		if _, err := v.Verify(context.Background(), sign(t, jose.ES256, "", key, tc.claims)); err != tc.err {
+			t.Fatalf("expect %v. Got %v", tc.err, err)
+		}
+	}
+
+	if _, err := v.Verify(context.Background(), ""); err != ErrNoToken {
+		t.Fatalf("expect ErrNoToken. Got %v", err)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, jose.HS256, "", []byte("secret"), valid)); err == nil {
+		t.Fatal("expect HS256 tokens to be refused")
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	if _, err := NewVerifier(); err != ErrNoKeySource {
+		t.Fatalf("expect ErrNoKeySource. Got %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewVerifier(OptionPublicKey("", key)); err == nil {
+		t.Fatal("expect an error for a private key")
+	}
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	for _, tc := range []struct {
+		header, cookie, expected string
+	}{
+		{"token=abc", "", "abc"},
+		{"Bearer abc", "", "abc"},
+		{"", "abc", "abc"},
+		{"Basic abc", "", ""},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		if tc.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: authCookie, Value: tc.cookie})
+		}
+		if token := TokenFromRequest(r); token != tc.expected {
+			t.Fatalf("expect %q. Got %q", tc.expected, token)
+		}
+	}
+}
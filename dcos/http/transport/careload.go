@@ -0,0 +1,120 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// caReloader verifies server certificates against a CA certificate that is
+// reloaded when the file changes, so long running processes pick up rotated
+// CA bundles. The file is checked at most once per interval, when a
+// connection is established.
+type caReloader struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	pool    *x509.CertPool
+	modTime time.Time
+	checked time.Time
+}
+
+func newCAReloader(path string, interval time.Duration) (*caReloader, error) {
+	r := &caReloader{path: path, interval: interval}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the CA certificate if it changed since it was last loaded.
+func (r *caReloader) reload() error {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	if r.pool != nil && fi.ModTime().Equal(r.modTime) {
+		return nil
+	}
+
+	pool, err := loadCAPool(r.path)
+	if err != nil {
+		return err
+	}
+	r.pool = pool
+	r.modTime = fi.ModTime()
+	return nil
+}
+
+// current returns the CA pool, reloading it first if the interval elapsed.
+// If the CA certificate cannot be reloaded, e.g. while it is being replaced,
+// the previous one is used.
+func (r *caReloader) current() *x509.CertPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now := time.Now(); now.Sub(r.checked) >= r.interval {
+		r.checked = now
+		r.reload()
+	}
+	return r.pool
+}
+
+// configure makes the transport establish TLS connections verified against
+// the current CA pool. Connections through a proxy are established by the
+// transport itself and verified against its static RootCAs, i.e. the CA
+// certificate loaded initially.
+func (r *caReloader) configure(tr *http.Transport) {
+	tr.DialTLS = func(network, addr string) (net.Conn, error) {
+		// the configuration is cloned for every connection, HTTP/2 may
+		// have been configured after the reloader.
+		cfg := tr.TLSClientConfig.Clone()
+		cfg.RootCAs = r.current()
+		if cfg.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			cfg.ServerName = host
+		}
+
+		dial := tr.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		conn, err := dial(context.Background(), network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if tr.TLSHandshakeTimeout > 0 {
+			conn.SetDeadline(time.Now().Add(tr.TLSHandshakeTimeout))
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return tlsConn, nil
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOptionCAReloadInterval(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "transport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the CA certificate does not match the server certificate at first.
+	caPath := filepath.Join(dir, "ca.crt")
+	wrongCA, err := ioutil.ReadFile(CACertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(caPath, wrongCA, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(OptionCaCertificatePath(caPath), OptionCAReloadInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	if _, err := client.Get(ts.URL); err == nil {
+		t.Fatal("Expected an error verifying the server certificate, got nil")
+	}
+
+	// rotate the CA certificate.
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := ioutil.WriteFile(caPath, serverCA, 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(caPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Expected the rotated CA certificate to be used, got %s", err)
+	}
+	resp.Body.Close()
+
+	// an unreadable CA certificate keeps the previous one.
+	if err := ioutil.WriteFile(caPath, []byte("invalid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(caPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	tr.(*http.Transport).CloseIdleConnections()
+
+	resp, err = client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Expected the previous CA certificate to be used, got %s", err)
+	}
+	resp.Body.Close()
+
+	if _, err := NewTransport(OptionCAReloadInterval(time.Second)); err == nil {
+		t.Fatal("Expected an error without a CA certificate path, got nil")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+// errUnsupportedKey is returned by parsePrivateKey for keys that cannot sign service login tokens.
+var errUnsupportedKey = errors.New("private key must be an RSA or an ECDSA P-256, P-384 or P-521 key")
+
+// parsePrivateKey parses the private key of a service account, either PEM encoded in PKCS #8, PKCS #1 or SEC 1
+// form, or a JSON web key. It returns the key and the algorithm to sign tokens with: RS256 for RSA keys, and
+// ES256, ES384 or ES512 for ECDSA keys depending on their curve.
+func parsePrivateKey(secret []byte) (interface{}, jose.SignatureAlgorithm, error) {
+	var key interface{}
+	if block, _ := pem.Decode(secret); block != nil {
+		var err error
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		var jwk jose.JSONWebKey
+		if err := json.Unmarshal(secret, &jwk); err != nil {
+			return nil, "", err
+		}
+		if jwk.IsPublic() {
+			return nil, "", errUnsupportedKey
+		}
+		key = jwk.Key
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, jose.RS256, nil
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return key, jose.ES256, nil
+		case elliptic.P384():
+			return key, jose.ES384, nil
+		case elliptic.P521():
+			return key, jose.ES512, nil
+		}
+	}
+	return nil, "", errUnsupportedKey
+}
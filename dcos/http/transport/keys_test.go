@@ -0,0 +1,137 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	must := func(b []byte, err error) []byte {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	encode := func(typ string, b []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b})
+	}
+
+	for _, tc := range []struct {
+		secret []byte
+		alg    jose.SignatureAlgorithm
+	}{
+		{encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), jose.RS256},
+		{encode("PRIVATE KEY", must(x509.MarshalPKCS8PrivateKey(rsaKey))), jose.RS256},
+		{encode("EC PRIVATE KEY", must(x509.MarshalECPrivateKey(ecKey))), jose.ES256},
+		{encode("PRIVATE KEY", must(x509.MarshalPKCS8PrivateKey(ecKey))), jose.ES256},
+		{encode("EC PRIVATE KEY", must(x509.MarshalECPrivateKey(p384Key))), jose.ES384},
+		{must(json.Marshal(jose.JSONWebKey{Key: ecKey})), jose.ES256},
+	} {
+		if _, alg, err := parsePrivateKey(tc.secret); err != nil || alg != tc.alg {
+			t.Fatalf("Expected %s, got %s, %v", tc.alg, alg, err)
+		}
+	}
+
+	for _, secret := range [][]byte{
+		must(json.Marshal(jose.JSONWebKey{Key: &ecKey.PublicKey})),
+		encode("PUBLIC KEY", must(x509.MarshalPKIXPublicKey(&ecKey.PublicKey))),
+		[]byte("not a key"),
+	} {
+		if _, _, err := parsePrivateKey(secret); err == nil {
+			t.Fatalf("Expected an error for %s", secret)
+		}
+	}
+}
+
+func TestReadIAMConfigECKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loginToken string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var login struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(r.Body).Decode(&login)
+		loginToken = login.Token
+		w.Write([]byte(`{"token": "token"}`))
+	}))
+	defer ts.Close()
+
+	// the private key is a JSON web key rather than a PEM encoded string.
+	cfg, err := json.Marshal(map[string]interface{}{
+		"uid":            "test",
+		"private_key":    jose.JSONWebKey{Key: key},
+		"login_endpoint": ts.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "transport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "service_account.json")
+	if err := ioutil.WriteFile(path, cfg, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewRoundTripper(nil, OptionReadIAMConfig(path)); err != nil {
+		t.Fatal(err)
+	}
+
+	jws, err := jose.ParseSigned(loginToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alg := jws.Signatures[0].Header.Algorithm; alg != string(jose.ES256) {
+		t.Fatalf("Expected the login token to be signed with ES256, got %s", alg)
+	}
+	if _, err := jws.Verify(&key.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+}
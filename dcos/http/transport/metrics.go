@@ -0,0 +1,74 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"net/http"
+	"time"
+)
+
+// Metrics receives measurements of the requests sent by the transport, and may
+// be used to feed them into a metrics library of choice. Implementations must
+// be safe for concurrent use, and should not block. Metrics are configured
+// with OptionMetrics.
+type Metrics interface {
+	// Request is called after every attempt to send a request, with the host
+	// and path template of the request, the status code of the response (0 if
+	// there is none), the time it took to receive the response headers and
+	// the error returned by the underlying transport, if any.
+	Request(host, path string, statusCode int, d time.Duration, err error)
+}
+
+// metricsRoundTripper reports every request to Metrics.
+type metricsRoundTripper struct {
+	transport    http.RoundTripper
+	metrics      Metrics
+	pathTemplate func(*http.Request) string
+}
+
+// RoundTrip is implementation of RoundTripper interface.
+func (t *metricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+	d := time.Since(start)
+
+	path := req.URL.Path
+	if t.pathTemplate != nil {
+		path = t.pathTemplate(req)
+	}
+
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+
+	t.metrics.Request(req.URL.Host, path, statusCode, d, err)
+	return resp, err
+}
+
+// AuthMetrics receives measurements of the authentication of requests by the round tripper returned by
+// NewRoundTripper. Like Metrics, implementations must be safe for concurrent use, and should not block. AuthMetrics
+// are configured with OptionAuthMetrics, or by passing Metrics that also implement AuthMetrics to OptionMetrics.
+type AuthMetrics interface {
+	// TokenRefresh is called after every login request to obtain a new token, with the time it took and its error,
+	// if any.
+	TokenRefresh(d time.Duration, err error)
+
+	// TokenAge is called before sending every request with the time since its token was obtained.
+	TokenAge(age time.Duration)
+
+	// AuthFailure is called for every response with status code 401 or 403.
+	AuthFailure(statusCode int)
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recordedRequest struct {
+	path       string
+	statusCode int
+	err        bool
+}
+
+type testMetrics struct {
+	sync.Mutex
+	requests []recordedRequest
+}
+
+func (m *testMetrics) Request(host, path string, statusCode int, d time.Duration, err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.requests = append(m.requests, recordedRequest{path: path, statusCode: statusCode, err: err != nil})
+}
+
+func TestOptionMetrics(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	metrics := &testMetrics{}
+	pathTemplate := func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/v1/jobs/") {
+			return "/v1/jobs/{id}"
+		}
+		return r.URL.Path
+	}
+	tr, err := NewTransport(
+		OptionMetrics(metrics, pathTemplate),
+		OptionRetry(RetryPolicy{InitialBackoff: time.Millisecond, MaxAttempts: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&http.Client{Transport: tr}).Get(ts.URL + "/v1/jobs/1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// requests to a closed port fail without a response.
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	tr, err = NewTransport(OptionMetrics(metrics, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&http.Client{Transport: tr}).Get(closed.URL + "/health"); err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	expected := []recordedRequest{
+		{path: "/v1/jobs/{id}", statusCode: http.StatusServiceUnavailable},
+		{path: "/v1/jobs/{id}", statusCode: http.StatusOK},
+		{path: "/health", err: true},
+	}
+	if !reflect.DeepEqual(metrics.requests, expected) {
+		t.Fatalf("Expected requests %+v, got %+v", expected, metrics.requests)
+	}
+
+	if _, err := NewTransport(OptionMetrics(nil, nil)); err == nil {
+		t.Fatal("Expected error with nil metrics, got nil")
+	}
+}
@@ -15,14 +15,17 @@
 package transport
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
+	"crypto/tls"
 	"encoding/json"
-	"encoding/pem"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/dcos/dcos-go/dcoslog"
 )
 
 var (
@@ -32,6 +35,14 @@ var (
 	// ErrInvalidUserAgent is the error returned by NewRoundTripper if user used empty string for a user agent.
 	ErrInvalidUserAgent = errors.New("userAgent cannot be empty")
 
+	// ErrInvalidRetryPolicy is the error returned by NewTransport if the retry policy allows no attempt or has a
+	// negative backoff.
+	ErrInvalidRetryPolicy = errors.New("retry policy must allow at least one attempt with a non negative backoff")
+
+	// ErrInsecureTLS is the error returned by NewTransport if OptionStrictTLS is combined with options that skip the
+	// verification of server certificates.
+	ErrInsecureTLS = errors.New("strict TLS does not allow skipping the verification of server certificates")
+
 	// ErrInvalidExpireDuration is the error returned by NewRoundTripper if the token expire duration is negative or
 	// zero value.
 	ErrInvalidExpireDuration = errors.New("token expire duration must be positive non zero value")
@@ -52,6 +63,13 @@ func errorOnEmpty(arg string) error {
 	return nil
 }
 
+func errorOnNonPositive(arg int64) error {
+	if arg < 1 {
+		return errors.New("Must pass a positive value to this option")
+	}
+	return nil
+}
+
 // OptionCaCertificatePath sets the CA certificate path option.
 func OptionCaCertificatePath(caCertificatePath string) OptionTransportFunc {
 	return func(o *dcosTransport) error {
@@ -63,17 +81,220 @@ func OptionCaCertificatePath(caCertificatePath string) OptionTransportFunc {
 	}
 }
 
+// OptionInsecureSkipVerify is an option to skip the verification of server certificates, e.g. to talk to a
+// cluster using self-signed certificates without its CA certificate. By default server certificates are verified
+// against the CA certificate set with OptionCaCertificatePath, or the system cert pool.
+func OptionInsecureSkipVerify() OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		o.InsecureSkipVerify = true
+		return nil
+	}
+}
+
+// OptionStrictTLS is an option to fail closed: NewTransport returns ErrInsecureTLS if any other option skips the
+// verification of server certificates, and TLS versions older than 1.2 are refused.
+func OptionStrictTLS() OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		o.StrictTLS = true
+		return nil
+	}
+}
+
+// OptionClientCertificate sets the PEM encoded client certificate and key presented to servers requiring TLS client
+// authentication, e.g. Mesos with SSL client auth enabled.
+func OptionClientCertificate(certPath, keyPath string) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		if err := errorOnEmpty(certPath); err != nil {
+			return err
+		}
+		if err := errorOnEmpty(keyPath); err != nil {
+			return err
+		}
+		o.ClientCertificatePath = certPath
+		o.ClientKeyPath = keyPath
+		return nil
+	}
+}
+
+// OptionTLSConfig sets the TLS configuration of the transport. The CA certificate and client certificate options
+// are applied on top of a copy of it, but OptionInsecureSkipVerify is not.
+func OptionTLSConfig(cfg *tls.Config) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		if cfg == nil {
+			return errors.New("Must pass a non-nil TLS configuration to this option")
+		}
+		o.TLSConfig = cfg
+		return nil
+	}
+}
+
+// OptionCAReloadInterval is an option to reload the CA certificate set with OptionCaCertificatePath when the file
+// changes, so long running processes pick up rotated CA bundles without a restart. The file is checked at most once
+// per interval, when a new connection is established. If it cannot be read, the previous CA certificate is used.
+func OptionCAReloadInterval(interval time.Duration) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		err := errorOnNonPositive(int64(interval))
+		if err == nil {
+			o.CAReloadInterval = interval
+		}
+		return err
+	}
+}
+
 // OptionIAMConfigPath sets the IAM configuration path option.
 func OptionIAMConfigPath(iamConfigPath string) OptionTransportFunc {
 	return func(o *dcosTransport) error {
 		err := errorOnEmpty(iamConfigPath)
 		if err == nil {
 			o.IAMConfigPath = iamConfigPath
+			o.IAMConfig = nil
+		}
+		return err
+	}
+}
+
+// OptionIAMConfig sets the content of the IAM configuration, for services that hold their service account secret in
+// memory rather than in a file. It replaces OptionIAMConfigPath.
+func OptionIAMConfig(data []byte) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		if len(data) == 0 {
+			return errors.New("Must pass a non-empty IAM configuration to this option")
+		}
+		o.IAMConfig = data
+		o.IAMConfigPath = ""
+		return nil
+	}
+}
+
+// OptionIAMConfigFromEnv sets the IAM configuration from the content of the environment variable with the given
+// name, e.g. a service account secret of the DC/OS secrets store exposed as EnvServiceAccountCredential. It replaces
+// OptionIAMConfigPath.
+func OptionIAMConfigFromEnv(name string) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		data := os.Getenv(name)
+		if data == "" {
+			return fmt.Errorf("environment variable %s is not set", name)
+		}
+		return OptionIAMConfig([]byte(data))(o)
+	}
+}
+
+// OptionMaxIdleConns sets the maximum number of idle connections across all hosts. Defaults to 100.
+func OptionMaxIdleConns(n int) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		err := errorOnNonPositive(int64(n))
+		if err == nil {
+			o.MaxIdleConns = n
+		}
+		return err
+	}
+}
+
+// OptionMaxIdleConnsPerHost sets the maximum number of idle connections kept per host. Defaults to 32.
+func OptionMaxIdleConnsPerHost(n int) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		err := errorOnNonPositive(int64(n))
+		if err == nil {
+			o.MaxIdleConnsPerHost = n
+		}
+		return err
+	}
+}
+
+// OptionIdleConnTimeout sets how long an idle connection is kept in the pool. Defaults to 90s.
+func OptionIdleConnTimeout(timeout time.Duration) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		err := errorOnNonPositive(int64(timeout))
+		if err == nil {
+			o.IdleConnTimeout = timeout
+		}
+		return err
+	}
+}
+
+// OptionDialTimeout sets the timeout of establishing a TCP connection. Defaults to 30s.
+func OptionDialTimeout(timeout time.Duration) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		err := errorOnNonPositive(int64(timeout))
+		if err == nil {
+			o.DialTimeout = timeout
+		}
+		return err
+	}
+}
+
+// OptionTLSHandshakeTimeout sets the timeout of the TLS handshake. Defaults to 10s.
+func OptionTLSHandshakeTimeout(timeout time.Duration) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		err := errorOnNonPositive(int64(timeout))
+		if err == nil {
+			o.TLSHandshakeTimeout = timeout
 		}
 		return err
 	}
 }
 
+// OptionRequestTimeout sets the time after which a request is canceled, from sending it, including retries, to
+// closing the response body. Requests do not time out by default, see NewClient.
+func OptionRequestTimeout(timeout time.Duration) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		err := errorOnNonPositive(int64(timeout))
+		if err == nil {
+			o.RequestTimeout = timeout
+		}
+		return err
+	}
+}
+
+// OptionResponseHeaderTimeout sets how long to wait for the response headers after sending a request. There is no
+// limit by default, see NewClient.
+func OptionResponseHeaderTimeout(timeout time.Duration) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		err := errorOnNonPositive(int64(timeout))
+		if err == nil {
+			o.ResponseHeaderTimeout = timeout
+		}
+		return err
+	}
+}
+
+// OptionDisableHTTP2 is an option to only use HTTP/1.1. By default HTTP/2 is used with servers supporting it, like
+// Admin Router.
+func OptionDisableHTTP2() OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		o.DisableHTTP2 = true
+		return nil
+	}
+}
+
+// OptionMetrics is an option to report every request to the given Metrics, including retries. Requests are reported
+// with their URL path, unless pathTemplate is set, in which case it is called to return a path template with a
+// bounded number of values, e.g. /v1/jobs/{id} rather than /v1/jobs/1234. If metrics also implement AuthMetrics,
+// they receive the authentication metrics of the IAM configuration, see OptionAuthMetrics.
+func OptionMetrics(metrics Metrics, pathTemplate func(*http.Request) string) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		if metrics == nil {
+			return errors.New("Must pass non-nil metrics to this option")
+		}
+		o.Metrics = metrics
+		o.PathTemplate = pathTemplate
+		return nil
+	}
+}
+
+// OptionRetry is an option to retry idempotent requests that fail with a connection error, a 5xx or a 429
+// response, with an exponential backoff. A Retry-After header in the response overrides the backoff if it is
+// longer, up to the MaxBackoff of the policy. Requests are not retried by default.
+func OptionRetry(policy RetryPolicy) OptionTransportFunc {
+	return func(o *dcosTransport) error {
+		if policy.MaxAttempts < 1 || policy.InitialBackoff < 0 || policy.MaxBackoff < 0 {
+			return ErrInvalidRetryPolicy
+		}
+		o.RetryPolicy = &policy
+		return nil
+	}
+}
+
 // OptionTokenExpire is an option to set JWT expiration date. If not set 24h is ussed by default.
 func OptionTokenExpire(t time.Duration) OptionRoundtripperFunc {
 	return func(j *dcosRoundtripper) error {
@@ -85,7 +306,70 @@ func OptionTokenExpire(t time.Duration) OptionRoundtripperFunc {
 	}
 }
 
-// OptionCredentials is an option to set uid, secret and loginEndpoint.
+// OptionTokenRefreshSkew is an option to set how long before its expiry, read from its exp claim, a token is
+// refreshed. Tokens are refreshed 1m before they expire by default. Tokens without an exp claim are only refreshed
+// when a request returns 401.
+func OptionTokenRefreshSkew(skew time.Duration) OptionRoundtripperFunc {
+	return func(j *dcosRoundtripper) error {
+		if skew < 0 {
+			return errors.New("Must pass a non negative value to this option")
+		}
+		j.refreshSkew = skew
+		return nil
+	}
+}
+
+// OptionTokenCache is an option to share tokens with the other round trippers using the same TokenCache. Round
+// trippers with the same uid and login endpoint then log in once instead of once each.
+func OptionTokenCache(cache *TokenCache) OptionRoundtripperFunc {
+	return func(j *dcosRoundtripper) error {
+		if cache == nil {
+			return errors.New("Must pass a non-nil token cache to this option")
+		}
+		j.tokens = cache
+		return nil
+	}
+}
+
+// OptionLoginTimeout is an option to set how long a login request to obtain a token may take. The login is shared
+// by concurrent requests and is not canceled with their context, a canceled request only stops waiting for it.
+// Logins time out after 30s by default.
+func OptionLoginTimeout(timeout time.Duration) OptionRoundtripperFunc {
+	return func(j *dcosRoundtripper) error {
+		err := errorOnNonPositive(int64(timeout))
+		if err == nil {
+			j.loginTimeout = timeout
+		}
+		return err
+	}
+}
+
+// OptionAuthMetrics is an option to report token refreshes, token ages and requests rejected with 401 or 403 to the
+// given AuthMetrics.
+func OptionAuthMetrics(metrics AuthMetrics) OptionRoundtripperFunc {
+	return func(j *dcosRoundtripper) error {
+		if metrics == nil {
+			return errors.New("Must pass non-nil metrics to this option")
+		}
+		j.authMetrics = metrics
+		return nil
+	}
+}
+
+// OptionAuthRetry is an option to recover from requests rejected with 401 or 403, e.g. after bouncer rotated its
+// keys: a new token is obtained and idempotent requests are replayed once. If the replay is rejected again,
+// RoundTrip returns ErrUnauthorized. Without this option, requests returning 401 are sent again once, whatever
+// their method, and 403 responses are returned as is.
+func OptionAuthRetry() OptionRoundtripperFunc {
+	return func(j *dcosRoundtripper) error {
+		j.authRetry = true
+		return nil
+	}
+}
+
+// OptionCredentials is an option to set uid, secret and loginEndpoint. The secret is the private key of the service
+// account, an RSA or ECDSA key either PEM encoded or as a JSON web key. Login tokens are signed with RS256 for RSA
+// keys and ES256 for P-256 keys.
 func OptionCredentials(uid, secret, loginEndpoint string) OptionRoundtripperFunc {
 	return func(j *dcosRoundtripper) error {
 		if uid == "" || secret == "" || loginEndpoint == "" {
@@ -94,20 +378,30 @@ func OptionCredentials(uid, secret, loginEndpoint string) OptionRoundtripperFunc
 		j.uid = uid
 		j.loginEndpoint = loginEndpoint
 
-		block, _ := pem.Decode([]byte(secret))
-		if block == nil {
-			return ErrInvalidCredentials
-		}
-		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		key, alg, err := parsePrivateKey([]byte(secret))
 		if err != nil {
 			return ErrInvalidCredentials
 		}
-		if key, ok := key.(*rsa.PrivateKey); ok {
-			j.secret = key
-			return nil
-		}
+		j.secret = key
+		j.algorithm = alg
+		j.password = ""
+		return nil
+	}
+}
 
-		return ErrInvalidCredentials
+// OptionUserCredentials is an option to log in as a DC/OS user with a username and password instead of a
+// service account. The session token returned by the login endpoint is cached and renewed by logging in again
+// when a request returns 401.
+func OptionUserCredentials(uid, password, loginEndpoint string) OptionRoundtripperFunc {
+	return func(j *dcosRoundtripper) error {
+		if uid == "" || password == "" || loginEndpoint == "" {
+			return ErrInvalidCredentials
+		}
+		j.uid = uid
+		j.password = password
+		j.loginEndpoint = loginEndpoint
+		j.secret = nil
+		return nil
 	}
 }
 
@@ -122,6 +416,15 @@ func OptionUserAgent(userAgent string) OptionRoundtripperFunc {
 	}
 }
 
+// OptionLogger is an option to set a logger which receives messages about token generation.
+// By default nothing is logged.
+func OptionLogger(logger dcoslog.Logger) OptionRoundtripperFunc {
+	return func(j *dcosRoundtripper) error {
+		j.logger = dcoslog.OrNop(logger)
+		return nil
+	}
+}
+
 // OptionReadIAMConfig is an option to read the IAMConfig from file system and populate uid, secret and loginEndpoint.
 func OptionReadIAMConfig(path string) OptionRoundtripperFunc {
 	return func(j *dcosRoundtripper) error {
@@ -136,16 +439,32 @@ func OptionReadIAMConfig(path string) OptionRoundtripperFunc {
 			return err
 		}
 
+		return OptionParseIAMConfig(fileContent)(j)
+	}
+}
+
+// OptionParseIAMConfig is an option to populate uid, secret and loginEndpoint from the content of an IAMConfig, e.g.
+// a service account secret held in memory rather than in a file.
+func OptionParseIAMConfig(data []byte) OptionRoundtripperFunc {
+	return func(j *dcosRoundtripper) error {
+		// the private key is either a PEM encoded string or a JSON web key.
 		var cfg = struct {
-			UID           string `json:"uid"`
-			Secret        string `json:"private_key"`
-			LoginEndpoint string `json:"login_endpoint"`
+			UID           string          `json:"uid"`
+			Secret        json.RawMessage `json:"private_key"`
+			LoginEndpoint string          `json:"login_endpoint"`
 		}{}
 
-		if err := json.Unmarshal(fileContent, &cfg); err != nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
 			return err
 		}
 
-		return OptionCredentials(cfg.UID, cfg.Secret, cfg.LoginEndpoint)(j)
+		secret := string(cfg.Secret)
+		if strings.HasPrefix(secret, `"`) {
+			if err := json.Unmarshal(cfg.Secret, &secret); err != nil {
+				return err
+			}
+		}
+
+		return OptionCredentials(cfg.UID, secret, cfg.LoginEndpoint)(j)
 	}
 }
@@ -0,0 +1,167 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how the transport retries failing requests, see
+// OptionRetry. The backoff doubles after every failed attempt, starting at
+// InitialBackoff, up to MaxBackoff.
+type RetryPolicy struct {
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff, including delays requested with a
+	// Retry-After header. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// MaxAttempts is the number of attempts after which a request is no
+	// longer retried, including the first one.
+	MaxAttempts int
+}
+
+// DefaultRetryPolicy is a reasonable policy for requests to DC/OS components.
+var DefaultRetryPolicy = RetryPolicy{
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	MaxAttempts:    5,
+}
+
+// backoff returns how long to wait after the given number of failed attempts.
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempts && d > 0 && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// wait returns how long to wait after the given number of failed attempts,
+// the last of which returned resp. A longer delay requested by the response
+// is honored up to MaxBackoff.
+func (p RetryPolicy) wait(attempts int, resp *http.Response) time.Duration {
+	d := p.backoff(attempts)
+	if after := retryAfter(resp); after > d {
+		d = after
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// maxDrain is how much of a discarded response body is read so that the
+// connection can be reused. Larger bodies are not worth reading, the
+// connection is closed instead.
+const maxDrain = 64 << 10
+
+// retryRoundTripper retries idempotent requests that fail with a connection
+// error, a 5xx or a 429 response.
+type retryRoundTripper struct {
+	transport http.RoundTripper
+	policy    RetryPolicy
+}
+
+// idempotent returns whether the request can be retried safely, that is if its
+// method is idempotent and its body can be sent again.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryable returns whether a request that returned the given response or
+// error should be retried.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// response, in seconds or as an HTTP date, or 0 if there is none.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// RoundTrip is implementation of RoundTripper interface.
+func (t *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req) {
+		return t.transport.RoundTrip(req)
+	}
+
+	for attempts := 1; ; attempts++ {
+		attempt := req
+		if attempts > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt = req.WithContext(req.Context())
+			attempt.Body = body
+		}
+
+		resp, err := t.transport.RoundTrip(attempt)
+		if !retryable(resp, err) || attempts >= t.policy.MaxAttempts {
+			return resp, err
+		}
+
+		wait := t.policy.wait(attempts, resp)
+
+		// the response is discarded, drain it so the connection can be reused.
+		if resp != nil {
+			io.CopyN(ioutil.Discard, resp.Body, maxDrain)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOptionRetry(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write(body)
+		}
+	}))
+	defer ts.Close()
+
+	tr, err := NewTransport(OptionRetry(RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxAttempts: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	req, err := http.NewRequest("PUT", ts.URL, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected response code 200, got %d", resp.StatusCode)
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "payload" {
+		t.Fatalf("Expected the body to be sent again, got %q", body)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("Expected 3 requests, got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("Expected to wait for Retry-After, took %s", elapsed)
+	}
+}
+
+func TestOptionRetryGivesUp(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	tr, err := NewTransport(OptionRetry(RetryPolicy{InitialBackoff: time.Millisecond, MaxAttempts: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Expected the last response, got %d", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("Expected 3 requests, got %d", n)
+	}
+
+	// POST is not idempotent.
+	atomic.StoreInt32(&requests, 0)
+	resp, err = client.Post(ts.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("Expected 1 request, got %d", n)
+	}
+
+	// the context of the request stops retries.
+	tr, err = NewTransport(OptionRetry(RetryPolicy{InitialBackoff: time.Hour, MaxAttempts: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req.WithContext(ctx)); err != context.DeadlineExceeded {
+		t.Fatalf("Expected error %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	if _, err := NewTransport(OptionRetry(RetryPolicy{})); err != ErrInvalidRetryPolicy {
+		t.Fatalf("Expected error %s, got %v", ErrInvalidRetryPolicy, err)
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	retryAfter := func(header string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": []string{header}}}
+	}
+	for _, tc := range []struct {
+		policy   RetryPolicy
+		attempts int
+		resp     *http.Response
+		expected time.Duration
+	}{
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 3, nil, 4 * time.Second},
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 50, nil, 5 * time.Second},
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 1, retryAfter("3"), 3 * time.Second},
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 1, retryAfter("3600"), 5 * time.Second},
+		// zero MaxBackoff caps neither the backoff nor Retry-After.
+		{RetryPolicy{InitialBackoff: time.Second}, 4, nil, 8 * time.Second},
+		{RetryPolicy{InitialBackoff: time.Second}, 1000, nil, math.MaxInt64},
+		{RetryPolicy{InitialBackoff: time.Second}, 1, retryAfter("3600"), time.Hour},
+	} {
+		if d := tc.policy.wait(tc.attempts, tc.resp); d != tc.expected {
+			t.Fatalf("Expected a wait of %s after %d attempts with %+v, got %s", tc.expected, tc.attempts, tc.policy, d)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for header, expected := range map[string]time.Duration{
+		"":        0,
+		"3":       3 * time.Second,
+		"invalid": 0,
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if header != "" {
+			resp.Header.Set("Retry-After", header)
+		}
+		if d := retryAfter(resp); d != expected {
+			t.Fatalf("Expected %s for Retry-After %q, got %s", expected, header, d)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d := retryAfter(resp); d <= 0 || d > time.Minute {
+		t.Fatalf("Expected a delay of up to a minute, got %s", d)
+	}
+}
@@ -16,14 +16,16 @@ package transport
 
 import (
 	"bytes"
-	"crypto/rsa"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
-	"sync"
 	"time"
 
+	"github.com/dcos/dcos-go/dcoslog"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 )
@@ -37,6 +39,18 @@ func (e ErrTokenRefresh) Error() string {
 	return e.msg
 }
 
+// ErrUnauthorized is an error type returned by `RoundTrip` with OptionAuthRetry if a request is rejected with 401 or
+// 403 again after refreshing the token.
+type ErrUnauthorized struct {
+	// StatusCode is the status code of the rejected request.
+	StatusCode int
+	msg        string
+}
+
+func (e ErrUnauthorized) Error() string {
+	return e.msg
+}
+
 var (
 	// ErrEmptyToken returned by `GenerateToken` if signed string returned empty string.
 	ErrEmptyToken = errors.New("Empty token")
@@ -46,13 +60,19 @@ var (
 )
 
 type dcosRoundtripper struct {
-	sync.Mutex
-	token              string
+	tokens             *TokenCache
+	refreshSkew        time.Duration
 	expire             time.Duration
 	uid, loginEndpoint string
 	userAgent          string
-	secret             *rsa.PrivateKey
+	authRetry          bool
+	loginTimeout       time.Duration
+	authMetrics        AuthMetrics
+	secret             interface{} // *rsa.PrivateKey or *ecdsa.PrivateKey
+	algorithm          jose.SignatureAlgorithm
+	password           string
 	transport          http.RoundTripper
+	logger             dcoslog.Logger
 }
 
 // Debug is an interface which defines methods to generate a token and get the latest generated token.
@@ -61,14 +81,19 @@ type Debug interface {
 	CurrentToken() string
 }
 
-// NewRoundTripper returns RoundTripper implementation with JWT handling.
+// NewRoundTripper returns RoundTripper implementation with JWT handling. The RoundTripper is safe for concurrent use:
+// concurrent requests share the cached token, and when it must be refreshed they wait for a single login and are
+// all sent with the new token.
 func NewRoundTripper(rt http.RoundTripper, opts ...OptionRoundtripperFunc) (http.RoundTripper, error) {
 	if rt == nil {
 		rt = http.DefaultTransport
 	}
 
 	t := &dcosRoundtripper{
-		transport: rt,
+		transport:    rt,
+		logger:       dcoslog.Nop(),
+		refreshSkew:  defaultTokenRefreshSkew,
+		loginTimeout: defaultLoginTimeout,
 	}
 
 	for _, opt := range opts {
@@ -86,69 +111,80 @@ func NewRoundTripper(rt http.RoundTripper, opts ...OptionRoundtripperFunc) (http
 		t.expire = time.Duration(time.Hour * 24 * 5)
 	}
 
-	if err := t.GenerateToken(); err != nil {
+	if t.tokens == nil {
+		t.tokens = NewTokenCache()
+	}
+
+	// obtain a token unless the token cache already holds a valid one.
+	if _, err := t.token(context.Background(), ""); err != nil {
 		return nil, err
 	}
 
 	return t, nil
 }
 
-// generateToken is a function that generates JWT and makes a POST request to bouncer to sign it.
-func (t *dcosRoundtripper) GenerateToken() error {
-	t.Lock()
-	defer t.Unlock()
+// tokenKey returns the key of the tokens of the configured identity in the token cache.
+func (t *dcosRoundtripper) tokenKey() string {
+	return t.loginEndpoint + "\x00" + t.uid
+}
 
-	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: t.secret}, (&jose.SignerOptions{}).WithType("JWT"))
-	if err != nil {
-		return err
-	}
+// GenerateToken obtains a new token from bouncer, even if the cached one is still valid.
+func (t *dcosRoundtripper) GenerateToken() error {
+	_, err := t.tokens.get(context.Background(), t.tokenKey(), t.refreshSkew, t.CurrentToken(), t.fetchToken)
+	return err
+}
 
-	cl := struct {
-		UID string `json:"uid"`
-		Exp int64  `json:"exp"`
-	}{
-		t.uid,
-		time.Now().Add(t.expire).Unix(),
-	}
-	tokenStr, err := jwt.Signed(sig).Claims(cl).CompactSerialize()
-	if err != nil {
-		return err
-	}
+// token returns a valid token, obtaining a new one if the cached one expires within the refresh skew or is stale,
+// i.e. it is the token a request was just rejected with.
+func (t *dcosRoundtripper) token(ctx context.Context, stale string) (string, error) {
+	return t.tokens.get(ctx, t.tokenKey(), t.refreshSkew, stale, t.fetchToken)
+}
 
-	if tokenStr == "" {
-		return ErrEmptyToken
+// fetchToken is a function that obtains a new token from bouncer. Depending on the configured credentials it
+// either generates a JWT signed with the service account key or logs in with a username and password.
+func (t *dcosRoundtripper) fetchToken(ctx context.Context) (string, error) {
+	start := time.Now()
+	token, err := t.login(ctx)
+	if t.authMetrics != nil {
+		t.authMetrics.TokenRefresh(time.Since(start), err)
 	}
+	return token, err
+}
 
-	authReq := struct {
-		UID   string `json:"uid"`
-		Token string `json:"token,omitempty"`
-		Exp   int64  `json:"exp,omitempty"`
-	}{
-		UID:   t.uid,
-		Token: tokenStr,
-		Exp:   time.Now().Add(t.expire).Unix(),
+// login sends the login request to bouncer and returns the token of the response. The request is canceled with the
+// context, or after the login timeout.
+func (t *dcosRoundtripper) login(ctx context.Context) (string, error) {
+	authReq, err := t.loginRequest()
+	if err != nil {
+		return "", err
 	}
 
 	b, err := json.Marshal(authReq)
 	if err != nil {
-		return err
+		return "", err
 	}
 
+	t.logger.Debugf("transport: requesting a new token for uid %s from %s", t.uid, t.loginEndpoint)
 	authBody := bytes.NewBuffer(b)
 	req, err := http.NewRequest("POST", t.loginEndpoint, authBody)
 	if err != nil {
-		return err
+		return "", err
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, t.loginTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
 	req.Header.Add("Content-type", "application/json")
 	resp, err := t.transport.RoundTrip(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return ErrTokenRefresh{
+		t.logger.Errorf("transport: token request to %s returned response code %d", t.loginEndpoint, resp.StatusCode)
+		return "", ErrTokenRefresh{
 			msg: fmt.Sprintf("POST %s failed, expect response code 200. Got %d", t.loginEndpoint, resp.StatusCode),
 		}
 	}
@@ -158,17 +194,59 @@ func (t *dcosRoundtripper) GenerateToken() error {
 	}
 
 	if err = json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return err
+		return "", err
 	}
 
-	t.token = authResp.Token
-	return nil
+	return authResp.Token, nil
 }
 
+// loginRequest returns the body of the login request sent to bouncer.
+func (t *dcosRoundtripper) loginRequest() (interface{}, error) {
+	if t.password != "" {
+		return struct {
+			UID      string `json:"uid"`
+			Password string `json:"password"`
+		}{
+			UID:      t.uid,
+			Password: t.password,
+		}, nil
+	}
+
+	sig, err := jose.NewSigner(jose.SigningKey{Algorithm: t.algorithm, Key: t.secret}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return nil, err
+	}
+
+	cl := struct {
+		UID string `json:"uid"`
+		Exp int64  `json:"exp"`
+	}{
+		t.uid,
+		time.Now().Add(t.expire).Unix(),
+	}
+	tokenStr, err := jwt.Signed(sig).Claims(cl).CompactSerialize()
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenStr == "" {
+		return nil, ErrEmptyToken
+	}
+
+	return struct {
+		UID   string `json:"uid"`
+		Token string `json:"token,omitempty"`
+		Exp   int64  `json:"exp,omitempty"`
+	}{
+		UID:   t.uid,
+		Token: tokenStr,
+		Exp:   time.Now().Add(t.expire).Unix(),
+	}, nil
+}
+
+// CurrentToken returns the cached token.
 func (t *dcosRoundtripper) CurrentToken() string {
-	t.Lock()
-	defer t.Unlock()
-	return t.token
+	return t.tokens.current(t.tokenKey())
 }
 
 // RoundTrip is implementation of RoundTripper interface.
@@ -180,33 +258,28 @@ func (t *dcosRoundtripper) RoundTrip(req *http.Request) (*http.Response, error)
 	}
 	req.Header.Set("User-Agent", userAgent)
 
-	// helper function to update `Authorization` header.
-	addAuthToken := func() {
-		if token := t.CurrentToken(); token != "" {
-			req.Header.Set("Authorization", "token="+token)
-		}
+	token, err := t.token(req.Context(), "")
+	if err != nil {
+		return nil, err
 	}
 
-	var (
-		resp *http.Response
-		err  error
-	)
-
-	// try first time
-	addAuthToken()
-	resp, err = t.transport.RoundTrip(req)
+	resp, err := t.send(req, token)
 	if err != nil {
 		return resp, err
 	}
 
+	if t.authRetry {
+		return t.retryRejected(req, token, resp)
+	}
+
 	// if request returned 401 retry one more time.
 	if resp.StatusCode == http.StatusUnauthorized {
-		if err := t.GenerateToken(); err != nil {
+		t.logger.Infof("transport: %s %s returned 401, refreshing token", req.Method, req.URL)
+		if token, err = t.token(req.Context(), token); err != nil {
 			return resp, err
 		}
 
-		addAuthToken()
-		resp, err = t.transport.RoundTrip(req)
+		resp, err = t.send(req, token)
 		if err != nil {
 			return nil, err
 		}
@@ -215,6 +288,73 @@ func (t *dcosRoundtripper) RoundTrip(req *http.Request) (*http.Response, error)
 	return resp, nil
 }
 
+// send sends the request with the token and reports it to the auth metrics, if any.
+func (t *dcosRoundtripper) send(req *http.Request, token string) (*http.Response, error) {
+	if token != "" {
+		req.Header.Set("Authorization", "token="+token)
+	}
+
+	if t.authMetrics == nil {
+		return t.transport.RoundTrip(req)
+	}
+
+	if fetched := t.tokens.fetched(t.tokenKey(), token); !fetched.IsZero() {
+		t.authMetrics.TokenAge(time.Since(fetched))
+	}
+	resp, err := t.transport.RoundTrip(req)
+	if err == nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		t.authMetrics.AuthFailure(resp.StatusCode)
+	}
+	return resp, err
+}
+
+// retryRejected obtains a new token if the request was rejected with 401 or 403, and replays it once if it is
+// idempotent, see OptionAuthRetry.
+func (t *dcosRoundtripper) retryRejected(req *http.Request, token string, resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+		return resp, nil
+	}
+
+	t.logger.Infof("transport: %s %s returned %d, refreshing token", req.Method, req.URL, resp.StatusCode)
+	token, err := t.token(req.Context(), token)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	// other requests are not replayed, but the next one uses the new token.
+	if !idempotent(req) {
+		return resp, nil
+	}
+
+	// the response is discarded, drain it so the connection can be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	replay := req
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		replay = req.WithContext(req.Context())
+		replay.Body = body
+	}
+	resp, err = t.send(replay, token)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, ErrUnauthorized{
+			StatusCode: resp.StatusCode,
+			msg: fmt.Sprintf("%s %s failed with a new token, expect response code 200. Got %d",
+				req.Method, req.URL, resp.StatusCode),
+		}
+	}
+	return resp, nil
+}
+
 // DebugTransport is a function user can use to get a token from decorated http.RoundTripper if it implements
 // implWithJWT.
 func DebugTransport(rt http.RoundTripper) (Debug, error) {
@@ -15,12 +15,21 @@
 package transport
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/dcos/dcos-go/dcoslog"
 )
 
 var signedToken = "1234567890"
@@ -241,3 +250,249 @@ func TestOptionTokenExpire(t *testing.T) {
 		t.Fatalf("Expect: %s. Got %s", ErrInvalidExpireDuration, err)
 	}
 }
+
+func TestOptionLogger(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(bouncerToken))
+	defer ts.Close()
+
+	fr := &fakeRoundTripper{
+		func(req *http.Request) (*http.Response, error) {
+			return http.Get(ts.URL)
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	_, err := NewRoundTripper(fr, OptionReadIAMConfig("./fixtures/test_service_account.json"),
+		OptionLogger(dcoslog.NewStdLogger(log.New(buf, "", 0), true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "requesting a new token for uid test_user") {
+		t.Fatalf("Expect token generation to be logged. Got: %s", buf.String())
+	}
+}
+
+func TestOptionUserCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(bouncerToken))
+	defer ts.Close()
+
+	var logins int32
+	fr := &fakeRoundTripper{
+		func(req *http.Request) (*http.Response, error) {
+			if req.URL.String() == "http://127.0.0.1:8101/acs/api/v1/auth/login" {
+				atomic.AddInt32(&logins, 1)
+				postParams := struct {
+					UID      string `json:"uid"`
+					Password string `json:"password"`
+					Token    string `json:"token"`
+				}{}
+				if err := json.NewDecoder(req.Body).Decode(&postParams); err != nil {
+					t.Fatal(err)
+				}
+				if postParams.UID != "operator" || postParams.Password != "secret" || postParams.Token != "" {
+					t.Fatalf("Expect uid and password login. Got %+v", postParams)
+				}
+				return http.Get(ts.URL)
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		},
+	}
+
+	rt, err := NewRoundTripper(fr, OptionUserCredentials("operator", "secret", "http://127.0.0.1:8101/acs/api/v1/auth/login"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	debug, err := DebugTransport(rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if debug.CurrentToken() != signedToken {
+		t.Fatalf("Expect token %s. Got %s", signedToken, debug.CurrentToken())
+	}
+
+	// the session token is cached and only renewed after a 401.
+	var cnt int32
+	ts2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&cnt, 1) == 2 {
+			http.Error(w, "", http.StatusUnauthorized)
+		}
+	}))
+	defer ts2.Close()
+
+	c := http.Client{Transport: rt}
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(ts2.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expect http status 200. Got %d", resp.StatusCode)
+		}
+	}
+
+	if n := atomic.LoadInt32(&logins); n != 2 {
+		t.Fatalf("Expect 2 logins. Got %d", n)
+	}
+
+	for _, opt := range []OptionRoundtripperFunc{
+		OptionUserCredentials("", "secret", "http://127.0.0.1"),
+		OptionUserCredentials("operator", "", "http://127.0.0.1"),
+		OptionUserCredentials("operator", "secret", ""),
+	} {
+		if _, err := NewRoundTripper(fr, opt); err != ErrInvalidCredentials {
+			t.Fatalf("Expect: %s. Got %s", ErrInvalidCredentials, err)
+		}
+	}
+}
+
+func TestOptionAuthRetry(t *testing.T) {
+	var (
+		logins, requests int32
+		rejected         int32 = 1 // requests rejected until a new token is obtained
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/acs/api/v1/auth/login" {
+			n := atomic.AddInt32(&logins, 1)
+			fmt.Fprintf(w, `{"token": "token-%d"}`, n)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") == "token=token-1" || atomic.LoadInt32(&rejected) == 2 {
+			http.Error(w, "", http.StatusForbidden)
+			return
+		}
+		if r.Method == "PUT" {
+			b, _ := ioutil.ReadAll(r.Body)
+			w.Write(b)
+		}
+	}))
+	defer ts.Close()
+
+	rt, err := NewRoundTripper(nil, OptionCredentials("test", testKey(t), ts.URL+"/acs/api/v1/auth/login"),
+		OptionAuthRetry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http.Client{Transport: rt}
+
+	// an idempotent request is replayed with its body and a new token.
+	req, err := http.NewRequest("PUT", ts.URL, strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(b) != "body" {
+		t.Fatalf("Expected 200 with the replayed body, got %d %q", resp.StatusCode, b)
+	}
+	if logins != 2 || requests != 2 {
+		t.Fatalf("Expected 2 logins and 2 requests, got %d and %d", logins, requests)
+	}
+
+	// a request rejected again fails with ErrUnauthorized.
+	atomic.StoreInt32(&rejected, 2)
+	_, err = c.Get(ts.URL)
+	if uerr, ok := err.(*url.Error); !ok || uerr.Err.(ErrUnauthorized).StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected ErrUnauthorized, got %v", err)
+	}
+
+	// other requests are not replayed, but the token is refreshed.
+	resp, err = c.Post(ts.URL, "text/plain", strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || logins != 4 || requests != 5 {
+		t.Fatalf("Expected 403 after 4 logins and 5 requests, got %d after %d and %d", resp.StatusCode, logins, requests)
+	}
+}
+
+type fakeAuthMetrics struct {
+	refreshes, ages, failures int32
+}
+
+func (m *fakeAuthMetrics) TokenRefresh(d time.Duration, err error) { atomic.AddInt32(&m.refreshes, 1) }
+func (m *fakeAuthMetrics) TokenAge(age time.Duration)              { atomic.AddInt32(&m.ages, 1) }
+func (m *fakeAuthMetrics) AuthFailure(statusCode int)              { atomic.AddInt32(&m.failures, 1) }
+
+// Test that concurrent requests are never sent with a revoked token once a new one was obtained, and that they
+// share a single login.
+func TestRoundTripperConcurrent(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		valid    string // the only accepted token, empty once revoked
+		logins   int
+		rejected int32
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/acs/api/v1/auth/login" {
+			logins++
+			valid = fmt.Sprintf("token-%d", logins)
+			fmt.Fprintf(w, `{"token": %q}`, valid)
+			return
+		}
+		if r.Header.Get("Authorization") != "token="+valid {
+			atomic.AddInt32(&rejected, 1)
+			http.Error(w, "", http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	metrics := &fakeAuthMetrics{}
+	rt, err := NewRoundTripper(nil, OptionCredentials("test", testKey(t), ts.URL+"/acs/api/v1/auth/login"),
+		OptionAuthMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http.Client{Transport: rt}
+
+	const phases, workers, requests = 3, 20, 10
+	for phase := 0; phase < phases; phase++ {
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < requests; j++ {
+					resp, err := c.Get(ts.URL)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					resp.Body.Close()
+					if resp.StatusCode != http.StatusOK {
+						t.Errorf("Expected 200, got %d", resp.StatusCode)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		// revoke the token, e.g. bouncer rotated its keys.
+		mu.Lock()
+		valid = ""
+		mu.Unlock()
+	}
+
+	if logins != phases {
+		t.Fatalf("Expected one login per phase, got %d logins", logins)
+	}
+	if refreshes := atomic.LoadInt32(&metrics.refreshes); refreshes != phases {
+		t.Fatalf("Expected %d token refreshes, got %d", phases, refreshes)
+	}
+	if failures := atomic.LoadInt32(&metrics.failures); failures != atomic.LoadInt32(&rejected) {
+		t.Fatalf("Expected %d auth failures, got %d", rejected, failures)
+	}
+	if ages := atomic.LoadInt32(&metrics.ages); ages < phases*workers*requests {
+		t.Fatalf("Expected a token age for every request, got %d", ages)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// timeoutRoundTripper limits the time a request may take, from sending it to
+// closing the response body.
+type timeoutRoundTripper struct {
+	transport http.RoundTripper
+	timeout   time.Duration
+}
+
+// cancelBody cancels the context of a request once its response body is
+// closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// RoundTrip is implementation of RoundTripper interface.
+func (t *timeoutRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// Client defaults, see NewClient.
+const (
+	DefaultRequestTimeout        = time.Minute
+	DefaultResponseHeaderTimeout = 30 * time.Second
+)
+
+// NewClient returns an *http.Client using a DC/OS transport with defaults
+// suited to DC/OS components: requests time out after DefaultRequestTimeout,
+// or DefaultResponseHeaderTimeout without a response, and are retried with
+// DefaultRetryPolicy. The options override the defaults.
+func NewClient(clientOptionFuncs ...OptionTransportFunc) (*http.Client, error) {
+	opts := append([]OptionTransportFunc{
+		OptionRequestTimeout(DefaultRequestTimeout),
+		OptionResponseHeaderTimeout(DefaultResponseHeaderTimeout),
+		OptionRetry(DefaultRetryPolicy),
+	}, clientOptionFuncs...)
+
+	tr, err := NewTransport(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: tr}, nil
+}
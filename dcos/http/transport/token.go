@@ -0,0 +1,153 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTokenRefreshSkew is how long before its expiry a token is refreshed.
+const defaultTokenRefreshSkew = time.Minute
+
+// defaultLoginTimeout is how long a login request to obtain a token may take.
+const defaultLoginTimeout = 30 * time.Second
+
+// TokenCache holds the tokens obtained from bouncer, keyed by login endpoint and uid. Round trippers sharing a
+// TokenCache, see OptionTokenCache, share the tokens of the same identity instead of each logging in. A
+// TokenCache is safe for concurrent use.
+type TokenCache struct {
+	sync.Mutex
+	entries map[string]*tokenEntry
+}
+
+// NewTokenCache returns an empty TokenCache.
+func NewTokenCache() *TokenCache {
+	return &TokenCache{entries: make(map[string]*tokenEntry)}
+}
+
+// tokenEntry is the token of an identity, and the token request in flight, if any.
+type tokenEntry struct {
+	token   string
+	expires time.Time // zero if the token has no exp claim
+	fetched time.Time
+	call    *tokenCall
+}
+
+// tokenCall is a token request shared by all the callers that need a new token at the same time.
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// tokenExpiry returns the expiry of the token from its exp claim, or zero if it has none. The signature is not
+// verified, the token is only parsed to know when to refresh it.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// fresh returns whether the token can be used without refreshing it first.
+func (e *tokenEntry) fresh(now time.Time, skew time.Duration) bool {
+	return e.token != "" && (e.expires.IsZero() || now.Add(skew).Before(e.expires))
+}
+
+// get returns the token for key. A new token is obtained with fetch if there is none, if it expires within skew,
+// or if it is stale, i.e. the token a request was rejected with. Concurrent callers wait for a single fetch. The
+// fetch does not run with the context of any caller, so that a canceled caller does not fail the others; callers
+// give up waiting when their own context is done.
+func (c *TokenCache) get(ctx context.Context, key string, skew time.Duration, stale string,
+	fetch func(context.Context) (string, error)) (string, error) {
+	c.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &tokenEntry{}
+		c.entries[key] = e
+	}
+
+	call := e.call
+	if call == nil {
+		if e.fresh(time.Now(), skew) && (stale == "" || e.token != stale) {
+			token := e.token
+			c.Unlock()
+			return token, nil
+		}
+
+		call = &tokenCall{done: make(chan struct{})}
+		e.call = call
+		go c.fetch(e, call, fetch)
+	}
+	c.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// fetch obtains a new token for the entry and stores it if the fetch succeeds.
+func (c *TokenCache) fetch(e *tokenEntry, call *tokenCall, fetch func(context.Context) (string, error)) {
+	call.token, call.err = fetch(context.Background())
+
+	c.Lock()
+	if call.err == nil {
+		e.token = call.token
+		e.expires = tokenExpiry(call.token)
+		e.fetched = time.Now()
+	}
+	e.call = nil
+	c.Unlock()
+	close(call.done)
+}
+
+// current returns the cached token for key, or an empty string.
+func (c *TokenCache) current(key string) string {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[key]; ok {
+		return e.token
+	}
+	return ""
+}
+
+// fetched returns when the token for key was obtained, or zero if it is no longer the cached token.
+func (c *TokenCache) fetched(key, token string) time.Time {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[key]; ok && e.token == token {
+		return e.fetched
+	}
+	return time.Time{}
+}
@@ -0,0 +1,265 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testToken returns an unsigned token with the given exp claim.
+func testToken(exp time.Time) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"RS256"}`)) + "." + enc([]byte(fmt.Sprintf(`{"uid":"test","exp":%d}`, exp.Unix()))) + ".sig"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Unix(1600000000, 0)
+	if e := tokenExpiry(testToken(exp)); !e.Equal(exp) {
+		t.Fatalf("Expected %s, got %s", exp, e)
+	}
+	for _, token := range []string{"", signedToken, "a.b.c"} {
+		if e := tokenExpiry(token); !e.IsZero() {
+			t.Fatalf("Expected no expiry for %q, got %s", token, e)
+		}
+	}
+}
+
+// testKey returns the private key of the test service account.
+func testKey(t *testing.T) string {
+	b, err := ioutil.ReadFile("./fixtures/test_service_account.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Secret string `json:"private_key"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Secret
+}
+
+// newTokenServer returns a bouncer issuing tokens that expire after ttl, and the number of tokens it issued.
+// The caller must close the server.
+func newTokenServer(ttl time.Duration) (*httptest.Server, *int32) {
+	var logins int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/acs/api/v1/auth/login" {
+			return
+		}
+		atomic.AddInt32(&logins, 1)
+		json.NewEncoder(w).Encode(map[string]string{"token": testToken(time.Now().Add(ttl))})
+	}))
+	return ts, &logins
+}
+
+func TestTokenProactiveRefresh(t *testing.T) {
+	ts, logins := newTokenServer(time.Hour)
+	defer ts.Close()
+
+	rt, err := NewRoundTripper(nil, OptionCredentials("test", testKey(t), ts.URL+"/acs/api/v1/auth/login"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http.Client{Transport: rt}
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if n := atomic.LoadInt32(logins); n != 1 {
+		t.Fatalf("Expected a valid token to be reused, got %d logins", n)
+	}
+
+	// the token expires within the skew, it is refreshed before the next request.
+	rt, err = NewRoundTripper(nil, OptionCredentials("test", testKey(t), ts.URL+"/acs/api/v1/auth/login"),
+		OptionTokenRefreshSkew(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = &http.Client{Transport: rt}
+	resp, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(logins); n != 3 {
+		t.Fatalf("Expected the token to be refreshed before it expires, got %d logins", n)
+	}
+}
+
+func TestTokenCacheShared(t *testing.T) {
+	ts, logins := newTokenServer(time.Hour)
+	defer ts.Close()
+
+	cache := NewTokenCache()
+	for i := 0; i < 3; i++ {
+		_, err := NewRoundTripper(nil, OptionCredentials("test", testKey(t), ts.URL+"/acs/api/v1/auth/login"),
+			OptionTokenCache(cache))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(logins); n != 1 {
+		t.Fatalf("Expected round trippers to share the token, got %d logins", n)
+	}
+
+	if _, err := NewRoundTripper(nil, OptionTokenCache(nil)); err == nil {
+		t.Fatal("Expected an error for a nil token cache")
+	}
+}
+
+func TestTokenCacheSingleFetch(t *testing.T) {
+	cache := NewTokenCache()
+	release := make(chan struct{})
+	var fetches int32
+	fetch := func(context.Context) (string, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return "token", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := cache.get(context.Background(), "key", time.Minute, "", fetch); err != nil || token != "token" {
+				t.Errorf("Expected token, got %q, %v", token, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("Expected concurrent callers to share one fetch, got %d", n)
+	}
+
+	// a stale token is only refreshed once.
+	if _, err := cache.get(context.Background(), "key", time.Minute, "token", func(context.Context) (string, error) { return "new", nil }); err != nil {
+		t.Fatal(err)
+	}
+	token, err := cache.get(context.Background(), "key", time.Minute, "token", func(context.Context) (string, error) { return "newer", nil })
+	if err != nil || token != "new" {
+		t.Fatalf("Expected new, got %q, %v", token, err)
+	}
+}
+
+func TestTokenLoginContext(t *testing.T) {
+	var logins int32
+	hang := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/acs/api/v1/auth/login" {
+			return
+		}
+		// the first login succeeds with a token expiring soon, then bouncer hangs.
+		if atomic.AddInt32(&logins, 1) > 1 {
+			select {
+			case <-hang:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": testToken(time.Now().Add(time.Second))})
+	}))
+	defer ts.Close()
+	defer close(hang)
+
+	rt, err := NewRoundTripper(nil, OptionCredentials("test", testKey(t), ts.URL+"/acs/api/v1/auth/login"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the token needs a refresh, the request stops waiting for the login when it is canceled.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest("GET", ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := rt.RoundTrip(req.WithContext(ctx)); err == nil {
+		t.Fatal("Expected an error for a request canceled during the login")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected the request to stop waiting for the login, took %s", elapsed)
+	}
+
+	// the login timeout applies without a deadline on the request.
+	_, err = NewRoundTripper(nil, OptionCredentials("test", testKey(t), ts.URL+"/acs/api/v1/auth/login"),
+		OptionLoginTimeout(50*time.Millisecond))
+	if err == nil {
+		t.Fatal("Expected the login to time out")
+	}
+
+	if _, err := NewRoundTripper(nil, OptionLoginTimeout(0)); err == nil {
+		t.Fatal("Expected an error for a zero login timeout")
+	}
+}
+
+func TestTokenCacheCanceledCaller(t *testing.T) {
+	cache := NewTokenCache()
+	release := make(chan struct{})
+	var fetches int32
+	fetch := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&fetches, 1)
+		select {
+		case <-release:
+			return "token", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	// the first caller starts the fetch and is canceled while it is running.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := cache.get(ctx, "key", time.Minute, "", fetch)
+		errc <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+
+	// a second caller still gets the token of the shared fetch.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	token, err := cache.get(context.Background(), "key", time.Minute, "", fetch)
+	if err != nil || token != "token" {
+		t.Fatalf("Expected token, got %q, %v", token, err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("Expected the fetch to survive the canceled caller, got %d fetches", n)
+	}
+}
@@ -19,13 +19,54 @@ import (
 	"crypto/x509"
 	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// EnvServiceAccountCredential is the environment variable service account secrets of the DC/OS secrets store are
+// conventionally exposed as to tasks, see OptionIAMConfigFromEnv.
+const EnvServiceAccountCredential = "DCOS_SERVICE_ACCOUNT_CREDENTIAL"
+
+// Connection pool defaults. Most DC/OS components talk to a single host, Admin
+// Router, so unlike http.DefaultTransport many idle connections are kept per
+// host rather than opening new ones under load.
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 32
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultDialTimeout         = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
 )
 
 type dcosTransport struct {
 	CaCertificatePath string
 	IAMConfigPath     string
+	IAMConfig         []byte
+	RetryPolicy       *RetryPolicy
+
+	ClientCertificatePath string
+	ClientKeyPath         string
+	TLSConfig             *tls.Config
+	InsecureSkipVerify    bool
+	StrictTLS             bool
+	CAReloadInterval      time.Duration
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	DisableHTTP2        bool
+
+	RequestTimeout        time.Duration
+	ResponseHeaderTimeout time.Duration
+
+	Metrics      Metrics
+	PathTemplate func(*http.Request) string
 }
 
 // loadCAPool will load a valid x509 cert.
@@ -49,51 +90,160 @@ func loadCAPool(path string) (*x509.CertPool, error) {
 	return caPool, nil
 }
 
-// newTransport will return transport for http.Client
-func configureTLS(caCertificatePath string) (*http.Transport, error) {
-	tr := &http.Transport{}
-	// if user provided CA cert we must use it, otherwise use InsecureSkipVerify: true for all HTTPS requests.
+// configureTLS will return transport for http.Client. Server certificates are
+// verified against the CA certificate if given, or the system cert pool
+// otherwise, unless insecureSkipVerify is set.
+func configureTLS(caCertificatePath string, insecureSkipVerify bool) (*http.Transport, error) {
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: insecureSkipVerify,
+		},
+	}
 	if caCertificatePath != "" {
 		caPool, err := loadCAPool(caCertificatePath)
 		if err != nil {
 			return tr, err
 		}
+		tr.TLSClientConfig.RootCAs = caPool
+	}
+	return tr, nil
+}
 
-		tr.TLSClientConfig = &tls.Config{
-			RootCAs: caPool,
+// checkStrictTLS returns an error if the TLS configuration of the transport
+// skips the verification of server certificates, and refuses TLS versions
+// older than 1.2.
+func checkStrictTLS(tr *http.Transport) error {
+	if tr.TLSClientConfig.InsecureSkipVerify {
+		return ErrInsecureTLS
+	}
+	if tr.TLSClientConfig.MinVersion < tls.VersionTLS12 {
+		tr.TLSClientConfig.MinVersion = tls.VersionTLS12
+	}
+	return nil
+}
+
+// configureClientTLS applies the TLS configuration and the client certificate
+// set by the options on top of the CA configured by configureTLS.
+func configureClientTLS(tr *http.Transport, t dcosTransport) error {
+	if t.TLSConfig != nil {
+		cfg := t.TLSConfig.Clone()
+		if t.CaCertificatePath != "" {
+			cfg.RootCAs = tr.TLSClientConfig.RootCAs
 		}
-	} else {
-		tr.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: true,
+		tr.TLSClientConfig = cfg
+	}
+
+	if t.ClientCertificatePath != "" {
+		cert, err := tls.LoadX509KeyPair(t.ClientCertificatePath, t.ClientKeyPath)
+		if err != nil {
+			return err
 		}
+		tr.TLSClientConfig.Certificates = append(tr.TLSClientConfig.Certificates, cert)
 	}
-	return tr, nil
+	return nil
+}
+
+// configurePool configures the connection pool and timeouts of the transport.
+func configurePool(tr *http.Transport, t dcosTransport) {
+	tr.Proxy = http.ProxyFromEnvironment
+	tr.DialContext = (&net.Dialer{
+		Timeout:   t.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	tr.MaxIdleConns = t.MaxIdleConns
+	tr.MaxIdleConnsPerHost = t.MaxIdleConnsPerHost
+	tr.IdleConnTimeout = t.IdleConnTimeout
+	tr.TLSHandshakeTimeout = t.TLSHandshakeTimeout
+	tr.ExpectContinueTimeout = time.Second
+	tr.ResponseHeaderTimeout = t.ResponseHeaderTimeout
 }
 
 // NewTransport returns a DC/OS transport implementation by leveraging a roundtripper for
 // IAM configuration if passed with a pre-configured TLS configuration.
 func NewTransport(clientOptionFuncs ...OptionTransportFunc) (http.RoundTripper, error) {
-	t := dcosTransport{}
+	t := dcosTransport{
+		MaxIdleConns:        defaultMaxIdleConns,
+		MaxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     defaultIdleConnTimeout,
+		DialTimeout:         defaultDialTimeout,
+		TLSHandshakeTimeout: defaultTLSHandshakeTimeout,
+	}
 	for _, opt := range clientOptionFuncs {
 		if err := opt(&t); err != nil {
 			return nil, err
 		}
 	}
 
-	tr, err := configureTLS(t.CaCertificatePath)
+	tr, err := configureTLS(t.CaCertificatePath, t.InsecureSkipVerify)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(t.IAMConfigPath) != 0 {
-		withIAM, err := NewRoundTripper(
-			tr,
-			OptionReadIAMConfig(t.IAMConfigPath))
+	if err := configureClientTLS(tr, t); err != nil {
+		return nil, err
+	}
+
+	if t.StrictTLS {
+		if err := checkStrictTLS(tr); err != nil {
+			return nil, err
+		}
+	}
+
+	// the reloader verifies server certificates itself, so it is only
+	// configured if they are verified.
+	if t.CAReloadInterval > 0 && !tr.TLSClientConfig.InsecureSkipVerify {
+		if t.CaCertificatePath == "" {
+			return nil, errors.New("CA certificate reloading requires a CA certificate path")
+		}
+		reloader, err := newCAReloader(t.CaCertificatePath, t.CAReloadInterval)
 		if err != nil {
 			return nil, err
 		}
-		return withIAM, nil
+		reloader.configure(tr)
 	}
 
-	return tr, nil
+	configurePool(tr, t)
+
+	// a custom TLS configuration disables HTTP/2 unless it is configured
+	// explicitly.
+	if !t.DisableHTTP2 {
+		if err := http2.ConfigureTransport(tr); err != nil {
+			return nil, err
+		}
+	}
+
+	var rt http.RoundTripper = tr
+	var iamConfig OptionRoundtripperFunc
+	if len(t.IAMConfig) != 0 {
+		iamConfig = OptionParseIAMConfig(t.IAMConfig)
+	} else if len(t.IAMConfigPath) != 0 {
+		iamConfig = OptionReadIAMConfig(t.IAMConfigPath)
+	}
+	if iamConfig != nil {
+		opts := []OptionRoundtripperFunc{iamConfig}
+		if authMetrics, ok := t.Metrics.(AuthMetrics); ok {
+			opts = append(opts, OptionAuthMetrics(authMetrics))
+		}
+		withIAM, err := NewRoundTripper(tr, opts...)
+		if err != nil {
+			return nil, err
+		}
+		rt = withIAM
+	}
+
+	// every attempt is reported, including retries.
+	if t.Metrics != nil {
+		rt = &metricsRoundTripper{transport: rt, metrics: t.Metrics, pathTemplate: t.PathTemplate}
+	}
+
+	if t.RetryPolicy != nil {
+		rt = &retryRoundTripper{transport: rt, policy: *t.RetryPolicy}
+	}
+
+	// the request timeout includes retries.
+	if t.RequestTimeout > 0 {
+		rt = &timeoutRoundTripper{transport: rt, timeout: t.RequestTimeout}
+	}
+
+	return rt, nil
 }
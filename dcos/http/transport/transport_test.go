@@ -15,13 +15,20 @@
 package transport
 
 import (
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 const (
 	CACertPath     = "fixtures/root_ca_cert.pem"
+	CAKeyPath      = "fixtures/root_ca.key"
 	ServiceAccount = "fixtures/test_service_account.json"
 )
 
@@ -43,7 +50,7 @@ func TestLoadCAPool(t *testing.T) {
 }
 
 func TestConfigureTLS(t *testing.T) {
-	tr, err := configureTLS(CACertPath)
+	tr, err := configureTLS(CACertPath, false)
 
 	if err != nil {
 		t.Error("Expected no errors, got", err.Error())
@@ -53,7 +60,25 @@ func TestConfigureTLS(t *testing.T) {
 		t.Error("Expected skip verify to be false, got true")
 	}
 
-	noVerifyTr, noVerifyErr := configureTLS("")
+	if tr.TLSClientConfig.RootCAs == nil {
+		t.Error("Expected the CA certificate to be used, got the system cert pool")
+	}
+
+	systemTr, systemErr := configureTLS("", false)
+
+	if systemErr != nil {
+		t.Error("Expected no errors, got", systemErr.Error())
+	}
+
+	if systemTr.TLSClientConfig.InsecureSkipVerify {
+		t.Error("Expected skip verify to be false without a CA certificate, got true")
+	}
+
+	if systemTr.TLSClientConfig.RootCAs != nil {
+		t.Error("Expected the system cert pool to be used, got a custom one")
+	}
+
+	noVerifyTr, noVerifyErr := configureTLS("", true)
 
 	if noVerifyErr != nil {
 		t.Error("Expected no errors, got", noVerifyErr.Error())
@@ -63,3 +88,205 @@ func TestConfigureTLS(t *testing.T) {
 		t.Error("Expected skip verify to be true, got false")
 	}
 }
+
+func TestOptionStrictTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// the self-signed certificate of the server is only accepted in insecure mode.
+	for insecure, opts := range map[bool][]OptionTransportFunc{
+		false: nil,
+		true:  {OptionInsecureSkipVerify()},
+	} {
+		tr, err := NewTransport(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := (&http.Client{Transport: tr}).Get(ts.URL)
+		if insecure && err != nil {
+			t.Errorf("Expected no errors in insecure mode, got %s", err)
+		}
+		if !insecure && err == nil {
+			t.Error("Expected an error verifying the server certificate, got nil")
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+
+	rt, err := NewTransport(OptionStrictTLS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := rt.(*http.Transport).TLSClientConfig.MinVersion; v != tls.VersionTLS12 {
+		t.Errorf("Expected TLS 1.2 at least, got %x", v)
+	}
+
+	for _, opt := range []OptionTransportFunc{
+		OptionInsecureSkipVerify(),
+		OptionTLSConfig(&tls.Config{InsecureSkipVerify: true}),
+	} {
+		if _, err := NewTransport(OptionStrictTLS(), opt); err != ErrInsecureTLS {
+			t.Errorf("Expected error %s, got %v", ErrInsecureTLS, err)
+		}
+	}
+}
+
+func TestNewTransportPool(t *testing.T) {
+	rt, err := NewTransport(
+		OptionMaxIdleConns(10),
+		OptionMaxIdleConnsPerHost(5),
+		OptionIdleConnTimeout(time.Minute),
+		OptionDialTimeout(time.Second),
+		OptionTLSHandshakeTimeout(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, ok := rt.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", rt)
+	}
+	if tr.MaxIdleConns != 10 || tr.MaxIdleConnsPerHost != 5 || tr.IdleConnTimeout != time.Minute {
+		t.Errorf("Unexpected connection pool configuration %d, %d, %s", tr.MaxIdleConns, tr.MaxIdleConnsPerHost,
+			tr.IdleConnTimeout)
+	}
+	if tr.TLSHandshakeTimeout != 2*time.Second || tr.DialContext == nil {
+		t.Errorf("Unexpected timeouts %s", tr.TLSHandshakeTimeout)
+	}
+
+	for _, opt := range []OptionTransportFunc{OptionMaxIdleConns(0), OptionMaxIdleConnsPerHost(-1),
+		OptionIdleConnTimeout(0), OptionDialTimeout(0), OptionTLSHandshakeTimeout(0)} {
+		if _, err := NewTransport(opt); err == nil {
+			t.Error("Expected an error with a non positive value, got nil")
+		}
+	}
+}
+
+func TestNewTransportHTTP2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	for expected, opts := range map[int][]OptionTransportFunc{
+		2: {OptionInsecureSkipVerify()},
+		1: {OptionInsecureSkipVerify(), OptionDisableHTTP2()},
+	} {
+		tr, err := NewTransport(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := (&http.Client{Transport: tr}).Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.ProtoMajor != expected {
+			t.Errorf("Expected HTTP/%d, got %s", expected, resp.Proto)
+		}
+	}
+}
+
+func TestNewTransportClientCertificate(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(ts.Certificate())
+
+	tr, err := NewTransport(
+		OptionTLSConfig(&tls.Config{RootCAs: serverCAs}),
+		OptionClientCertificate(CACertPath, CAKeyPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&http.Client{Transport: tr}).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected response code 200, got %d", resp.StatusCode)
+	}
+
+	// the server is not trusted without the custom TLS configuration.
+	tr, err = NewTransport(OptionCaCertificatePath(CACertPath), OptionClientCertificate(CACertPath, CAKeyPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&http.Client{Transport: tr}).Get(ts.URL); err == nil {
+		t.Error("Expected an error verifying the server certificate, got nil")
+	}
+
+	if _, err := NewTransport(OptionClientCertificate(CACertPath, "fake/key/path")); err == nil {
+		t.Error("Expected error with bad key path, got nil")
+	}
+	if _, err := NewTransport(OptionClientCertificate("", CAKeyPath)); err == nil {
+		t.Error("Expected error with empty certificate path, got nil")
+	}
+	if _, err := NewTransport(OptionTLSConfig(nil)); err == nil {
+		t.Error("Expected error with nil TLS configuration, got nil")
+	}
+}
+
+func TestNewTransportIAMConfig(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/acs/api/v1/auth/login" {
+			w.Write([]byte(`{"token": "in-memory"}`))
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "token=in-memory" {
+			t.Errorf("Expected Authorization token=in-memory, got %q", auth)
+		}
+	}))
+	defer ts.Close()
+
+	cfg, err := json.Marshal(map[string]string{
+		"scheme":         "RS256",
+		"uid":            "test",
+		"private_key":    testKey(t),
+		"login_endpoint": ts.URL + "/acs/api/v1/auth/login",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv(EnvServiceAccountCredential, string(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(EnvServiceAccountCredential)
+
+	for _, opt := range []OptionTransportFunc{
+		OptionIAMConfig(cfg),
+		OptionIAMConfigFromEnv(EnvServiceAccountCredential),
+	} {
+		// the in-memory configuration replaces the path.
+		tr, err := NewTransport(OptionIAMConfigPath("/nonexistent"), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := (&http.Client{Transport: tr}).Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if _, err := NewTransport(OptionIAMConfigFromEnv("DCOS_GO_TEST_UNSET")); err == nil {
+		t.Fatal("Expected an error for an unset environment variable")
+	}
+	if _, err := NewTransport(OptionIAMConfig(nil)); err == nil {
+		t.Fatal("Expected an error for an empty IAM configuration")
+	}
+}
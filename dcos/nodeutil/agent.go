@@ -0,0 +1,122 @@
+package nodeutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+// agentState is the part of the mesos agent state used by AgentAttributes and
+// AgentResources.
+type agentState struct {
+	Attributes map[string]string
+	Resources  Resources
+}
+
+// decodeAgentState decodes the attributes and resources of a mesos agent
+// state, skipping the executors and frameworks it holds. Scalar attributes
+// are formatted as in the agent flags, e.g. "1.5".
+func decodeAgentState(dec *json.Decoder) (state agentState, err error) {
+	err = decodeObject(dec, func(key string) error {
+		switch key {
+		case "attributes":
+			var attributes map[string]json.RawMessage
+			if err := dec.Decode(&attributes); err != nil {
+				return err
+			}
+			state.Attributes = make(map[string]string, len(attributes))
+			for name, value := range attributes {
+				var s string
+				if err := json.Unmarshal(value, &s); err != nil {
+					// scalar attributes are numbers.
+					s = string(value)
+				}
+				state.Attributes[name] = s
+			}
+			return nil
+		case "resources":
+			return dec.Decode(&state.Resources)
+		}
+		return skipValue(dec)
+	})
+	if err != nil {
+		return state, fmt.Errorf("could not decode mesos agent state: %s", err)
+	}
+	return state, nil
+}
+
+// AgentInspector reads the attributes and resources of the local mesos agent.
+// The NodeInfo returned by NewNodeInfo implements it.
+type AgentInspector interface {
+	AgentAttributes(ctx context.Context) (map[string]string, error)
+	AgentResources(ctx context.Context) (*Resources, error)
+}
+
+// ensure that dcosInfo implements AgentInspector.
+var _ AgentInspector = &dcosInfo{}
+
+// AgentAttributes returns the attributes of the mesos agent running on the
+// node, e.g. public_ip. It only works on agent nodes.
+func (d *dcosInfo) AgentAttributes(ctx context.Context) (map[string]string, error) {
+	state, err := d.agentState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return state.Attributes, nil
+}
+
+// AgentResources returns the total resources of the mesos agent running on the
+// node. It only works on agent nodes.
+func (d *dcosInfo) AgentResources(ctx context.Context) (*Resources, error) {
+	state, err := d.agentState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &state.Resources, nil
+}
+
+// agentState retrieves the state of the mesos agent running on the node.
+func (d *dcosInfo) agentState(ctx context.Context) (state agentState, err error) {
+	if d.role == dcos.RoleMaster {
+		return state, ErrNodeInfo{"Mesos agent state is not available on master nodes"}
+	}
+
+	stateURL := d.agentStateURL
+	if stateURL == "" {
+		localIP, err := d.DetectIP()
+		if err != nil {
+			return state, err
+		}
+		stateURL = fmt.Sprintf("http://%s/slave(1)/state", net.JoinHostPort(localIP.String(), strconv.Itoa(dcos.PortMesosAgent)))
+	}
+
+	err = d.retry(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequest("GET", stateURL, nil)
+		if err != nil {
+			return err
+		}
+
+		if header, ok := HeaderFromContext(ctx); ok {
+			req.Header = header
+		}
+
+		resp, err := d.client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return ErrNodeInfo{fmt.Sprintf("GET request to %s returned response code %d", stateURL, resp.StatusCode)}
+		}
+
+		state, err = decodeAgentState(json.NewDecoder(resp.Body))
+		return err
+	})
+	return state, err
+}
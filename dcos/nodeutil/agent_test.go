@@ -0,0 +1,57 @@
+package nodeutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+func TestAgentAttributesAndResources(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+			"id": "agent-id",
+			"attributes": {"public_ip": "true", "rack": 3.5},
+			"resources": {"cpus": 4, "mem": 14861, "disk": 35577, "gpus": 0, "ports": "[1025-2180, 2182-3887]"},
+			"frameworks": [{"id": "framework-id", "executors": [{"tasks": [{"id": "a"}]}]}]
+		}`)
+	}))
+	defer ts.Close()
+
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleAgentPublic, OptionAgentStateURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attributes, err := d.(AgentInspector).AgentAttributes(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedAttributes := map[string]string{"public_ip": "true", "rack": "3.5"}
+	if !reflect.DeepEqual(attributes, expectedAttributes) {
+		t.Fatalf("expect attributes %v. Got %v", expectedAttributes, attributes)
+	}
+
+	resources, err := d.(AgentInspector).AgentResources(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedResources := Resources{CPUs: 4, Mem: 14861, Disk: 35577, Ports: "[1025-2180, 2182-3887]"}
+	if *resources != expectedResources {
+		t.Fatalf("expect resources %+v. Got %+v", expectedResources, *resources)
+	}
+}
+
+func TestAgentStateMaster(t *testing.T) {
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.(AgentInspector).AgentAttributes(context.TODO()); err == nil {
+		t.Fatal("expect an error on a master node")
+	}
+}
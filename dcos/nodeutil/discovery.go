@@ -0,0 +1,170 @@
+package nodeutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+// defaultMasterListLocation is a file on every DC/OS node listing the IPs of
+// the masters.
+const defaultMasterListLocation = "/opt/mesosphere/etc/master_list"
+
+// MasterDiscoverer discovers the IPs of the mesos masters of a cluster.
+//
+// The implementations in this package have a Next field: if discovery fails,
+// the next discoverer is tried, so they can be chained, e.g.
+//
+//	d := &DiscoverMastersZK{Conn: conn, Next: &DiscoverMastersDNS{}}
+type MasterDiscoverer interface {
+	DiscoverMasters(ctx context.Context) ([]net.IP, error)
+}
+
+// next returns the masters discovered by the next discoverer in the chain, or
+// err if there is none.
+func next(ctx context.Context, next MasterDiscoverer, err error) ([]net.IP, error) {
+	if next == nil {
+		return nil, err
+	}
+
+	ips, nextErr := next.DiscoverMasters(ctx)
+	if nextErr != nil {
+		return nil, ErrNodeInfo{fmt.Sprintf("%s; %s", err, nextErr)}
+	}
+	return ips, nil
+}
+
+// DiscoverMastersDNS discovers the masters by resolving a DNS record.
+type DiscoverMastersDNS struct {
+	// Record is the DNS record resolving to the masters. Defaults to
+	// master.mesos.
+	Record string
+
+	// Next is tried if the record cannot be resolved.
+	Next MasterDiscoverer
+}
+
+// DiscoverMasters implements MasterDiscoverer.
+func (d *DiscoverMastersDNS) DiscoverMasters(ctx context.Context) ([]net.IP, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	record := d.Record
+	if record == "" {
+		record = dcos.DNSRecordMasters
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, record)
+	if err != nil {
+		return next(ctx, d.Next, ErrNodeInfo{fmt.Sprintf("dns: %s", err)})
+	}
+
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return ips, nil
+}
+
+// DiscoverMastersStaticFile discovers the masters by reading a file holding
+// a JSON list of their IPs, e.g. ["10.0.0.1", "10.0.0.2"].
+type DiscoverMastersStaticFile struct {
+	// Path is the location of the file. Defaults to
+	// /opt/mesosphere/etc/master_list.
+	Path string
+
+	// Next is tried if the file cannot be read.
+	Next MasterDiscoverer
+}
+
+// DiscoverMasters implements MasterDiscoverer.
+func (d *DiscoverMastersStaticFile) DiscoverMasters(ctx context.Context) ([]net.IP, error) {
+	path := d.Path
+	if path == "" {
+		path = defaultMasterListLocation
+	}
+
+	ips, err := readMasterList(path)
+	if err != nil {
+		return next(ctx, d.Next, ErrNodeInfo{fmt.Sprintf("static file: %s", err)})
+	}
+	return ips, nil
+}
+
+func readMasterList(path string) ([]net.IP, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var masters []string
+	if err := json.Unmarshal(body, &masters); err != nil {
+		return nil, err
+	}
+	if len(masters) == 0 {
+		return nil, fmt.Errorf("no masters in %s", path)
+	}
+
+	ips := make([]net.IP, len(masters))
+	for i, master := range masters {
+		ips[i] = net.ParseIP(master)
+		if ips[i] == nil {
+			return nil, fmt.Errorf("invalid master IP %s in %s", master, path)
+		}
+	}
+	return ips, nil
+}
+
+// DiscoverMastersZK discovers the masters taking part in the mesos leader
+// election in ZK. The leader comes first.
+type DiscoverMastersZK struct {
+	// Conn is the ZK connection.
+	Conn ZKConn
+
+	// Path is the ZK path of the mesos leader election. Defaults to /mesos.
+	Path string
+
+	// Next is tried if the masters cannot be read from ZK.
+	Next MasterDiscoverer
+}
+
+// DiscoverMasters implements MasterDiscoverer.
+func (d *DiscoverMastersZK) DiscoverMasters(ctx context.Context) ([]net.IP, error) {
+	ips, err := d.discoverMasters()
+	if err != nil {
+		return next(ctx, d.Next, ErrNodeInfo{fmt.Sprintf("zk: %s", err)})
+	}
+	return ips, nil
+}
+
+func (d *DiscoverMastersZK) discoverMasters() ([]net.IP, error) {
+	if d.Conn == nil {
+		return nil, ErrNodeInfo{"No ZK connection configured"}
+	}
+
+	path := d.Path
+	if path == "" {
+		path = defaultMesosZKPath
+	}
+
+	contenders, err := zkContenders(d.Conn, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(contenders) == 0 {
+		return nil, ErrNodeInfo{fmt.Sprintf("No mesos masters found in ZK at %s", path)}
+	}
+
+	ips := make([]net.IP, len(contenders))
+	for i, contender := range contenders {
+		if ips[i], err = zkContenderIP(d.Conn, path, contender); err != nil {
+			return nil, err
+		}
+	}
+	return ips, nil
+}
@@ -0,0 +1,85 @@
+package nodeutil
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverMastersStaticFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nodeutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "master_list")
+	if err := ioutil.WriteFile(path, []byte(`["10.0.0.1", "10.0.0.2"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// ZK is tried first, and fails without a connection.
+	d := &DiscoverMastersZK{Next: &DiscoverMastersStaticFile{Path: path}}
+	ips, err := d.DiscoverMasters(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}
+	if !reflect.DeepEqual(ips, expected) {
+		t.Fatalf("expect masters %s. Got %s", expected, ips)
+	}
+
+	for _, invalid := range []string{`[]`, `["10.0.0"]`, `10.0.0.1`} {
+		if err := ioutil.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := (&DiscoverMastersStaticFile{Path: path}).DiscoverMasters(context.TODO()); err == nil {
+			t.Fatalf("expect an error reading master list %s", invalid)
+		}
+	}
+}
+
+func TestDiscoverMastersZK(t *testing.T) {
+	conn := fakeZKConn{
+		"json.info_0000000002": []byte(`{"address": {"ip": "10.10.0.2"}}`),
+		"json.info_0000000001": []byte(`{"address": {"ip": "10.10.0.1"}}`),
+		"log_replicas":         nil,
+	}
+
+	ips, err := (&DiscoverMastersZK{Conn: conn}).DiscoverMasters(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []net.IP{net.ParseIP("10.10.0.1"), net.ParseIP("10.10.0.2")}
+	if !reflect.DeepEqual(ips, expected) {
+		t.Fatalf("expect masters %s. Got %s", expected, ips)
+	}
+}
+
+func TestDiscoverMastersDNS(t *testing.T) {
+	ips, err := (&DiscoverMastersDNS{Record: "localhost"}).DiscoverMasters(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) == 0 || !ips[0].IsLoopback() {
+		t.Fatalf("expect localhost to resolve to a loopback address. Got %s", ips)
+	}
+}
+
+func TestDiscoverMastersChainError(t *testing.T) {
+	d := &DiscoverMastersZK{Next: &DiscoverMastersStaticFile{Path: "fixture/missing"}}
+	_, err := d.DiscoverMasters(context.TODO())
+	if err == nil {
+		t.Fatal("expect an error when all discoverers fail")
+	}
+	if !strings.Contains(err.Error(), "zk: ") || !strings.Contains(err.Error(), "static file: ") {
+		t.Fatalf("expect the errors of all discoverers. Got %s", err)
+	}
+}
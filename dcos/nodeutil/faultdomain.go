@@ -0,0 +1,215 @@
+package nodeutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+// AWSCloudMetadataURL returns the availability zone of an instance on AWS. It
+// can be passed to OptionCloudMetadataURL.
+const AWSCloudMetadataURL = "http://169.254.169.254/latest/meta-data/placement/availability-zone"
+
+// cloudMetadataTimeout is the timeout of requests to the cloud provider
+// metadata, which is expected to respond right away if it is available.
+const cloudMetadataTimeout = 2 * time.Second
+
+// FaultDomain is the region and zone a node runs in.
+type FaultDomain struct {
+	Region string
+	Zone   string
+}
+
+// faultDomainInfo is the fault domain format used by mesos and the
+// detect_fault_domain script, e.g.
+// {"fault_domain": {"region": {"name": "aws/us-east-1"}, "zone": {"name": "aws/us-east-1a"}}}
+type faultDomainInfo struct {
+	FaultDomain struct {
+		Region struct {
+			Name string `json:"name"`
+		} `json:"region"`
+		Zone struct {
+			Name string `json:"name"`
+		} `json:"zone"`
+	} `json:"fault_domain"`
+}
+
+// parseFaultDomain parses a fault domain in the format used by mesos.
+func parseFaultDomain(body []byte) (*FaultDomain, error) {
+	var info faultDomainInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, err
+	}
+
+	fd := &FaultDomain{
+		Region: info.FaultDomain.Region.Name,
+		Zone:   info.FaultDomain.Zone.Name,
+	}
+	if fd.Region == "" || fd.Zone == "" {
+		return nil, ErrNodeInfo{fmt.Sprintf("Region or zone missing in fault domain %s", body)}
+	}
+	return fd, nil
+}
+
+// FaultDomainDetector detects the fault domain of a node. The NodeInfo
+// returned by NewNodeInfo implements it.
+type FaultDomainDetector interface {
+	FaultDomain(ctx context.Context) (*FaultDomain, error)
+}
+
+// ensure that dcosInfo implements FaultDomainDetector.
+var _ FaultDomainDetector = &dcosInfo{}
+
+// FaultDomain returns the region and zone of the node. It runs the
+// detect_fault_domain script, then falls back to the domain flag of the local
+// mesos master or agent, and to the cloud provider metadata if configured with
+// OptionCloudMetadataURL.
+func (d *dcosInfo) FaultDomain(ctx context.Context) (*FaultDomain, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	d.Lock()
+	if d.cache && d.cachedFaultDomain != nil {
+		fd := *d.cachedFaultDomain
+		d.Unlock()
+		return &fd, nil
+	}
+	d.Unlock()
+
+	type source struct {
+		name   string
+		detect func(context.Context) (*FaultDomain, error)
+	}
+	sources := []source{
+		{"script", d.faultDomainFromScript},
+		{"mesos", d.faultDomainFromMesos},
+	}
+	if d.cloudMetadataURL != "" {
+		sources = append(sources, source{"cloud", d.faultDomainFromCloud})
+	}
+
+	var msgs []string
+	for _, source := range sources {
+		fd, err := source.detect(ctx)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", source.name, err))
+			continue
+		}
+
+		if d.cache {
+			cached := *fd
+			d.Lock()
+			d.cachedFaultDomain = &cached
+			d.Unlock()
+		}
+		return fd, nil
+	}
+
+	return nil, ErrNodeInfo{fmt.Sprintf("Error getting fault domain: %s", strings.Join(msgs, "; "))}
+}
+
+// faultDomainFromScript runs the detect_fault_domain script.
+func (d *dcosInfo) faultDomainFromScript(ctx context.Context) (*FaultDomain, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.detectIPTimeout)
+	defer cancel()
+
+	body, err := runScript(ctx, d.detectFaultDomainLocation)
+	if err != nil {
+		return nil, err
+	}
+	return parseFaultDomain(body)
+}
+
+// faultDomainFromMesos reads the domain flag of the mesos master or agent
+// running on the node.
+func (d *dcosInfo) faultDomainFromMesos(ctx context.Context) (*FaultDomain, error) {
+	flagsURL := d.mesosFlagsURL
+	if flagsURL == "" {
+		localIP, err := d.DetectIP()
+		if err != nil {
+			return nil, err
+		}
+
+		port := dcos.PortMesosAgent
+		if d.role == dcos.RoleMaster {
+			port = dcos.PortMesosMaster
+		}
+		flagsURL = fmt.Sprintf("http://%s/flags", net.JoinHostPort(localIP.String(), strconv.Itoa(port)))
+	}
+
+	body, err := d.get(ctx, flagsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags struct {
+		Flags struct {
+			Domain string `json:"domain"`
+		} `json:"flags"`
+	}
+	if err := json.Unmarshal(body, &flags); err != nil {
+		return nil, err
+	}
+
+	if flags.Flags.Domain == "" {
+		return nil, ErrNodeInfo{"Mesos domain flag is not set"}
+	}
+	return parseFaultDomain([]byte(flags.Flags.Domain))
+}
+
+// faultDomainFromCloud reads the availability zone from the cloud provider
+// metadata. The region is the zone without its trailing letter. The metadata
+// is requested without the client of the dcosInfo, which may send IAM
+// credentials.
+func (d *dcosInfo) faultDomainFromCloud(ctx context.Context) (*FaultDomain, error) {
+	client := &http.Client{Timeout: cloudMetadataTimeout}
+	body, err := d.getWithClient(ctx, client, d.cloudMetadataURL)
+	if err != nil {
+		return nil, err
+	}
+
+	zone := strings.TrimSpace(string(body))
+	if len(zone) < 2 {
+		return nil, ErrNodeInfo{fmt.Sprintf("Invalid availability zone %q", zone)}
+	}
+	return &FaultDomain{Region: zone[:len(zone)-1], Zone: zone}, nil
+}
+
+// get returns the body of a GET request to the given URL.
+func (d *dcosInfo) get(ctx context.Context, u string) ([]byte, error) {
+	return d.getWithClient(ctx, d.client, u)
+}
+
+// getWithClient returns the body of a GET request to the given URL sent with
+// the given client.
+func (d *dcosInfo) getWithClient(ctx context.Context, client *http.Client, u string) (body []byte, err error) {
+	err = d.retry(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return ErrNodeInfo{fmt.Sprintf("GET request to %s returned response code %d", u, resp.StatusCode)}
+		}
+
+		body, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+	return body, err
+}
@@ -0,0 +1,94 @@
+package nodeutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+func TestFaultDomainScript(t *testing.T) {
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleAgent, OptionDetectFaultDomain(getFixture("detect_fault_domain_good")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := d.(FaultDomainDetector).FaultDomain(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := FaultDomain{Region: "aws/us-east-1", Zone: "aws/us-east-1a"}
+	if *fd != expected {
+		t.Fatalf("expect fault domain %+v. Got %+v", expected, *fd)
+	}
+}
+
+func TestFaultDomainFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flags":
+			fmt.Fprint(w, `{"flags": {"domain": "{\"fault_domain\": {\"region\": {\"name\": \"us-west-2\"}, \"zone\": {\"name\": \"us-west-2b\"}}}"}}`)
+		case "/az":
+			fmt.Fprint(w, "eu-central-1c")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		flagsURL string
+		expected FaultDomain
+	}{
+		{ts.URL + "/flags", FaultDomain{Region: "us-west-2", Zone: "us-west-2b"}},
+		{ts.URL + "/missing", FaultDomain{Region: "eu-central-1", Zone: "eu-central-1c"}},
+	} {
+		d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectFaultDomain("fixture/missing"),
+			OptionMesosFlagsURL(tc.flagsURL), OptionCloudMetadataURL(ts.URL+"/az"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fd, err := d.(FaultDomainDetector).FaultDomain(context.TODO())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *fd != tc.expected {
+			t.Fatalf("expect fault domain %+v. Got %+v", tc.expected, *fd)
+		}
+	}
+
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectFaultDomain("fixture/missing"),
+		OptionMesosFlagsURL(ts.URL+"/missing"), OptionCloudMetadataURL(ts.URL+"/missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.(FaultDomainDetector).FaultDomain(context.TODO()); err == nil {
+		t.Fatal("expect an error when all sources fail")
+	} else if _, ok := err.(ErrNodeInfo); !ok {
+		t.Fatalf("expect error of type ErrNodeInfo. Got %s", err)
+	}
+
+	// the cloud provider metadata is not used by default.
+	d, err = NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectFaultDomain("fixture/missing"),
+		OptionMesosFlagsURL(ts.URL+"/missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.(FaultDomainDetector).FaultDomain(context.TODO()); err == nil || strings.Contains(err.Error(), "cloud") {
+		t.Fatalf("expect an error without the cloud provider metadata. Got %v", err)
+	}
+}
+
+func TestParseFaultDomain(t *testing.T) {
+	for _, invalid := range []string{``, `{}`, `{"fault_domain": {"region": {"name": "us-east-1"}}}`} {
+		if _, err := parseFaultDomain([]byte(invalid)); err == nil {
+			t.Fatalf("expect an error parsing %q", invalid)
+		}
+	}
+}
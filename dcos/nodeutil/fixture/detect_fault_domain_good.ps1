@@ -0,0 +1 @@
+write-output '{"fault_domain": {"region": {"name": "aws/us-east-1"}, "zone": {"name": "aws/us-east-1a"}}}'
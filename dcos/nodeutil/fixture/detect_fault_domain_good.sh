@@ -0,0 +1,3 @@
+#!/bin/bash
+
+echo '{"fault_domain": {"region": {"name": "aws/us-east-1"}, "zone": {"name": "aws/us-east-1a"}}}'
@@ -0,0 +1,219 @@
+package nodeutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dcos/dcos-go/dcos"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// defaultMesosZKPath is the ZK path under which mesos masters elect a leader.
+const defaultMesosZKPath = "/mesos"
+
+// mesosLeaderZnodePrefix is the prefix of the znodes created by mesos masters
+// taking part in the leader election. The one with the lowest sequence number
+// belongs to the leader.
+const mesosLeaderZnodePrefix = "json.info_"
+
+// LeaderStrategy is a way of detecting whether a master is the leader.
+type LeaderStrategy int
+
+const (
+	// LeaderStrategyDNS resolves the leader DNS record and compares the
+	// result with the IP of the node, see OptionLeaderDNSRecord.
+	LeaderStrategyDNS LeaderStrategy = iota + 1
+
+	// LeaderStrategyMesos asks the mesos master running on the node for its
+	// leader, see OptionStateSummaryURL.
+	LeaderStrategyMesos
+
+	// LeaderStrategyZK reads the leader from the mesos leader election znodes
+	// in ZK, see OptionZKConn.
+	LeaderStrategyZK
+)
+
+func (s LeaderStrategy) String() string {
+	switch s {
+	case LeaderStrategyDNS:
+		return "dns"
+	case LeaderStrategyMesos:
+		return "mesos"
+	case LeaderStrategyZK:
+		return "zk"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// ZKConn is the part of a *zk.Conn used to find the mesos leader.
+type ZKConn interface {
+	Children(path string) ([]string, *zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+}
+
+// leadership returns whether the node with the given IP is the leader. The
+// leader strategies are tried in order until one of them succeeds. If
+// strictDNS is set, a leader DNS record that does not resolve to the node is
+// an error rather than a sign that the node does not lead, see leaderFromDNS.
+func (d *dcosInfo) leadership(localIP net.IP, strictDNS bool) (bool, error) {
+	var errs []error
+	for _, strategy := range d.leaderStrategies {
+		isLeader, err := d.detectLeader(strategy, localIP, strictDNS)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return isLeader, nil
+	}
+
+	// a single strategy fails with its own error.
+	if len(errs) == 1 {
+		return false, errs[0]
+	}
+
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = fmt.Sprintf("%s: %s", d.leaderStrategies[i], err)
+	}
+	return false, ErrNodeInfo{fmt.Sprintf("Error getting mesos leader: %s", strings.Join(msgs, "; "))}
+}
+
+// detectLeader returns whether the node with the given IP is the leader,
+// using the given strategy.
+func (d *dcosInfo) detectLeader(strategy LeaderStrategy, localIP net.IP, strictDNS bool) (bool, error) {
+	switch strategy {
+	case LeaderStrategyDNS:
+		return d.leaderFromDNS(localIP, strictDNS)
+	case LeaderStrategyMesos:
+		return d.leaderFromMesos(localIP)
+	case LeaderStrategyZK:
+		return d.leaderFromZK(localIP)
+	}
+	return false, ErrNodeInfo{fmt.Sprintf("Invalid leader strategy %s", strategy)}
+}
+
+// leaderFromDNS returns true if the leader DNS record resolves to the local
+// IP. Otherwise it returns false if strict is not set, or an error if it is,
+// since the record may not be up to date.
+func (d *dcosInfo) leaderFromDNS(localIP net.IP, strict bool) (bool, error) {
+	addrs, err := net.LookupIP(d.dnsRecordLeader)
+	if err != nil {
+		return false, err
+	}
+
+	for _, addr := range addrs {
+		if localIP.Equal(addr) {
+			return true, nil
+		}
+	}
+
+	if !strict {
+		return false, nil
+	}
+	return false, ErrNodeInfo{fmt.Sprintf("Error getting mesos leader. Number of ip addresses %d", len(addrs))}
+}
+
+// leaderFromMesos returns whether the leader reported by the mesos master
+// state summary has the local IP.
+func (d *dcosInfo) leaderFromMesos(localIP net.IP) (bool, error) {
+	summaryURL := d.stateSummaryURL
+	if summaryURL == "" {
+		summaryURL = fmt.Sprintf("http://%s/state-summary", net.JoinHostPort(localIP.String(), strconv.Itoa(dcos.PortMesosMaster)))
+	}
+
+	resp, err := d.client.Get(summaryURL)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, ErrNodeInfo{fmt.Sprintf("GET request to %s returned response code %d", summaryURL, resp.StatusCode)}
+	}
+
+	var summary struct {
+		Leader string `json:"leader"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return false, err
+	}
+	if summary.Leader == "" {
+		return false, ErrNodeInfo{"Mesos state summary has no leader"}
+	}
+
+	leaderIP, err := getIPFromPIDField(summary.Leader)
+	if err != nil {
+		return false, err
+	}
+	return localIP.Equal(*leaderIP), nil
+}
+
+// leaderFromZK returns whether the mesos master that won the leader election
+// in ZK has the local IP.
+func (d *dcosInfo) leaderFromZK(localIP net.IP) (bool, error) {
+	if d.zkConn == nil {
+		return false, ErrNodeInfo{"No ZK connection configured"}
+	}
+
+	contenders, err := zkContenders(d.zkConn, d.mesosZKPath)
+	if err != nil {
+		return false, err
+	}
+	if len(contenders) == 0 {
+		return false, ErrNodeInfo{fmt.Sprintf("No mesos leader found in ZK at %s", d.mesosZKPath)}
+	}
+
+	leaderIP, err := zkContenderIP(d.zkConn, d.mesosZKPath, contenders[0])
+	if err != nil {
+		return false, err
+	}
+	return localIP.Equal(leaderIP), nil
+}
+
+// zkContenders returns the znodes of the mesos masters taking part in the
+// leader election at path, the one of the leader first.
+func zkContenders(conn ZKConn, path string) ([]string, error) {
+	children, _, err := conn.Children(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var contenders []string
+	for _, child := range children {
+		if strings.HasPrefix(child, mesosLeaderZnodePrefix) {
+			contenders = append(contenders, child)
+		}
+	}
+	// the sequence numbers are zero padded, so they sort lexically.
+	sort.Strings(contenders)
+	return contenders, nil
+}
+
+// zkContenderIP returns the IP of the mesos master that created the given
+// leader election znode.
+func zkContenderIP(conn ZKConn, path, contender string) (net.IP, error) {
+	data, _, err := conn.Get(path + "/" + contender)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Address struct {
+			IP string `json:"ip"`
+		} `json:"address"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(info.Address.IP)
+	if ip == nil {
+		return nil, ErrNodeInfo{fmt.Sprintf("Incorrect IP in mesos leader info %s", info.Address.IP)}
+	}
+	return ip, nil
+}
@@ -0,0 +1,113 @@
+package nodeutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-go/dcos"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+type fakeZKConn map[string][]byte
+
+func (c fakeZKConn) Children(path string) ([]string, *zk.Stat, error) {
+	var children []string
+	for p := range c {
+		children = append(children, p)
+	}
+	return children, &zk.Stat{}, nil
+}
+
+func (c fakeZKConn) Get(path string) ([]byte, *zk.Stat, error) {
+	for p, data := range c {
+		if "/mesos/"+p == path {
+			return data, &zk.Stat{}, nil
+		}
+	}
+	return nil, nil, zk.ErrNoNode
+}
+
+func TestIsLeaderMesos(t *testing.T) {
+	for leader, expected := range map[string]bool{
+		"master@10.10.0.1:5050": true,
+		"master@10.10.0.2:5050": false,
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"hostname": "10.10.0.1", "leader": %q}`, leader)
+		}))
+
+		d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectIP(getFixture("detect_ip_good")),
+			OptionLeaderStrategies(LeaderStrategyMesos), OptionStateSummaryURL(ts.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		isLeader, err := d.IsLeader()
+		ts.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isLeader != expected {
+			t.Fatalf("Expect IsLeader %t with leader %s. Got %t", expected, leader, isLeader)
+		}
+	}
+}
+
+func TestIsLeaderZK(t *testing.T) {
+	conn := fakeZKConn{
+		"json.info_0000000002": []byte(`{"address": {"ip": "10.10.0.2"}}`),
+		"json.info_0000000001": []byte(`{"address": {"ip": "10.10.0.1"}}`),
+		"log_replicas":         nil,
+	}
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectIP(getFixture("detect_ip_good")),
+		OptionLeaderStrategies(LeaderStrategyZK), OptionZKConn(conn, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isLeader, err := d.IsLeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isLeader {
+		t.Fatal("Expect the node to be the leader")
+	}
+}
+
+func TestIsLeaderFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"leader": "master@10.10.0.1:5050"}`)
+	}))
+	defer ts.Close()
+
+	// the ZK strategy fails without a ZK connection.
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectIP(getFixture("detect_ip_good")),
+		OptionLeaderStrategies(LeaderStrategyZK, LeaderStrategyMesos), OptionStateSummaryURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	isLeader, err := d.IsLeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isLeader {
+		t.Fatal("Expect the node to be the leader")
+	}
+
+	d, err = NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectIP(getFixture("detect_ip_good")),
+		OptionLeaderStrategies(LeaderStrategyZK, LeaderStrategyMesos), OptionStateSummaryURL("http://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.IsLeader(); err == nil {
+		t.Fatal("Expect an error when all strategies fail")
+	} else if _, ok := err.(ErrNodeInfo); !ok {
+		t.Fatalf("Expect error of type ErrNodeInfo. Got %s", err)
+	}
+
+	if _, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionLeaderStrategies(LeaderStrategy(42))); err == nil {
+		t.Fatal("Expect an invalid strategy to be rejected")
+	}
+}
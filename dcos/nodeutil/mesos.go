@@ -41,7 +41,24 @@ type Task struct {
 	State       string `json:"state"`
 	Role        string `json:"role"`
 
-	Statuses []Status `json:"statuses"`
+	Resources Resources `json:"resources"`
+	Labels    []Label   `json:"labels"`
+	Statuses  []Status  `json:"statuses"`
+}
+
+// Resources is a field in state.json
+type Resources struct {
+	CPUs  float64 `json:"cpus"`
+	Mem   float64 `json:"mem"`
+	Disk  float64 `json:"disk"`
+	GPUs  float64 `json:"gpus"`
+	Ports string  `json:"ports"`
+}
+
+// Label is a field in state.json
+type Label struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 // Status is a field in state.json
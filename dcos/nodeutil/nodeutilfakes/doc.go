@@ -0,0 +1,24 @@
+// Package nodeutilfakes provides a configurable fake of nodeutil.NodeInfo for
+// use in tests.
+//
+// Code written against nodeutil.NodeInfo can be unit tested without detect_ip
+// scripts or httptest servers standing in for mesos:
+//
+//	func NewService(node nodeutil.NodeInfo) *Service { ... }
+//
+//	func TestService(t *testing.T) {
+//		node := &nodeutilfakes.NodeInfo{
+//			IP:     net.ParseIP("10.0.0.1"),
+//			Role:   dcos.RoleMaster,
+//			Leader: true,
+//			State:  nodeutil.State{ID: "master-id"},
+//		}
+//		svc := NewService(node)
+//		...
+//		node.SetLeader(false) // WatchLeadership reports the failover
+//	}
+//
+// Methods derive their results from the fields the same way nodeutil does,
+// e.g. MesosID finds the agent with the IP of the node in State, and fail
+// with the error set for them in Errors.
+package nodeutilfakes
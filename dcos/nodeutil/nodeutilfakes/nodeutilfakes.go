@@ -0,0 +1,316 @@
+package nodeutilfakes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/dcos/dcos-go/dcos"
+	"github.com/dcos/dcos-go/dcos/nodeutil"
+)
+
+// NodeInfo is a fake nodeutil.NodeInfo. The fields must be set before the
+// fake is used, except the leadership which can be changed with SetLeader.
+// It is safe for concurrent use.
+type NodeInfo struct {
+	// IP is returned by DetectIP.
+	IP net.IP
+
+	// Role is the role of the node. Defaults to dcos.RoleMaster.
+	Role string
+
+	// Leader is returned by IsLeader on masters.
+	Leader bool
+
+	// ClusterUUID is returned by ClusterID.
+	ClusterUUID string
+
+	// State is the mesos state used by MesosID, TaskCanonicalID and ListTasks.
+	State nodeutil.State
+
+	// Domain is returned by FaultDomain.
+	Domain *nodeutil.FaultDomain
+
+	// Attributes and Resources are returned by AgentAttributes and
+	// AgentResources on agents.
+	Attributes map[string]string
+	Resources  nodeutil.Resources
+
+	// Units is returned by SystemdUnits.
+	Units []nodeutil.Unit
+
+	// Version is returned by DCOSVersion.
+	Version *nodeutil.DCOSVersion
+
+	// Errors are returned by the methods with the given names, e.g.
+	// Errors["MesosID"].
+	Errors map[string]error
+
+	mu       sync.Mutex
+	watchers map[chan struct{}]struct{}
+}
+
+// ensure that NodeInfo confirms to the nodeutil.NodeInfo interface, and to
+// the interfaces of the features implemented by nodeutil.NewNodeInfo.
+var _ nodeutil.NodeInfo = &NodeInfo{}
+var _ nodeutil.TaskLister = &NodeInfo{}
+var _ nodeutil.LeadershipWatcher = &NodeInfo{}
+var _ nodeutil.FaultDomainDetector = &NodeInfo{}
+var _ nodeutil.AgentInspector = &NodeInfo{}
+var _ nodeutil.UnitLister = &NodeInfo{}
+var _ nodeutil.VersionDetector = &NodeInfo{}
+
+func (n *NodeInfo) err(method string) error {
+	return n.Errors[method]
+}
+
+func (n *NodeInfo) role() string {
+	if n.Role == "" {
+		return dcos.RoleMaster
+	}
+	return n.Role
+}
+
+// SetLeader changes the leadership of the node, which is reported to
+// WatchLeadership.
+func (n *NodeInfo) SetLeader(leader bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Leader = leader
+	for w := range n.watchers {
+		select {
+		case w <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// DetectIP returns IP.
+func (n *NodeInfo) DetectIP() (net.IP, error) {
+	if err := n.err("DetectIP"); err != nil {
+		return nil, err
+	}
+	if n.IP == nil {
+		return nil, errors.New("nodeutilfakes: IP is not set")
+	}
+	return n.IP, nil
+}
+
+// IsLeader returns Leader on masters, and false on agents.
+func (n *NodeInfo) IsLeader() (bool, error) {
+	if err := n.err("IsLeader"); err != nil {
+		return false, err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.role() == dcos.RoleMaster && n.Leader, nil
+}
+
+// WatchLeadership sends the initial leadership of the node and every change
+// made with SetLeader, until ctx is done.
+func (n *NodeInfo) WatchLeadership(ctx context.Context) <-chan nodeutil.LeadershipEvent {
+	changed := make(chan struct{}, 1)
+	n.mu.Lock()
+	if n.watchers == nil {
+		n.watchers = make(map[chan struct{}]struct{})
+	}
+	n.watchers[changed] = struct{}{}
+	n.mu.Unlock()
+
+	events := make(chan nodeutil.LeadershipEvent)
+	go func() {
+		defer close(events)
+		defer func() {
+			n.mu.Lock()
+			delete(n.watchers, changed)
+			n.mu.Unlock()
+		}()
+
+		var last *nodeutil.LeadershipEvent
+		for {
+			var event nodeutil.LeadershipEvent
+			event.IsLeader, event.Err = n.IsLeader()
+			if last == nil || event.Err != nil || last.Err != nil || last.IsLeader != event.IsLeader {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				last = &event
+			}
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// MesosID returns the ID of State on masters, and the ID of the agent with
+// the IP of the node on agents.
+func (n *NodeInfo) MesosID(ctx context.Context) (string, error) {
+	if err := n.err("MesosID"); err != nil {
+		return "", err
+	}
+	if n.role() == dcos.RoleMaster {
+		if n.State.ID == "" {
+			return "", errors.New("nodeutilfakes: State.ID is not set")
+		}
+		return n.State.ID, nil
+	}
+
+	for _, slave := range n.State.Slaves {
+		if strings.Contains(slave.Pid, "@"+n.IP.String()+":") {
+			return slave.ID, nil
+		}
+	}
+	return "", fmt.Errorf("nodeutilfakes: no agent with IP %s in State", n.IP)
+}
+
+// ClusterID returns ClusterUUID.
+func (n *NodeInfo) ClusterID() (string, error) {
+	if err := n.err("ClusterID"); err != nil {
+		return "", err
+	}
+	if n.ClusterUUID == "" {
+		return "", errors.New("nodeutilfakes: ClusterUUID is not set")
+	}
+	return n.ClusterUUID, nil
+}
+
+// TaskCanonicalID returns the canonical ID of the task in State with the
+// given name, or whose ID contains it.
+func (n *NodeInfo) TaskCanonicalID(ctx context.Context, task string, completed bool) (*nodeutil.CanonicalTaskID, error) {
+	if err := n.err("TaskCanonicalID"); err != nil {
+		return nil, err
+	}
+
+	frameworks := n.State.Frameworks
+	if completed {
+		frameworks = append(frameworks[:len(frameworks):len(frameworks)], n.State.CompletedFrameworks...)
+	}
+
+	var found []nodeutil.Task
+	for _, f := range frameworks {
+		tasks := f.Tasks
+		if completed {
+			tasks = f.CompletedTasks
+		}
+		for _, t := range tasks {
+			if t.Name == task || strings.Contains(t.ID, task) {
+				found = append(found, t)
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, nodeutil.ErrTaskNotFound
+	} else if len(found) > 1 {
+		return nil, fmt.Errorf("found more then 1 task with name %s", task)
+	}
+
+	t := found[0]
+	containerIDs, err := t.ContainerIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	return &nodeutil.CanonicalTaskID{
+		ID:           t.ID,
+		AgentID:      t.SlaveID,
+		FrameworkID:  t.FrameworkID,
+		ExecutorID:   t.ExecutorID,
+		ContainerIDs: containerIDs,
+	}, nil
+}
+
+// ListTasks returns the tasks in State that match the filter.
+func (n *NodeInfo) ListTasks(ctx context.Context, filter nodeutil.Filter) ([]nodeutil.Task, error) {
+	if err := n.err("ListTasks"); err != nil {
+		return nil, err
+	}
+
+	var tasks []nodeutil.Task
+	for _, frameworks := range [][]nodeutil.Framework{n.State.Frameworks, n.State.CompletedFrameworks} {
+		for _, f := range frameworks {
+			if filter.Framework != "" && f.ID != filter.Framework && f.Name != filter.Framework {
+				continue
+			}
+			for _, t := range append(f.Tasks[:len(f.Tasks):len(f.Tasks)], f.CompletedTasks...) {
+				if filter.State != "" && t.State != filter.State {
+					continue
+				}
+				if filter.AgentID != "" && t.SlaveID != filter.AgentID {
+					continue
+				}
+				tasks = append(tasks, t)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// FaultDomain returns Domain.
+func (n *NodeInfo) FaultDomain(ctx context.Context) (*nodeutil.FaultDomain, error) {
+	if err := n.err("FaultDomain"); err != nil {
+		return nil, err
+	}
+	if n.Domain == nil {
+		return nil, errors.New("nodeutilfakes: Domain is not set")
+	}
+	fd := *n.Domain
+	return &fd, nil
+}
+
+// AgentAttributes returns Attributes on agents.
+func (n *NodeInfo) AgentAttributes(ctx context.Context) (map[string]string, error) {
+	if err := n.err("AgentAttributes"); err != nil {
+		return nil, err
+	}
+	if n.role() == dcos.RoleMaster {
+		return nil, errors.New("nodeutilfakes: agent attributes are not available on master nodes")
+	}
+	attributes := make(map[string]string, len(n.Attributes))
+	for k, v := range n.Attributes {
+		attributes[k] = v
+	}
+	return attributes, nil
+}
+
+// AgentResources returns Resources on agents.
+func (n *NodeInfo) AgentResources(ctx context.Context) (*nodeutil.Resources, error) {
+	if err := n.err("AgentResources"); err != nil {
+		return nil, err
+	}
+	if n.role() == dcos.RoleMaster {
+		return nil, errors.New("nodeutilfakes: agent resources are not available on master nodes")
+	}
+	resources := n.Resources
+	return &resources, nil
+}
+
+// SystemdUnits returns Units.
+func (n *NodeInfo) SystemdUnits(ctx context.Context) ([]nodeutil.Unit, error) {
+	if err := n.err("SystemdUnits"); err != nil {
+		return nil, err
+	}
+	return append([]nodeutil.Unit(nil), n.Units...), nil
+}
+
+// DCOSVersion returns Version.
+func (n *NodeInfo) DCOSVersion(ctx context.Context) (*nodeutil.DCOSVersion, error) {
+	if err := n.err("DCOSVersion"); err != nil {
+		return nil, err
+	}
+	if n.Version == nil {
+		return nil, errors.New("nodeutilfakes: Version is not set")
+	}
+	v := *n.Version
+	return &v, nil
+}
@@ -0,0 +1,99 @@
+package nodeutilfakes
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-go/dcos"
+	"github.com/dcos/dcos-go/dcos/nodeutil"
+)
+
+var state = nodeutil.State{
+	ID:     "master-id",
+	Slaves: []nodeutil.Slave{{ID: "agent-id", Pid: "slave(1)@10.0.0.2:5051"}},
+	Frameworks: []nodeutil.Framework{{
+		ID:   "framework-id",
+		Name: "marathon",
+		Tasks: []nodeutil.Task{{
+			ID:          "app.1",
+			Name:        "app",
+			FrameworkID: "framework-id",
+			SlaveID:     "agent-id",
+			State:       "TASK_RUNNING",
+			Statuses: []nodeutil.Status{{ContainerStatus: nodeutil.ContainerStatus{
+				ContainerID: nodeutil.NestedValue{Value: "container-id"},
+			}}},
+		}},
+	}},
+}
+
+func TestNodeInfo(t *testing.T) {
+	master := &NodeInfo{IP: net.ParseIP("10.0.0.1"), Leader: true, State: state}
+	agent := &NodeInfo{IP: net.ParseIP("10.0.0.2"), Role: dcos.RoleAgent, Leader: true, State: state}
+
+	if leader, err := master.IsLeader(); err != nil || !leader {
+		t.Fatalf("expect the master to be the leader. Got %t, %v", leader, err)
+	}
+	if leader, err := agent.IsLeader(); err != nil || leader {
+		t.Fatalf("expect the agent not to be the leader. Got %t, %v", leader, err)
+	}
+
+	if id, err := master.MesosID(context.TODO()); err != nil || id != "master-id" {
+		t.Fatalf("expect mesos id master-id. Got %s, %v", id, err)
+	}
+	if id, err := agent.MesosID(context.TODO()); err != nil || id != "agent-id" {
+		t.Fatalf("expect mesos id agent-id. Got %s, %v", id, err)
+	}
+
+	cID, err := master.TaskCanonicalID(context.TODO(), "app", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cID.ID != "app.1" || cID.AgentID != "agent-id" || len(cID.ContainerIDs) != 1 {
+		t.Fatalf("unexpected canonical task id %+v", cID)
+	}
+	if _, err := master.TaskCanonicalID(context.TODO(), "app", true); err != nodeutil.ErrTaskNotFound {
+		t.Fatalf("expect error %s. Got %v", nodeutil.ErrTaskNotFound, err)
+	}
+
+	tasks, err := master.ListTasks(context.TODO(), nodeutil.Filter{Framework: "marathon", State: "TASK_RUNNING"})
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("expect 1 task. Got %d, %v", len(tasks), err)
+	}
+
+	if _, err := master.AgentResources(context.TODO()); err == nil {
+		t.Fatal("expect an error getting agent resources on a master")
+	}
+
+	errMesos := errors.New("mesos unavailable")
+	master.Errors = map[string]error{"MesosID": errMesos}
+	if _, err := master.MesosID(context.TODO()); err != errMesos {
+		t.Fatalf("expect error %s. Got %v", errMesos, err)
+	}
+}
+
+func TestWatchLeadership(t *testing.T) {
+	node := &NodeInfo{IP: net.ParseIP("10.0.0.1")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := node.WatchLeadership(ctx)
+
+	for _, leader := range []bool{false, true, false} {
+		node.SetLeader(leader)
+		select {
+		case event := <-events:
+			if event.Err != nil || event.IsLeader != leader {
+				t.Fatalf("expect leader %t. Got %+v", leader, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a leadership event")
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
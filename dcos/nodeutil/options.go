@@ -2,6 +2,7 @@ package nodeutil
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"time"
 )
@@ -76,3 +77,151 @@ func OptionClusterIDFile(f string) Option {
 		return nil
 	}
 }
+
+// OptionLeaderStrategies sets the strategies used by IsLeader to detect
+// whether the node is the leading master. They are tried in the given order
+// until one of them succeeds. Defaults to LeaderStrategyDNS.
+func OptionLeaderStrategies(strategies ...LeaderStrategy) Option {
+	return func(d *dcosInfo) error {
+		if len(strategies) == 0 {
+			return ErrEmptyParam
+		}
+		for _, s := range strategies {
+			if s < LeaderStrategyDNS || s > LeaderStrategyZK {
+				return fmt.Errorf("invalid leader strategy %s", s)
+			}
+		}
+		d.leaderStrategies = strategies
+		return nil
+	}
+}
+
+// OptionStateSummaryURL sets the URL of the mesos master state summary used by
+// LeaderStrategyMesos. Defaults to the state summary of the mesos master on
+// the IP returned by DetectIP.
+func OptionStateSummaryURL(summaryURL string) Option {
+	return func(d *dcosInfo) error {
+		if summaryURL == "" {
+			return ErrEmptyParam
+		}
+		d.stateSummaryURL = summaryURL
+		return nil
+	}
+}
+
+// OptionZKConn sets the ZK connection used by LeaderStrategyZK, along with the
+// path of the mesos leader election, which defaults to /mesos if empty.
+func OptionZKConn(conn ZKConn, path string) Option {
+	return func(d *dcosInfo) error {
+		if conn == nil {
+			return ErrEmptyParam
+		}
+		d.zkConn = conn
+		if path != "" {
+			d.mesosZKPath = path
+		}
+		return nil
+	}
+}
+
+// OptionLeaderPollInterval sets how often WatchLeadership detects the
+// leadership of the node. Defaults to 5 seconds.
+func OptionLeaderPollInterval(interval time.Duration) Option {
+	return func(d *dcosInfo) error {
+		if interval <= 0 {
+			return ErrEmptyParam
+		}
+
+		d.leaderPoll = interval
+		return nil
+	}
+}
+
+// OptionDetectFaultDomain sets a path to the detect_fault_domain script used by FaultDomain.
+func OptionDetectFaultDomain(path string) Option {
+	return func(d *dcosInfo) error {
+		if path == "" {
+			return ErrEmptyParam
+		}
+		d.detectFaultDomainLocation = path
+		return nil
+	}
+}
+
+// OptionMesosFlagsURL sets the URL of the mesos flags used by FaultDomain.
+// Defaults to the flags of the mesos master or agent on the IP returned by
+// DetectIP.
+func OptionMesosFlagsURL(flagsURL string) Option {
+	return func(d *dcosInfo) error {
+		if flagsURL == "" {
+			return ErrEmptyParam
+		}
+		d.mesosFlagsURL = flagsURL
+		return nil
+	}
+}
+
+// OptionCloudMetadataURL sets the URL of the cloud provider metadata returning
+// the availability zone of the node, e.g. AWSCloudMetadataURL. FaultDomain
+// only falls back to the cloud provider metadata if it is set.
+func OptionCloudMetadataURL(metadataURL string) Option {
+	return func(d *dcosInfo) error {
+		if metadataURL == "" {
+			return ErrEmptyParam
+		}
+		d.cloudMetadataURL = metadataURL
+		return nil
+	}
+}
+
+// OptionAgentStateURL sets the URL of the mesos agent state used by
+// AgentAttributes and AgentResources. Defaults to the state of the mesos agent
+// on the IP returned by DetectIP.
+func OptionAgentStateURL(stateURL string) Option {
+	return func(d *dcosInfo) error {
+		if stateURL == "" {
+			return ErrEmptyParam
+		}
+		d.agentStateURL = stateURL
+		return nil
+	}
+}
+
+// OptionRetryPolicy sets how requests to mesos are retried by MesosID,
+// TaskCanonicalID, ListTasks, FaultDomain, AgentAttributes and AgentResources,
+// so callers do not fail on transient errors during cluster bootstrap.
+// Requests are not retried by default.
+func OptionRetryPolicy(policy RetryPolicy) Option {
+	return func(d *dcosInfo) error {
+		if policy.InitialBackoff < 0 || policy.MaxBackoff < 0 || policy.AttemptTimeout < 0 || policy.MaxElapsedTime < 0 {
+			return fmt.Errorf("invalid retry policy %+v", policy)
+		}
+		if policy.MaxElapsedTime > 0 && policy.InitialBackoff == 0 {
+			return fmt.Errorf("invalid retry policy %+v: InitialBackoff must be set", policy)
+		}
+		d.retryPolicy = policy
+		return nil
+	}
+}
+
+// OptionSystemctl sets the systemctl command used by SystemdUnits.
+func OptionSystemctl(path string) Option {
+	return func(d *dcosInfo) error {
+		if path == "" {
+			return ErrEmptyParam
+		}
+		d.systemctlPath = path
+		return nil
+	}
+}
+
+// OptionDCOSVersionFile sets a path to dcos-version.json file.
+func OptionDCOSVersionFile(f string) Option {
+	return func(d *dcosInfo) error {
+		if f == "" {
+			return ErrEmptyParam
+		}
+		d.dcosVersionLocation = f
+		return nil
+	}
+}
@@ -0,0 +1,82 @@
+package nodeutil
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// RetryPolicy configures how requests to mesos are retried, see
+// OptionRetryPolicy. The backoff doubles after every failed attempt, starting
+// at InitialBackoff, up to MaxBackoff. The zero value disables retries.
+type RetryPolicy struct {
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff. Zero means no cap, the backoff keeps
+	// doubling until MaxElapsedTime.
+	MaxBackoff time.Duration
+
+	// AttemptTimeout limits the duration of every attempt. Zero means no
+	// limit other than the context of the caller.
+	AttemptTimeout time.Duration
+
+	// MaxElapsedTime is the time after which a request is no longer retried.
+	// Zero disables retries.
+	MaxElapsedTime time.Duration
+}
+
+// backoff returns how long to wait after the given number of failed attempts.
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempts && d > 0 && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// retry calls fn until it succeeds, as configured by the retry policy. It
+// gives up once the next attempt would start after the maximum elapsed time
+// or ctx is done, and returns the error of the last attempt.
+func (d *dcosInfo) retry(ctx context.Context, fn func(context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	for attempts := 1; ; attempts++ {
+		err := d.attempt(ctx, fn)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		backoff := d.retryPolicy.backoff(attempts)
+		if backoff >= d.retryPolicy.MaxElapsedTime-time.Since(start) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// attempt calls fn once, limited by the attempt timeout of the retry policy.
+func (d *dcosInfo) attempt(ctx context.Context, fn func(context.Context) error) error {
+	if d.retryPolicy.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.retryPolicy.AttemptTimeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
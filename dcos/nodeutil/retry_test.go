@@ -0,0 +1,119 @@
+package nodeutil
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		policy   RetryPolicy
+		attempts int
+		expected time.Duration
+	}{
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 1, time.Second},
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 3, 4 * time.Second},
+		{RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 50, 5 * time.Second},
+		// zero MaxBackoff does not cap the backoff.
+		{RetryPolicy{InitialBackoff: time.Second}, 1, time.Second},
+		{RetryPolicy{InitialBackoff: time.Second}, 4, 8 * time.Second},
+		{RetryPolicy{InitialBackoff: time.Second}, 1000, math.MaxInt64},
+	} {
+		if d := tc.policy.backoff(tc.attempts); d != tc.expected {
+			t.Fatalf("expect a backoff of %s after %d attempts with %+v. Got %s", tc.expected, tc.attempts, tc.policy, d)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	// the first two requests fail, the second one by timing out.
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			<-r.Context().Done()
+		default:
+			fmt.Fprint(w, `{"id": "master-id"}`)
+		}
+	}))
+	defer ts.Close()
+
+	policy := RetryPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		AttemptTimeout: 100 * time.Millisecond,
+		MaxElapsedTime: 5 * time.Second,
+	}
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectIP(getFixture("detect_ip_good")),
+		OptionMesosStateURL(ts.URL), OptionRetryPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := d.MesosID(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "master-id" {
+		t.Fatalf("expect mesos id master-id. Got %s", id)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("expect 3 requests. Got %d", n)
+	}
+}
+
+func TestRetryPolicyGivesUp(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	// without a retry policy, requests are not retried.
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionMesosStateURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.(TaskLister).ListTasks(context.TODO(), Filter{}); err == nil {
+		t.Fatal("expect an error")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expect 1 request. Got %d", n)
+	}
+
+	policy := RetryPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+		MaxElapsedTime: 200 * time.Millisecond,
+	}
+	d, err = NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionMesosStateURL(ts.URL), OptionRetryPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = d.(TaskLister).ListTasks(context.TODO(), Filter{})
+	if _, ok := err.(ErrNodeInfo); !ok {
+		t.Fatalf("expect the error of the last attempt. Got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expect to give up after the maximum elapsed time. Took %s", elapsed)
+	}
+	if n := atomic.LoadInt32(&requests); n < 3 {
+		t.Fatalf("expect the request to be retried. Got %d requests", n)
+	}
+
+	if _, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionRetryPolicy(RetryPolicy{MaxElapsedTime: time.Second})); err == nil {
+		t.Fatal("expect a retry policy without backoff to be invalid")
+	}
+}
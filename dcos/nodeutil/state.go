@@ -0,0 +1,159 @@
+package nodeutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeState decodes a mesos state.json from r, keeping only the tasks for
+// which keepTask returns true, or all tasks if keepTask is nil.
+//
+// state.json can grow to hundreds of megabytes on large clusters, so rather
+// than decoding it at once, it is streamed token by token: only a single
+// slave, task or framework without its tasks is held in memory at a time,
+// and all fields that State does not need are skipped.
+func decodeState(r io.Reader, keepTask func(Task) bool) (state State, err error) {
+	dec := json.NewDecoder(r)
+	err = decodeObject(dec, func(key string) error {
+		switch key {
+		case "id":
+			return dec.Decode(&state.ID)
+		case "slaves":
+			return decodeArray(dec, func() error {
+				var slave Slave
+				if err := dec.Decode(&slave); err != nil {
+					return err
+				}
+				state.Slaves = append(state.Slaves, slave)
+				return nil
+			})
+		case "frameworks":
+			return decodeFrameworks(dec, keepTask, &state.Frameworks)
+		case "completed_frameworks":
+			return decodeFrameworks(dec, keepTask, &state.CompletedFrameworks)
+		}
+		return skipValue(dec)
+	})
+	if err != nil {
+		return state, fmt.Errorf("could not decode mesos state: %s", err)
+	}
+	return state, nil
+}
+
+// decodeFrameworks decodes an array of frameworks, appending them to
+// frameworks.
+func decodeFrameworks(dec *json.Decoder, keepTask func(Task) bool, frameworks *[]Framework) error {
+	return decodeArray(dec, func() error {
+		var f Framework
+		err := decodeObject(dec, func(key string) error {
+			switch key {
+			case "id":
+				return dec.Decode(&f.ID)
+			case "name":
+				return dec.Decode(&f.Name)
+			case "pid":
+				return dec.Decode(&f.PID)
+			case "role":
+				return dec.Decode(&f.Role)
+			case "tasks":
+				return decodeTasks(dec, keepTask, &f.Tasks)
+			case "completed_tasks":
+				return decodeTasks(dec, keepTask, &f.CompletedTasks)
+			}
+			return skipValue(dec)
+		})
+		if err != nil {
+			return err
+		}
+		*frameworks = append(*frameworks, f)
+		return nil
+	})
+}
+
+// decodeTasks decodes an array of tasks, appending those to keep to tasks.
+func decodeTasks(dec *json.Decoder, keepTask func(Task) bool, tasks *[]Task) error {
+	return decodeArray(dec, func() error {
+		var t Task
+		if err := dec.Decode(&t); err != nil {
+			return err
+		}
+		if keepTask == nil || keepTask(t) {
+			*tasks = append(*tasks, t)
+		}
+		return nil
+	})
+}
+
+// decodeObject reads a JSON object, calling fn with each key. fn must consume
+// the value of the key. A null value is treated as an empty object.
+func decodeObject(dec *json.Decoder, fn func(key string) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("expected an object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expected an object key, got %v", tok)
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token() // the closing }
+	return err
+}
+
+// decodeArray reads a JSON array, calling fn for each element. fn must
+// consume the element. A null value is treated as an empty array.
+func decodeArray(dec *json.Decoder, fn func() error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected an array, got %v", tok)
+	}
+	for dec.More() {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token() // the closing ]
+	return err
+}
+
+// skipValue reads and discards the next JSON value without holding it in
+// memory as a whole.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
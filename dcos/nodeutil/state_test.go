@@ -0,0 +1,86 @@
+package nodeutil
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeState(t *testing.T) {
+	body, err := ioutil.ReadFile("fixture/state.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected State
+	if err := json.Unmarshal(body, &expected); err != nil {
+		t.Fatal(err)
+	}
+	// decodeState leaves empty arrays nil.
+	for _, frameworks := range [][]Framework{expected.Frameworks, expected.CompletedFrameworks} {
+		for i := range frameworks {
+			if len(frameworks[i].Tasks) == 0 {
+				frameworks[i].Tasks = nil
+			}
+			if len(frameworks[i].CompletedTasks) == 0 {
+				frameworks[i].CompletedTasks = nil
+			}
+		}
+	}
+	if len(expected.CompletedFrameworks) == 0 {
+		expected.CompletedFrameworks = nil
+	}
+
+	f, err := os.Open("fixture/state.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	state, err := decodeState(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state, expected) {
+		t.Fatalf("expect the streamed state to match the unmarshalled one.\nExpected: %+v\nGot: %+v", expected, state)
+	}
+}
+
+func TestDecodeStateFilter(t *testing.T) {
+	body := `{
+		"id": "master-id",
+		"leader_info": {"id": "ignored", "nested": [1, [2, {"a": null}]]},
+		"slaves": [{"id": "agent-id", "pid": "slave(1)@10.0.0.1:5051", "unknown": {}}],
+		"frameworks": [{
+			"id": "framework-id",
+			"name": "marathon",
+			"tasks": [{"id": "a.1", "name": "a"}, {"id": "b.1", "name": "b"}],
+			"completed_tasks": null
+		}],
+		"completed_frameworks": []
+	}`
+	state, err := decodeState(strings.NewReader(body), func(t Task) bool { return t.Name == "b" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := State{
+		ID:     "master-id",
+		Slaves: []Slave{{ID: "agent-id", Pid: "slave(1)@10.0.0.1:5051"}},
+		Frameworks: []Framework{{
+			ID:    "framework-id",
+			Name:  "marathon",
+			Tasks: []Task{{ID: "b.1", Name: "b"}},
+		}},
+	}
+	if !reflect.DeepEqual(state, expected) {
+		t.Fatalf("expect state %+v. Got %+v", expected, state)
+	}
+
+	for _, invalid := range []string{``, `[]`, `{"slaves": {}}`, `{"id": "x"`} {
+		if _, err := decodeState(strings.NewReader(invalid), nil); err == nil {
+			t.Fatalf("expect an error decoding %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package nodeutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/dcos/dcos-go/exec"
+)
+
+const (
+	defaultSystemctlPath = "systemctl"
+
+	// dcosUnitsPattern matches the systemd units of DC/OS components.
+	dcosUnitsPattern = "dcos-*"
+)
+
+// Unit is the state of a systemd unit.
+type Unit struct {
+	Name        string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	Description string
+}
+
+// UnitLister lists the DC/OS systemd units of a node. The NodeInfo returned
+// by NewNodeInfo implements it.
+type UnitLister interface {
+	SystemdUnits(ctx context.Context) ([]Unit, error)
+}
+
+// ensure that dcosInfo implements UnitLister.
+var _ UnitLister = &dcosInfo{}
+
+// SystemdUnits returns the state of the dcos-* systemd units on the node. It
+// uses systemctl, see OptionSystemctl.
+func (d *dcosInfo) SystemdUnits(ctx context.Context) ([]Unit, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// only stdout is parsed, systemctl prints warnings to stderr.
+	spec := exec.Spec{
+		Command: d.systemctlPath,
+		Args:    []string{"list-units", "--all", "--plain", "--no-legend", "--no-pager", dcosUnitsPattern},
+	}
+	attempt, err := exec.RunUntilSuccess(ctx, spec, exec.BackoffPolicy{MaxAttempts: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseUnits(attempt.Stdout), nil
+}
+
+// parseUnits parses the output of systemctl list-units --plain --no-legend,
+// with a unit per line: UNIT LOAD ACTIVE SUB DESCRIPTION.
+func parseUnits(buf []byte) (units []Unit) {
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		// older versions of systemd mark failed units with a bullet.
+		fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "●"))
+		if len(fields) < 4 {
+			continue
+		}
+
+		units = append(units, Unit{
+			Name:        fields[0],
+			LoadState:   fields[1],
+			ActiveState: fields[2],
+			SubState:    fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+	return units
+}
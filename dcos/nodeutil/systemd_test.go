@@ -0,0 +1,64 @@
+package nodeutil
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+func TestSystemdUnits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("systemd is not available on windows")
+	}
+
+	dir, err := ioutil.TempDir("", "nodeutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	systemctl := filepath.Join(dir, "systemctl")
+	script := `#!/bin/bash
+echo "dcos-mesos-master.service   loaded active running Mesos Master: distributed systems kernel"
+echo "Warning: The unit file, source configuration file or drop-ins of dcos-metronome.service changed on disk." >&2
+echo "● dcos-metronome.service    loaded failed failed  Jobs Service: DC/OS Metronome"
+echo "dcos-gen-resolvconf.timer   loaded active waiting Generate resolv.conf: Periodically update systemd-resolved"
+`
+	if err := ioutil.WriteFile(systemctl, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionSystemctl(systemctl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	units, err := d.(UnitLister).SystemdUnits(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Unit{
+		{"dcos-mesos-master.service", "loaded", "active", "running", "Mesos Master: distributed systems kernel"},
+		{"dcos-metronome.service", "loaded", "failed", "failed", "Jobs Service: DC/OS Metronome"},
+		{"dcos-gen-resolvconf.timer", "loaded", "active", "waiting", "Generate resolv.conf: Periodically update systemd-resolved"},
+	}
+	if !reflect.DeepEqual(units, expected) {
+		t.Fatalf("expect units %+v. Got %+v", expected, units)
+	}
+
+	d, err = NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionSystemctl("/bin/false"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.(UnitLister).SystemdUnits(context.TODO()); err == nil {
+		t.Fatal("expect an error if systemctl fails")
+	}
+}
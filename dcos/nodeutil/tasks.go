@@ -0,0 +1,62 @@
+package nodeutil
+
+import "context"
+
+// Filter selects the tasks returned by ListTasks. Empty fields match all tasks.
+type Filter struct {
+	// Framework matches the ID or the name of the framework running the task.
+	Framework string
+
+	// State matches the mesos task state, e.g. TASK_RUNNING.
+	State string
+
+	// AgentID matches the ID of the agent running the task.
+	AgentID string
+}
+
+// matchTask returns whether the task matches the filter. The framework is
+// matched by matchFramework, since its name is not known to the task.
+func (f Filter) matchTask(t Task) bool {
+	if f.State != "" && t.State != f.State {
+		return false
+	}
+	if f.AgentID != "" && t.SlaveID != f.AgentID {
+		return false
+	}
+	return true
+}
+
+// matchFramework returns whether the framework matches the filter.
+func (f Filter) matchFramework(fw Framework) bool {
+	return f.Framework == "" || fw.ID == f.Framework || fw.Name == f.Framework
+}
+
+// TaskLister lists the tasks known to mesos. The NodeInfo returned by
+// NewNodeInfo implements it.
+type TaskLister interface {
+	ListTasks(ctx context.Context, filter Filter) ([]Task, error)
+}
+
+// ensure that dcosInfo implements TaskLister.
+var _ TaskLister = &dcosInfo{}
+
+// ListTasks returns the active and completed tasks of all frameworks known to
+// mesos that match the filter.
+func (d *dcosInfo) ListTasks(ctx context.Context, filter Filter) ([]Task, error) {
+	state, err := d.state(ctx, filter.matchTask)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []Task
+	for _, frameworks := range [][]Framework{state.Frameworks, state.CompletedFrameworks} {
+		for _, f := range frameworks {
+			if !filter.matchFramework(f) {
+				continue
+			}
+			tasks = append(tasks, f.Tasks...)
+			tasks = append(tasks, f.CompletedTasks...)
+		}
+	}
+	return tasks, nil
+}
@@ -0,0 +1,61 @@
+package nodeutil
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+func TestListTasks(t *testing.T) {
+	state, err := ioutil.ReadFile("fixture/state.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, string(state))
+	}))
+	defer ts.Close()
+
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionMesosStateURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		filter   Filter
+		expected int
+	}{
+		{Filter{}, 8},
+		{Filter{Framework: "marathon"}, 7},
+		{Filter{Framework: "db10f9b1-5b82-4187-aa47-4fbcefc7cdca-0000", State: "TASK_RUNNING"}, 7},
+		{Filter{State: "TASK_KILLED"}, 1},
+		{Filter{AgentID: "93397246-d2c3-4e56-9848-4573c8e778bb-S9"}, 1},
+		{Filter{Framework: "metronome"}, 0},
+	} {
+		tasks, err := d.(TaskLister).ListTasks(context.TODO(), tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != tc.expected {
+			t.Fatalf("expect %d tasks for filter %+v. Got %d", tc.expected, tc.filter, len(tasks))
+		}
+	}
+
+	tasks, err := d.(TaskLister).ListTasks(context.TODO(), Filter{Framework: "cassandra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := tasks[0]
+	if task.Resources.Mem != 4096 || task.Resources.Ports != "[7000-7001, 7199-7199, 9042-9042, 9160-9160]" {
+		t.Fatalf("unexpected task resources %+v", task.Resources)
+	}
+	if len(task.Labels) != 6 || task.Labels[0] != (Label{Key: "goal_state", Value: "RUNNING"}) {
+		t.Fatalf("unexpected task labels %+v", task.Labels)
+	}
+}
@@ -3,7 +3,6 @@ package nodeutil
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -22,8 +21,9 @@ import (
 )
 
 const (
-	defaultExecTimeout       = 10 * time.Second
-	defaultClusterIDLocation = "/var/lib/dcos/cluster-id"
+	defaultExecTimeout        = 10 * time.Second
+	defaultClusterIDLocation  = "/var/lib/dcos/cluster-id"
+	defaultLeaderPollInterval = 5 * time.Second
 )
 
 // ErrTaskNotFound is return if the canonical ID for a given task not found.
@@ -81,6 +81,9 @@ type dcosInfo struct {
 	cachedMesosID   string
 	cachedClusterID string
 
+	cachedFaultDomain *FaultDomain
+	cachedDCOSVersion *DCOSVersion
+
 	// caller parameters
 	client            *http.Client
 	detectIPLocation  string
@@ -89,6 +92,19 @@ type dcosInfo struct {
 	mesosStateURL     string
 	dnsRecordLeader   string
 	clusterIDLocation string
+	leaderStrategies  []LeaderStrategy
+	stateSummaryURL   string
+	zkConn            ZKConn
+	mesosZKPath       string
+	leaderPoll        time.Duration
+
+	detectFaultDomainLocation string
+	mesosFlagsURL             string
+	cloudMetadataURL          string
+	agentStateURL             string
+	retryPolicy               RetryPolicy
+	systemctlPath             string
+	dcosVersionLocation       string
 }
 
 func getDefaultShellPath() string {
@@ -131,6 +147,13 @@ func NewNodeInfo(client *http.Client, role string, options ...Option) (NodeInfo,
 		dnsRecordLeader:   dcos.DNSRecordLeader,
 		mesosStateURL:     defaultStateURL.String(),
 		clusterIDLocation: defaultClusterIDLocation,
+		leaderStrategies:  []LeaderStrategy{LeaderStrategyDNS},
+		mesosZKPath:       defaultMesosZKPath,
+		leaderPoll:        defaultLeaderPollInterval,
+
+		detectFaultDomainLocation: dcos.GetFileDetectFaultDomainLocation(),
+		systemctlPath:             defaultSystemctlPath,
+		dcosVersionLocation:       defaultDCOSVersionLocation,
 	}
 
 	// update parameters with a caller input.
@@ -157,23 +180,9 @@ func (d *dcosInfo) DetectIP() (net.IP, error) {
 		return *d.cachedIP, nil
 	}
 
-	if _, err := os.Stat(d.detectIPLocation); err != nil {
-		return nil, err
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), d.detectIPTimeout)
 	defer cancel()
-	ce, err := exec.Run(ctx, getDefaultShellPath(), []string{d.detectIPLocation})
-	if err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(ce)
-	if err != nil {
-		return nil, err
-	}
-
-	err = <-ce.Done
+	buf, err := runScript(ctx, d.detectIPLocation)
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +206,31 @@ func (d *dcosInfo) DetectIP() (net.IP, error) {
 	return validIP, nil
 }
 
-// IsLeader checks if the node is leader.
+// runScript runs the script at path with the default shell and returns its
+// output.
+func runScript(ctx context.Context, path string) ([]byte, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	ce, err := exec.Run(ctx, getDefaultShellPath(), []string{path})
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := ioutil.ReadAll(ce)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := <-ce.Done; err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// IsLeader checks if the node is leader. The leader strategies configured by
+// OptionLeaderStrategies are tried in order until one of them succeeds.
 func (d *dcosInfo) IsLeader() (bool, error) {
 	// find role and IP before locking the structure.
 	localIP, err := d.DetectIP()
@@ -217,24 +250,16 @@ func (d *dcosInfo) IsLeader() (bool, error) {
 		return false, nil
 	}
 
-	addrs, err := net.LookupIP(d.dnsRecordLeader)
+	isLeader, err := d.leadership(localIP, true)
 	if err != nil {
 		return false, err
 	}
 
-	for _, addr := range addrs {
-		if localIP.Equal(addr) {
-			isLeader := true
-
-			if d.cache {
-				d.cachedIsLeader = &isLeader
-			}
-
-			return isLeader, nil
-		}
+	if d.cache && isLeader {
+		d.cachedIsLeader = &isLeader
 	}
 
-	return false, ErrNodeInfo{fmt.Sprintf("Error getting mesos leader. Number of ip addresses %d", len(addrs))}
+	return isLeader, nil
 }
 
 // MesosID returns a mesosID for leading master and agents.
@@ -263,7 +288,8 @@ func (d *dcosInfo) MesosID(ctx context.Context) (string, error) {
 		return "", err
 	}
 
-	state, err := d.state(ctx)
+	// tasks are not needed to find the mesos ID.
+	state, err := d.state(ctx, func(Task) bool { return false })
 	if err != nil {
 		return "", err
 	}
@@ -355,33 +381,41 @@ func (d *dcosInfo) ClusterID() (string, error) {
 	return clusterID, nil
 }
 
-func (d *dcosInfo) state(ctx context.Context) (state State, err error) {
-	req, err := http.NewRequest("GET", d.mesosStateURL, nil)
-	if err != nil {
-		return state, err
-	}
+// state retrieves the mesos state, keeping only the tasks for which keepTask
+// returns true, or all tasks if keepTask is nil.
+func (d *dcosInfo) state(ctx context.Context, keepTask func(Task) bool) (state State, err error) {
+	err = d.retry(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequest("GET", d.mesosStateURL, nil)
+		if err != nil {
+			return err
+		}
 
-	if ctx != nil {
 		if header, ok := HeaderFromContext(ctx); ok {
 			req.Header = header
 		}
-		req = req.WithContext(ctx)
-	}
 
-	resp, err := d.client.Do(req)
-	if err != nil {
-		return state, err
-	}
-	defer resp.Body.Close()
+		resp, err := d.client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return state, ErrNodeInfo{fmt.Sprintf("GET request to %s returned response code %d", d.mesosStateURL, resp.StatusCode)}
-	}
+		if resp.StatusCode != http.StatusOK {
+			return ErrNodeInfo{fmt.Sprintf("GET request to %s returned response code %d", d.mesosStateURL, resp.StatusCode)}
+		}
 
-	err = json.NewDecoder(resp.Body).Decode(&state)
+		state, err = decodeState(resp.Body, keepTask)
+		return err
+	})
 	return state, err
 }
 
+// taskMatches returns whether the task has the given name or its ID contains
+// it.
+func taskMatches(t Task, name string) bool {
+	return t.Name == name || strings.Contains(t.ID, name)
+}
+
 func findTask(name string, completed bool, frameworks []Framework) (foundTasks []Task) {
 	for _, framework := range frameworks {
 		currentTasks := framework.Tasks
@@ -390,7 +424,7 @@ func findTask(name string, completed bool, frameworks []Framework) (foundTasks [
 		}
 
 		for _, t := range currentTasks {
-			if t.Name != name && !strings.Contains(t.ID, name) {
+			if !taskMatches(t, name) {
 				continue
 			}
 			foundTasks = append(foundTasks, t)
@@ -401,7 +435,7 @@ func findTask(name string, completed bool, frameworks []Framework) (foundTasks [
 
 // TaskCanonicalID return a CanonicalTaskID for a given task.
 func (d *dcosInfo) TaskCanonicalID(ctx context.Context, task string, completed bool) (*CanonicalTaskID, error) {
-	state, err := d.state(ctx)
+	state, err := d.state(ctx, func(t Task) bool { return taskMatches(t, task) })
 	if err != nil {
 		return nil, err
 	}
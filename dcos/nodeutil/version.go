@@ -0,0 +1,157 @@
+package nodeutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+const defaultDCOSVersionLocation = "/opt/mesosphere/etc/dcos-version.json"
+
+// Variant is the variant of DC/OS running on a cluster.
+type Variant string
+
+// DC/OS variants.
+const (
+	VariantOpen       Variant = "open"
+	VariantEnterprise Variant = "enterprise"
+)
+
+// Version is a DC/OS version, e.g. 1.12.0 or 1.13.0-beta1.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+
+	// Pre is the pre-release, e.g. beta1 or dev. Empty for releases.
+	Pre string
+}
+
+// ParseVersion parses a DC/OS version. The patch is optional, e.g. 1.12-dev.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	numbers := s
+	if i := strings.Index(s, "-"); i >= 0 {
+		numbers, v.Pre = s[:i], s[i+1:]
+	}
+
+	parts := strings.Split(numbers, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, ErrNodeInfo{fmt.Sprintf("Invalid DC/OS version %s", s)}
+	}
+
+	dst := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, ErrNodeInfo{fmt.Sprintf("Invalid DC/OS version %s", s)}
+		}
+		*dst[i] = n
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or greater than o.
+// A pre-release is lower than the release, e.g. 1.13.0-beta1 < 1.13.0.
+func (v Version) Compare(o Version) int {
+	for _, c := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if c[0] < c[1] {
+			return -1
+		}
+		if c[0] > c[1] {
+			return 1
+		}
+	}
+
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	}
+	return strings.Compare(v.Pre, o.Pre)
+}
+
+// AtLeast returns whether v is greater than or equal to o.
+func (v Version) AtLeast(o Version) bool {
+	return v.Compare(o) >= 0
+}
+
+// DCOSVersion is the version of DC/OS running on a node.
+type DCOSVersion struct {
+	Version     Version
+	Variant     Variant
+	ImageCommit string
+	BootstrapID string
+}
+
+// VersionDetector detects the DC/OS version installed on a node. The NodeInfo
+// returned by NewNodeInfo implements it.
+type VersionDetector interface {
+	DCOSVersion(ctx context.Context) (*DCOSVersion, error)
+}
+
+// ensure that dcosInfo implements VersionDetector.
+var _ VersionDetector = &dcosInfo{}
+
+// DCOSVersion returns the version of DC/OS installed on the node, read from
+// the dcos-version.json file, see OptionDCOSVersionFile. Clusters that do not
+// report a variant are open DC/OS.
+func (d *dcosInfo) DCOSVersion(ctx context.Context) (*DCOSVersion, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.cache && d.cachedDCOSVersion != nil {
+		v := *d.cachedDCOSVersion
+		return &v, nil
+	}
+
+	body, err := ioutil.ReadFile(d.dcosVersionLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Version     string `json:"version"`
+		Variant     string `json:"dcos-variant"`
+		ImageCommit string `json:"dcos-image-commit"`
+		BootstrapID string `json:"bootstrap-id"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, err
+	}
+
+	version, err := ParseVersion(info.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &DCOSVersion{
+		Version:     version,
+		Variant:     VariantOpen,
+		ImageCommit: info.ImageCommit,
+		BootstrapID: info.BootstrapID,
+	}
+	if info.Variant != "" {
+		v.Variant = Variant(info.Variant)
+	}
+
+	if d.cache {
+		cached := *v
+		d.cachedDCOSVersion = &cached
+	}
+	return v, nil
+}
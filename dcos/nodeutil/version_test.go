@@ -0,0 +1,85 @@
+package nodeutil
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+func TestDCOSVersion(t *testing.T) {
+	for path, expected := range map[string]DCOSVersion{
+		"fixture/version/open.json": {
+			Version:     Version{Major: 1, Minor: 12},
+			Variant:     VariantOpen,
+			ImageCommit: "6b5f5e5e3b1b9b8c4f1e3c0a4b2f6d2b4a1d3c5e",
+			BootstrapID: "2bb1d3bf0b0f9c0a2c8d5f1c4d1e6e0ef8b3f7a1",
+		},
+		"fixture/version/enterprise.json": {
+			Version: Version{Major: 1, Minor: 13, Pre: "beta1"},
+			Variant: VariantEnterprise,
+		},
+	} {
+		d, err := NewNodeInfo(&http.Client{}, dcos.RoleAgent, OptionDCOSVersionFile(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := d.(VersionDetector).DCOSVersion(context.TODO())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *v != expected {
+			t.Fatalf("expect version %+v for %s. Got %+v", expected, path, *v)
+		}
+	}
+
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleAgent, OptionDCOSVersionFile("fixture/version/missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.(VersionDetector).DCOSVersion(context.TODO()); err == nil {
+		t.Fatal("expect an error reading a missing file")
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.12.0", "1.12", 0},
+		{"1.12.1", "1.12.0", 1},
+		{"1.9.0", "1.10.0", -1},
+		{"2.0.0", "1.13.5", 1},
+		{"1.13.0-beta1", "1.13.0", -1},
+		{"1.13.0-beta2", "1.13.0-beta1", 1},
+		{"1.12-dev", "1.12-dev", 0},
+	} {
+		a, err := ParseVersion(tc.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseVersion(tc.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := a.Compare(b); c != tc.expected {
+			t.Fatalf("expect %s compared to %s to be %d. Got %d", tc.a, tc.b, tc.expected, c)
+		}
+		if a.AtLeast(b) != (tc.expected >= 0) {
+			t.Fatalf("expect %s at least %s to be %t", tc.a, tc.b, tc.expected >= 0)
+		}
+	}
+
+	for _, invalid := range []string{"", "1", "1.x.0", "1.2.3.4", "-1.2"} {
+		if _, err := ParseVersion(invalid); err == nil {
+			t.Fatalf("expect an error parsing %q", invalid)
+		}
+	}
+
+	if s := (Version{Major: 1, Minor: 13, Pre: "beta1"}).String(); s != "1.13.0-beta1" {
+		t.Fatalf("expect version 1.13.0-beta1. Got %s", s)
+	}
+}
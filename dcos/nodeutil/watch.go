@@ -0,0 +1,84 @@
+package nodeutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+// LeadershipEvent is sent by WatchLeadership when the leadership of the node
+// changes.
+type LeadershipEvent struct {
+	// IsLeader is true if the node became the leading master, and false if
+	// it lost leadership.
+	IsLeader bool
+
+	// Err is set if the leadership could not be detected. IsLeader is false
+	// in that case, and the next successful detection sends a new event.
+	Err error
+}
+
+// LeadershipWatcher follows the leadership of a master node. The NodeInfo
+// returned by NewNodeInfo implements it.
+type LeadershipWatcher interface {
+	WatchLeadership(ctx context.Context) <-chan LeadershipEvent
+}
+
+// ensure that dcosInfo implements LeadershipWatcher.
+var _ LeadershipWatcher = &dcosInfo{}
+
+// WatchLeadership polls the leadership of the node at the interval set by
+// OptionLeaderPollInterval, and sends an event on the returned channel with
+// the initial leadership and whenever it changes. Unlike IsLeader, the result
+// is never cached. The channel is closed when ctx is done.
+func (d *dcosInfo) WatchLeadership(ctx context.Context) <-chan LeadershipEvent {
+	events := make(chan LeadershipEvent)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(d.leaderPoll)
+		defer ticker.Stop()
+
+		var last *LeadershipEvent
+		for {
+			var event LeadershipEvent
+			event.IsLeader, event.Err = d.detectLeadership()
+
+			// errors are always sent, leadership only when it changes.
+			if last == nil || event.Err != nil || last.Err != nil || last.IsLeader != event.IsLeader {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				last = &event
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// detectLeadership returns whether the node is the leading master, without
+// using the cache. Unlike IsLeader, a leader DNS record resolving to another
+// node means that the node does not lead, so that losing leadership is not
+// reported as an error.
+func (d *dcosInfo) detectLeadership() (bool, error) {
+	localIP, err := d.DetectIP()
+	if err != nil {
+		return false, err
+	}
+
+	// agent cannot be leader
+	if d.role != dcos.RoleMaster {
+		return false, nil
+	}
+
+	return d.leadership(localIP, false)
+}
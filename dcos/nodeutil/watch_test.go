@@ -0,0 +1,111 @@
+package nodeutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-go/dcos"
+)
+
+func TestWatchLeadership(t *testing.T) {
+	// the node leads from the second to the third request, and the fourth
+	// fails.
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			fmt.Fprint(w, `{"leader": "master@10.10.0.2:5050"}`)
+		case 2, 3:
+			fmt.Fprint(w, `{"leader": "master@10.10.0.1:5050"}`)
+		case 4:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			fmt.Fprint(w, `{"leader": "master@10.10.0.2:5050"}`)
+		}
+	}))
+	defer ts.Close()
+
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectIP(getFixture("detect_ip_good")),
+		OptionLeaderStrategies(LeaderStrategyMesos), OptionStateSummaryURL(ts.URL),
+		OptionLeaderPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := d.(LeadershipWatcher).WatchLeadership(ctx)
+
+	for i, expected := range []struct {
+		isLeader bool
+		err      bool
+	}{
+		{false, false},
+		{true, false},
+		{false, true},
+		{false, false},
+	} {
+		select {
+		case event := <-events:
+			if event.IsLeader != expected.isLeader || (event.Err != nil) != expected.err {
+				t.Fatalf("event %d: expect leader %t and error %t. Got %+v", i, expected.isLeader, expected.err, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d: timed out", i)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchLeadershipAgent(t *testing.T) {
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleAgent, OptionDetectIP(getFixture("detect_ip_good")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := d.(LeadershipWatcher).WatchLeadership(ctx)
+	if event := <-events; event.IsLeader || event.Err != nil {
+		t.Fatalf("expect agent not to be the leader. Got %+v", event)
+	}
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("expect the channel to be closed")
+	}
+
+	if _, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionLeaderPollInterval(0)); err != ErrEmptyParam {
+		t.Fatalf("expect error %s. Got %v", ErrEmptyParam, err)
+	}
+}
+
+func TestWatchLeadershipDNS(t *testing.T) {
+	// the default strategy, the leader record resolves to another node.
+	d, err := NewNodeInfo(&http.Client{}, dcos.RoleMaster, OptionDetectIP(getFixture("detect_ip_good")),
+		OptionLeaderDNSRecord("localhost"), OptionLeaderPollInterval(10*time.Millisecond), OptionNoCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	select {
+	case event := <-d.(LeadershipWatcher).WatchLeadership(ctx):
+		if event.IsLeader || event.Err != nil {
+			t.Fatalf("expect the node not to be the leader, without error. Got %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+
+	// IsLeader keeps reporting an error, the record may not be up to date.
+	if _, err := d.IsLeader(); err == nil {
+		t.Fatal("expect IsLeader to return an error")
+	}
+}
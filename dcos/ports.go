@@ -2,6 +2,9 @@ package dcos
 
 // DC/OS ports.
 const (
+	// PortAdminrouterAgentHTTP defines a TCP port for Adminrouter on agent / public agent nodes.
+	PortAdminrouterAgentHTTP = 61001
+
 	// PortAdminrouterHTTP defines a TCP port for Adminrouter.
 	PortAdminrouterHTTP = 80
 
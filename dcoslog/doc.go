@@ -0,0 +1,17 @@
+// Package dcoslog defines the small logging interface used by the dcos-go
+// packages.
+//
+// Library code in this repository never writes log output on its own. Instead
+// packages such as zkstore, exec and dcos/http/transport accept a Logger via
+// their options and default to a no-op implementation, leaving the choice of
+// log library and destination to the consumer.
+//
+// The Logger interface is deliberately shaped after the leveled, printf-style
+// methods of github.com/sirupsen/logrus, so *logrus.Logger and *logrus.Entry
+// may be passed wherever a Logger is expected without an adapter:
+//
+//	store, err := zkstore.NewStore(connector, zkstore.OptLogger(logrus.StandardLogger()))
+//
+// Consumers using the standard library log package can wrap their *log.Logger
+// with NewStdLogger.
+package dcoslog
@@ -0,0 +1,75 @@
+package dcoslog
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger is the interface through which dcos-go packages emit log messages.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Nop returns a Logger that discards all messages. It is the default Logger
+// of every dcos-go package that accepts one.
+func Nop() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+// NewStdLogger adapts a standard library *log.Logger into a Logger. Every
+// message is prefixed with its level. Debug messages are dropped unless debug
+// is true. A nil *log.Logger writes to the standard logger of the log package.
+func NewStdLogger(l *log.Logger, debug bool) Logger {
+	return &stdLogger{logger: l, debug: debug}
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	debug  bool
+}
+
+func (s *stdLogger) output(level, format string, args ...interface{}) {
+	msg := level + " " + fmt.Sprintf(format, args...)
+	if s.logger == nil {
+		log.Output(3, msg)
+		return
+	}
+	s.logger.Output(3, msg)
+}
+
+func (s *stdLogger) Debugf(format string, args ...interface{}) {
+	if s.debug {
+		s.output("[DEBUG]", format, args...)
+	}
+}
+
+func (s *stdLogger) Infof(format string, args ...interface{}) {
+	s.output("[INFO]", format, args...)
+}
+
+func (s *stdLogger) Warnf(format string, args ...interface{}) {
+	s.output("[WARN]", format, args...)
+}
+
+func (s *stdLogger) Errorf(format string, args ...interface{}) {
+	s.output("[ERROR]", format, args...)
+}
+
+// OrNop returns l, or a no-op Logger if l is nil. Packages use it to guard
+// against consumers explicitly configuring a nil Logger.
+func OrNop(l Logger) Logger {
+	if l == nil {
+		return Nop()
+	}
+	return l
+}
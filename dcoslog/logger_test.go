@@ -0,0 +1,48 @@
+package dcoslog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestNop(t *testing.T) {
+	l := Nop()
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 1)
+	l.Warnf("warn %d", 1)
+	l.Errorf("error %d", 1)
+}
+
+func TestOrNop(t *testing.T) {
+	if _, ok := OrNop(nil).(nopLogger); !ok {
+		t.Fatal("expect nil logger to be replaced with a no-op logger")
+	}
+	l := NewStdLogger(nil, false)
+	if OrNop(l) != l {
+		t.Fatal("expect non-nil logger to be returned as is")
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := NewStdLogger(log.New(buf, "", 0), false)
+
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 2)
+	l.Warnf("warn %d", 3)
+	l.Errorf("error %d", 4)
+
+	expected := "[INFO] info 2\n[WARN] warn 3\n[ERROR] error 4\n"
+	if buf.String() != expected {
+		t.Fatalf("expect output %q. Got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	l = NewStdLogger(log.New(buf, "", 0), true)
+	l.Debugf("debug %d", 1)
+	if !strings.Contains(buf.String(), "[DEBUG] debug 1") {
+		t.Fatalf("expect debug output. Got %q", buf.String())
+	}
+}
@@ -0,0 +1,65 @@
+package exec
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchResult is the outcome of one of the commands run by RunAll.
+type BatchResult struct {
+	Spec Spec
+
+	// Attempt is the execution of the command, nil if it was not run because
+	// its options were invalid or the context was done before its turn.
+	Attempt *Attempt
+
+	// Err is nil if the command exited with code 0, the error of the attempt
+	// if it ran, and the reason it was not run otherwise.
+	Err error
+}
+
+// RunAll runs all commands, at most parallelism at a time, and returns their
+// results in the order of specs once they all exited. A parallelism of 0 or
+// less runs all commands at once. Commands that have not been started by the
+// time ctx is done are not run.
+func RunAll(ctx context.Context, specs []Spec, parallelism int) []BatchResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if parallelism <= 0 || parallelism > len(specs) {
+		parallelism = len(specs)
+	}
+	results := make([]BatchResult, len(specs))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		results[i].Spec = spec
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			// both cases may be ready at once.
+			<-sem
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(result *BatchResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			attempt, err := runAttempt(ctx, result.Spec)
+			if err != nil {
+				result.Err = err
+				return
+			}
+			result.Attempt, result.Err = attempt, attempt.Err
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,54 @@
+package exec
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRunAll(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses bash")
+	}
+	var specs []Spec
+	for i := 0; i < 6; i++ {
+		specs = append(specs, Spec{Command: "/bin/bash", Args: []string{"-c", "sleep 0.2; echo " + string(rune('a'+i))}})
+	}
+	specs = append(specs, Spec{Command: "/bin/bash", Args: []string{"-c", "exit 2"}})
+	specs = append(specs, Spec{Command: "/bin/bash", Options: []Option{WithMaxLineLength(-1)}})
+
+	start := time.Now()
+	results := RunAll(context.Background(), specs, 3)
+	elapsed := time.Since(start)
+
+	if len(results) != len(specs) {
+		t.Fatalf("expect %d results. Got %d", len(specs), len(results))
+	}
+	for i := 0; i < 6; i++ {
+		if results[i].Err != nil || string(results[i].Attempt.Stdout) != string(rune('a'+i))+"\n" {
+			t.Fatalf("expect command %d to succeed in order. Got %+v", i, results[i])
+		}
+	}
+	if results[6].Err == nil || results[6].Attempt.ExitCode != 2 {
+		t.Fatalf("expect exit code 2. Got %+v", results[6])
+	}
+	if results[7].Err == nil || results[7].Attempt != nil {
+		t.Fatalf("expect invalid options to be rejected. Got %+v", results[7])
+	}
+	// 6 commands of 200ms, 3 at a time.
+	if elapsed < 400*time.Millisecond || elapsed > 5*time.Second {
+		t.Fatalf("expect the commands to run 3 at a time. Took %s", elapsed)
+	}
+}
+
+func TestRunAllCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	specs := []Spec{{Command: getEchoCommand(), Args: []string{getEchoCommandParameters()}}}
+	results := RunAll(ctx, specs, 1)
+	if results[0].Err != context.Canceled || results[0].Attempt != nil {
+		t.Fatalf("expect the command to not run. Got %+v", results[0])
+	}
+}
@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package exec
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// setCredential fails, as running commands as a different user is not
+// supported on windows.
+func setCredential(cmd *exec.Cmd, uid, gid uint32) error {
+	return errors.New("running commands as a different user is not supported on windows")
+}
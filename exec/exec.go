@@ -3,12 +3,18 @@ package exec
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"os/user"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/dcos/dcos-go/dcoslog"
 )
 
 // dcos-go/exec is a os/exec wrapper. It implements io.Reader and can be used to read both STDOUT and STDERR.
@@ -35,11 +41,194 @@ import (
 //  <exit status N> where N is non 0 exit status.
 //  <context deadline exceeded> means timeout was reached and command was killed.
 //  <context canceled>  means that command was canceled by a user.
+// Wait can be used instead of, or in addition to, Done to retrieve a detailed Result.
 type CommandExecutor struct {
 	Done chan error
 
-	done chan error
-	pipe *io.PipeReader
+	done   chan error
+	pipe   *io.PipeReader
+	logger dcoslog.Logger
+
+	processGroup    bool          // whether to run the command in its own process group
+	killGracePeriod time.Duration // time between SIGTERM and SIGKILL, 0 to kill right away
+
+	env  []string // the environment of the command, nil to inherit ours
+	dir  string   // the working directory of the command, empty for ours
+	user *execUser
+
+	maxLineLength int // see WithMaxLineLength
+
+	pty        bool        // whether to run the command in a pseudo-terminal
+	windowSize *windowSize // the window size of the pty, if set
+
+	err     error         // the error sent on Done
+	decided chan struct{} // closed once err is set
+	result  *Result       // set once the command exited
+	exited  chan struct{} // closed once result is set
+}
+
+// Option is a functional option that configures how Run executes a command.
+type Option func(*CommandExecutor) error
+
+// WithLogger sets the logger which receives diagnostic messages about the command
+// execution. By default nothing is logged.
+func WithLogger(logger dcoslog.Logger) Option {
+	return func(c *CommandExecutor) error {
+		c.logger = dcoslog.OrNop(logger)
+		return nil
+	}
+}
+
+// Wait blocks until the command has exited and returns the result of its
+// execution. If the command was cancelled or timed out, Wait returns once the
+// killed command has exited.
+func (c *CommandExecutor) Wait() *Result {
+	<-c.exited
+	<-c.decided
+	result := *c.result
+	result.Err = c.err
+	return &result
+}
+
+// execUser is the user a command runs as.
+type execUser struct {
+	uid, gid uint32
+}
+
+// WithEnv sets the environment of the command, in the form "key=value". By
+// default the command inherits the environment of the current process.
+func WithEnv(env []string) Option {
+	return func(c *CommandExecutor) error {
+		for _, kv := range env {
+			if i := strings.Index(kv, "="); i <= 0 {
+				return fmt.Errorf("invalid environment variable %q, expected key=value", kv)
+			}
+		}
+		c.env = env
+		return nil
+	}
+}
+
+// WithDir sets the working directory of the command, which must exist. By
+// default the command runs in the working directory of the current process.
+func WithDir(dir string) Option {
+	return func(c *CommandExecutor) error {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("invalid working directory: %s", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid working directory: %s is not a directory", dir)
+		}
+		c.dir = dir
+		return nil
+	}
+}
+
+// WithUser runs the command as the given user, identified by name or uid, and
+// their primary group. This usually requires the current process to run as
+// root. Not supported on windows.
+func WithUser(name string) Option {
+	return func(c *CommandExecutor) error {
+		u, err := user.Lookup(name)
+		if err != nil {
+			var idErr error
+			if u, idErr = user.LookupId(name); idErr != nil {
+				return fmt.Errorf("invalid user: %s", err)
+			}
+		}
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid user %s: unsupported uid %s", name, u.Uid)
+		}
+		gid, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid user %s: unsupported gid %s", name, u.Gid)
+		}
+		c.user = &execUser{uid: uint32(uid), gid: uint32(gid)}
+		return nil
+	}
+}
+
+// windowSize is the size of a terminal in characters.
+type windowSize struct {
+	rows, cols uint16
+}
+
+// WithPTY runs the command in a pseudo-terminal, for commands that behave
+// differently or refuse to run without one. Both stdout and stderr are written
+// to the terminal, so the output cannot be told apart: RunLines reports all
+// of it as Stdout. Only supported on linux.
+func WithPTY() Option {
+	return func(c *CommandExecutor) error {
+		c.pty = true
+		return nil
+	}
+}
+
+// WithWindowSize sets the window size of the pseudo-terminal configured by
+// WithPTY, which is 0x0 by default.
+func WithWindowSize(rows, cols uint16) Option {
+	return func(c *CommandExecutor) error {
+		if rows == 0 || cols == 0 {
+			return fmt.Errorf("invalid window size %dx%d", rows, cols)
+		}
+		c.windowSize = &windowSize{rows: rows, cols: cols}
+		return nil
+	}
+}
+
+// WithProcessGroup runs the command in its own process group, so that all of
+// its descendants are killed along with it when the context is done. Otherwise
+// only the command itself is killed and its children are left running.
+// On windows the process tree is killed with taskkill.
+func WithProcessGroup() Option {
+	return func(c *CommandExecutor) error {
+		c.processGroup = true
+		return nil
+	}
+}
+
+// WithKillGracePeriod sends SIGTERM to the command when the context is done,
+// and only sends SIGKILL if it is still running after the given grace period.
+// By default the command is sent SIGKILL right away. On windows the command is
+// always killed right away.
+func WithKillGracePeriod(d time.Duration) Option {
+	return func(c *CommandExecutor) error {
+		if d < 0 {
+			return fmt.Errorf("invalid kill grace period %s", d)
+		}
+		c.killGracePeriod = d
+		return nil
+	}
+}
+
+// stop stops the command once ctx is done, unless it exited before.
+func (c *CommandExecutor) stop(ctx context.Context, cmd *exec.Cmd, exited <-chan struct{}) {
+	select {
+	case <-exited:
+		return
+	case <-ctx.Done():
+	}
+	if c.killGracePeriod == 0 {
+		if err := kill(cmd, c.processGroup); err != nil {
+			c.logger.Debugf("exec: could not kill %s: %s", cmd.Path, err)
+		}
+		return
+	}
+	if err := terminate(cmd, c.processGroup); err != nil {
+		c.logger.Debugf("exec: could not terminate %s: %s", cmd.Path, err)
+	}
+	timer := time.NewTimer(c.killGracePeriod)
+	defer timer.Stop()
+	select {
+	case <-exited:
+	case <-timer.C:
+		c.logger.Debugf("exec: %s did not exit within %s, killing it", cmd.Path, c.killGracePeriod)
+		if err := kill(cmd, c.processGroup); err != nil {
+			c.logger.Debugf("exec: could not kill %s: %s", cmd.Path, err)
+		}
+	}
 }
 
 // Read implements the io.Reader.
@@ -50,7 +239,27 @@ func (c *CommandExecutor) Read(p []byte) (int, error) {
 
 // Run spawns the given command and returns a handle to the running process in the form
 // of a CommandExecutor.
-func Run(ctx context.Context, command string, arg []string) (*CommandExecutor, error) {
+func Run(ctx context.Context, command string, arg []string, options ...Option) (*CommandExecutor, error) {
+	// Create a new PIPE.
+	// stdout and stderr will be both redirected to this pipe. When the command is executed / cancelled or timeout
+	// reached the pipe will be closed, unblocking the reader.
+	r, w := io.Pipe()
+	commandExecutor, err := start(ctx, command, arg, options, func(*CommandExecutor) (io.Writer, io.Writer, func()) {
+		return w, w, func() { w.Close() }
+	})
+	if err != nil {
+		return nil, err
+	}
+	commandExecutor.pipe = r
+	return commandExecutor, nil
+}
+
+// outputFunc returns the writers receiving the stdout and stderr of the
+// command, and a function called once the command exited.
+type outputFunc func(*CommandExecutor) (stdout, stderr io.Writer, closeOutput func())
+
+// start spawns the given command, writing its output as configured by output.
+func start(ctx context.Context, command string, arg []string, options []Option, output outputFunc) (*CommandExecutor, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -63,32 +272,115 @@ func Run(ctx context.Context, command string, arg []string) (*CommandExecutor, e
 		}
 	}
 	// by default Cancel is spineless unless someone configures an option to enable it
-	commandExecutor := &CommandExecutor{Done: make(chan error, 1), done: make(chan error, 1)}
+	commandExecutor := &CommandExecutor{
+		Done:    make(chan error, 1),
+		done:    make(chan error, 1),
+		logger:  dcoslog.Nop(),
+		decided: make(chan struct{}),
+		exited:  make(chan struct{}),
 
-	cmd := exec.CommandContext(ctx, command, arg...)
+		maxLineLength: DefaultMaxLineLength,
+	}
+	for _, opt := range options {
+		if opt != nil {
+			if err := opt(commandExecutor); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if commandExecutor.windowSize != nil && !commandExecutor.pty {
+		return nil, fmt.Errorf("a window size requires a pty")
+	}
+	logger := commandExecutor.logger
+
+	// the command is stopped by CommandExecutor.stop rather than
+	// exec.CommandContext, which only kills the command itself.
+	cmd := exec.Command(command, arg...)
+	cmd.Env = commandExecutor.env
+	cmd.Dir = commandExecutor.dir
+	if commandExecutor.processGroup && !commandExecutor.pty {
+		// with a pty, the command leads a new session and thereby a new
+		// process group anyway.
+		setProcessGroup(cmd)
+	}
+	if u := commandExecutor.user; u != nil {
+		if err := setCredential(cmd, u.uid, u.gid); err != nil {
+			return nil, err
+		}
+	}
+	var master, slave *os.File
+	if commandExecutor.pty {
+		var err error
+		if master, slave, err = openPTY(); err != nil {
+			return nil, fmt.Errorf("could not allocate a pty: %s", err)
+		}
+		if ws := commandExecutor.windowSize; ws != nil {
+			if err := setWindowSize(master, ws.rows, ws.cols); err != nil {
+				master.Close()
+				slave.Close()
+				return nil, fmt.Errorf("could not set the window size: %s", err)
+			}
+		}
+		setControllingTerminal(cmd)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	}
 	go func() {
 		var err error
-		defer func() { commandExecutor.Done <- err }()
+		defer func() {
+			commandExecutor.err = err
+			close(commandExecutor.decided)
+			commandExecutor.Done <- err
+		}()
 
 		select {
 		case <-ctx.Done():
 			err = ctx.Err()
 		case err = <-commandExecutor.done:
 		}
+		logger.Debugf("exec: %s %v finished: %v", command, arg, err)
 	}()
 
-	// Create a new PIPE.
-	// stdout and stderr will be both redirected to this pipe. When the command is executed / cancelled or timeout
-	// reached the pipe will be closed, unblocking the reader.
-	r, w := io.Pipe()
-	cmd.Stdout = w
-	cmd.Stderr = w
-	commandExecutor.pipe = r
+	stdout, stderr, closeOutput := output(commandExecutor)
+	if master == nil {
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+	}
 
 	// execute the command in the goroutine.
+	logger.Debugf("exec: running %s %v", command, arg)
 	go func() {
-		defer w.Close()
-		commandExecutor.done <- cmd.Run()
+		start := time.Now()
+		err := cmd.Start()
+		var copied chan struct{}
+		if master != nil {
+			// the command holds its own copy of the slave end, and reading the
+			// master fails once all copies are closed.
+			slave.Close()
+			if err == nil {
+				copied = make(chan struct{})
+				go func() {
+					defer close(copied)
+					if _, err := io.Copy(stdout, master); err != nil && !isPTYClosed(err) {
+						logger.Debugf("exec: could not read the pty of %s: %s", command, err)
+					}
+				}()
+			}
+		}
+		if err == nil {
+			exited := make(chan struct{})
+			go commandExecutor.stop(ctx, cmd, exited)
+			err = cmd.Wait()
+			close(exited)
+		}
+		if master != nil {
+			if copied != nil {
+				<-copied
+			}
+			master.Close()
+		}
+		closeOutput()
+		commandExecutor.result = newResult(cmd, start, time.Now())
+		close(commandExecutor.exited)
+		commandExecutor.done <- err
 	}()
 
 	return commandExecutor, nil
@@ -5,11 +5,19 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/dcos/dcos-go/dcoslog"
 )
 
 func getDefaultShellPath() string {
@@ -77,6 +85,26 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRunWithLogger(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	buf := new(bytes.Buffer)
+	ce, err := Run(ctx, getLsCmd(), getLsParams(), WithLogger(dcoslog.NewStdLogger(log.New(buf, "", 0), true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	io.Copy(ioutil.Discard, ce)
+	if err = <-ce.Done; err != nil {
+		t.Fatalf("Return should be nil. Got %s", err)
+	}
+
+	if !strings.Contains(buf.String(), "exec: running "+getLsCmd()) {
+		t.Fatalf("Expecting command start to be logged. Got: %s", buf.String())
+	}
+}
+
 func TestRunTimeout(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
@@ -224,3 +252,248 @@ func TestReturnCode(t *testing.T) {
 		t.Fatalf("expect return code 10. Got %d", code)
 	}
 }
+
+func TestWait(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	ce, err := Run(ctx, getDefaultShellPath(), []string{getFixture("return-err")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, ce)
+
+	result := ce.Wait()
+	if result.ExitCode != 10 {
+		t.Fatalf("expect exit code 10. Got %d", result.ExitCode)
+	}
+	if result.Err == nil || result.Err != <-ce.Done {
+		t.Fatalf("expect the error sent on Done. Got %v", result.Err)
+	}
+	if result.Signal != nil {
+		t.Fatalf("expect no signal. Got %s", result.Signal)
+	}
+	if result.Start.IsZero() || result.End.Before(result.Start) || result.Duration != result.End.Sub(result.Start) {
+		t.Fatalf("expect consistent start, end and duration. Got %+v", result)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ce, err := Run(ctx, getDefaultShellPath(), []string{getFixture("infinite")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, ce)
+
+	result := ce.Wait()
+	if result.Err != context.DeadlineExceeded {
+		t.Fatalf("expect %s. Got %v", context.DeadlineExceeded, result.Err)
+	}
+	if result.ExitCode != -1 {
+		t.Fatalf("expect exit code -1. Got %d", result.ExitCode)
+	}
+	if runtime.GOOS != "windows" && result.Signal == nil {
+		t.Fatal("expect the command to be killed by a signal")
+	}
+}
+
+func TestWaitNotFound(t *testing.T) {
+	ce, err := Run(context.Background(), "command_no_found", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, ce)
+
+	if result := ce.Wait(); result.ExitCode != -1 || result.Err == nil {
+		t.Fatalf("expect exit code -1 and an error. Got %+v", result)
+	}
+}
+
+func TestRunWithProcessGroup(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("checks process state in /proc")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ce, err := Run(ctx, "/bin/bash", []string{"-c", "sleep 1000 & echo $!; wait"}, WithProcessGroup())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(ce).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid := strings.TrimSpace(line)
+	cancel()
+	io.Copy(ioutil.Discard, ce)
+	if err := <-ce.Done; err != context.Canceled {
+		t.Fatalf("Expected %s. Got %s", context.Canceled, err)
+	}
+
+	// the grandchild is either gone or a zombie waiting to be reaped.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stat, err := ioutil.ReadFile("/proc/" + pid + "/stat")
+		if err != nil || strings.Contains(string(stat), ") Z ") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expect the grandchild %s to be killed. Got %s", pid, stat)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunWithKillGracePeriod(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows has no SIGTERM")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	script := `trap "echo terminated; exit 3" TERM; echo ready; while true; do sleep 0.1; done`
+	ce, err := Run(ctx, "/bin/bash", []string{"-c", script}, WithKillGracePeriod(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(ce)
+	if _, err := reader.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	output, _ := ioutil.ReadAll(reader)
+	if !strings.Contains(string(output), "terminated") {
+		t.Fatalf("expect the command to handle SIGTERM. Got %s", output)
+	}
+	if result := ce.Wait(); result.ExitCode != 3 {
+		t.Fatalf("expect exit code 3. Got %d", result.ExitCode)
+	}
+
+	if _, err := Run(ctx, "/bin/bash", nil, WithKillGracePeriod(-time.Second)); err == nil {
+		t.Fatal("expect a negative grace period to be rejected")
+	}
+}
+
+func TestRunWithKillGracePeriodEscalation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows has no SIGTERM")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	script := `trap "" TERM; echo ready; while true; do sleep 0.1; done`
+	ce, err := Run(ctx, "/bin/bash", []string{"-c", script}, WithKillGracePeriod(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(ce)
+	if _, err := reader.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	io.Copy(ioutil.Discard, reader)
+	if result := ce.Wait(); result.Signal != syscall.SIGKILL {
+		t.Fatalf("expect the command to be killed. Got %v", result.Signal)
+	}
+}
+
+func TestRunWithEnvAndDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses bash")
+	}
+	dir, err := ioutil.TempDir("", "exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ce, err := Run(context.Background(), "/bin/bash", []string{"-c", "echo $FOO; pwd"}, WithEnv([]string{"FOO=bar"}), WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, _ := ioutil.ReadAll(ce)
+	if err := <-ce.Done; err != nil {
+		t.Fatal(err)
+	}
+	realDir, _ := filepath.EvalSymlinks(dir)
+	if expected := "bar\n" + realDir + "\n"; string(output) != expected {
+		t.Fatalf("expect output %q. Got %q", expected, output)
+	}
+
+	if _, err := Run(context.Background(), "/bin/bash", nil, WithEnv([]string{"FOO"})); err == nil {
+		t.Fatal("expect an invalid environment to be rejected")
+	}
+	if _, err := Run(context.Background(), "/bin/bash", nil, WithDir(filepath.Join(dir, "missing"))); err == nil {
+		t.Fatal("expect a missing directory to be rejected")
+	}
+}
+
+func TestRunWithUser(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("requires the nobody user")
+	}
+
+	ce, err := Run(context.Background(), "id", []string{"-u"}, WithUser("nobody"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, _ := ioutil.ReadAll(ce)
+	if err := <-ce.Done; err != nil {
+		t.Fatal(err)
+	}
+	if uid := strings.TrimSpace(string(output)); uid != nobody.Uid {
+		t.Fatalf("expect uid %s. Got %s", nobody.Uid, uid)
+	}
+
+	if _, err := Run(context.Background(), "id", nil, WithUser("no-such-user")); err == nil {
+		t.Fatal("expect an unknown user to be rejected")
+	}
+}
+
+func TestRunWithPTY(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pseudo-terminals are only supported on linux")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ce, err := Run(ctx, "/bin/bash", []string{"-c", "[ -t 0 ] && [ -t 1 ] && [ -t 2 ] && echo tty; stty size"}, WithPTY(), WithWindowSize(24, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, _ := ioutil.ReadAll(ce)
+	if err := <-ce.Done; err != nil {
+		t.Fatal(err)
+	}
+	if expected := "tty\r\n24 80\r\n"; string(output) != expected {
+		t.Fatalf("expect output %q. Got %q", expected, output)
+	}
+
+	if _, err := Run(ctx, "/bin/bash", nil, WithWindowSize(24, 80)); err == nil {
+		t.Fatal("expect a window size without a pty to be rejected")
+	}
+}
+
+func TestRunWithPTYCancel(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pseudo-terminals are only supported on linux")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ce, err := Run(ctx, "/bin/bash", []string{"-c", "echo ready; sleep 1000"}, WithPTY(), WithProcessGroup())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(ce).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	io.Copy(ioutil.Discard, ce)
+	if result := ce.Wait(); result.Err != context.Canceled || result.Signal != syscall.SIGKILL {
+		t.Fatalf("expect the command to be killed. Got %+v", result)
+	}
+}
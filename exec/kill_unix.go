@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package exec
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes the command the leader of a new process group, so
+// that it can be signalled along with all of its descendants.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// terminate asks the command, or its whole process group, to exit.
+func terminate(cmd *exec.Cmd, group bool) error {
+	return signal(cmd, group, syscall.SIGTERM)
+}
+
+// kill forcefully stops the command, or its whole process group.
+func kill(cmd *exec.Cmd, group bool) error {
+	return signal(cmd, group, syscall.SIGKILL)
+}
+
+func signal(cmd *exec.Cmd, group bool, sig syscall.Signal) error {
+	pid := cmd.Process.Pid
+	if group {
+		// a negative pid signals the process group.
+		pid = -pid
+	}
+	return syscall.Kill(pid, sig)
+}
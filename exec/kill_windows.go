@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package exec
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// terminate stops the command right away, as windows has no equivalent of
+// SIGTERM for console processes.
+func terminate(cmd *exec.Cmd, group bool) error {
+	return kill(cmd, group)
+}
+
+// kill forcefully stops the command, or its whole process tree.
+func kill(cmd *exec.Cmd, group bool) error {
+	if group {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+	return cmd.Process.Kill()
+}
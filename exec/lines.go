@@ -0,0 +1,133 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultMaxLineLength is the maximum length of a Line passed to the callback
+// of RunLines, unless configured otherwise with WithMaxLineLength.
+const DefaultMaxLineLength = 64 * 1024
+
+// Stream identifies the output stream of a command.
+type Stream int
+
+const (
+	// Stdout is the standard output of a command.
+	Stdout Stream = iota + 1
+
+	// Stderr is the standard error of a command.
+	Stderr
+)
+
+func (s Stream) String() string {
+	switch s {
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// Line is a line of output of a command, see RunLines.
+type Line struct {
+	// Stream is the stream the line was written to.
+	Stream Stream
+
+	// Time is when the line was read, which is when its last byte was written
+	// by the command.
+	Time time.Time
+
+	// Text is the line without its trailing newline.
+	Text string
+}
+
+// WithMaxLineLength sets the maximum length of a Line passed to the callback
+// of RunLines. Longer lines are split. It has no effect on Run.
+func WithMaxLineLength(n int) Option {
+	return func(c *CommandExecutor) error {
+		if n <= 0 {
+			return fmt.Errorf("invalid max line length %d", n)
+		}
+		c.maxLineLength = n
+		return nil
+	}
+}
+
+// RunLines runs the given command and calls fn with every line it writes to
+// stdout or stderr, until it exits. Calls to fn are serialized, and the
+// command is blocked from writing while fn runs. The returned error is the
+// one that Run sends on CommandExecutor.Done.
+func RunLines(ctx context.Context, command string, arg []string, fn func(Line), options ...Option) error {
+	var mu sync.Mutex
+	emit := func(line Line) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn(line)
+	}
+	ce, err := start(ctx, command, arg, options, func(c *CommandExecutor) (io.Writer, io.Writer, func()) {
+		stdout := &lineWriter{stream: Stdout, max: c.maxLineLength, emit: emit}
+		stderr := &lineWriter{stream: Stderr, max: c.maxLineLength, emit: emit}
+		return stdout, stderr, func() {
+			stdout.flush()
+			stderr.flush()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// the output is flushed before Wait returns, unlike Done which is sent
+	// right away when ctx is done.
+	return ce.Wait().Err
+}
+
+// lineWriter splits the output written to it into lines.
+type lineWriter struct {
+	stream Stream
+	max    int
+	emit   func(Line)
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			break
+		}
+		w.buf = append(w.buf, p[:i]...)
+		w.emitLine(bytes.TrimSuffix(w.buf, []byte{'\r'}))
+		w.buf = w.buf[:0]
+		p = p[i+1:]
+	}
+	w.buf = append(w.buf, p...)
+	for len(w.buf) > w.max {
+		w.emit(Line{Stream: w.stream, Time: time.Now(), Text: string(w.buf[:w.max])})
+		w.buf = w.buf[w.max:]
+	}
+	return n, nil
+}
+
+// emitLine emits the text, split into lines of at most max bytes.
+func (w *lineWriter) emitLine(text []byte) {
+	now := time.Now()
+	for len(text) > w.max {
+		w.emit(Line{Stream: w.stream, Time: now, Text: string(text[:w.max])})
+		text = text[w.max:]
+	}
+	w.emit(Line{Stream: w.stream, Time: now, Text: string(text)})
+}
+
+// flush emits the last line, if it did not end with a newline.
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emitLine(w.buf)
+		w.buf = nil
+	}
+}
@@ -0,0 +1,66 @@
+package exec
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{stream: Stdout, max: 4, emit: func(line Line) {
+		if line.Stream != Stdout || line.Time.IsZero() {
+			t.Fatalf("expect a timestamped stdout line. Got %+v", line)
+		}
+		lines = append(lines, line.Text)
+	}}
+
+	for _, p := range []string{"ab", "c\n\nabcd\r\nabcdefghij", "k\nxy"} {
+		w.Write([]byte(p))
+	}
+	w.flush()
+
+	expected := []string{"abc", "", "abcd", "abcd", "efgh", "ijk", "xy"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("expect lines %q. Got %q", expected, lines)
+	}
+}
+
+func TestRunLines(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses bash")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var lines []Line
+	err := RunLines(ctx, "/bin/bash", []string{"-c", "echo out; echo err >&2; echo -n 0123456789"}, func(line Line) {
+		lines = append(lines, line)
+	}, WithMaxLineLength(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byStream := map[Stream][]string{}
+	for _, line := range lines {
+		byStream[line.Stream] = append(byStream[line.Stream], line.Text)
+	}
+	expected := map[Stream][]string{
+		Stdout: {"out", "01234567", "89"},
+		Stderr: {"err"},
+	}
+	if !reflect.DeepEqual(byStream, expected) {
+		t.Fatalf("expect lines %q. Got %q", expected, byStream)
+	}
+
+	err = RunLines(ctx, "/bin/bash", []string{"-c", "exit 3"}, func(Line) {})
+	if err == nil || err.Error() != "exit status 3" {
+		t.Fatalf("expect exit status 3. Got %v", err)
+	}
+
+	if err := RunLines(ctx, "/bin/bash", nil, func(Line) {}, WithMaxLineLength(0)); err == nil {
+		t.Fatal("expect an invalid max line length to be rejected")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package exec
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+func openPTY() (master, slave *os.File, err error) {
+	return nil, nil, fmt.Errorf("pseudo-terminals are not supported on %s", runtime.GOOS)
+}
+
+func setWindowSize(pty *os.File, rows, cols uint16) error {
+	return fmt.Errorf("pseudo-terminals are not supported on %s", runtime.GOOS)
+}
+
+func setControllingTerminal(cmd *exec.Cmd) {}
+
+func isPTYClosed(err error) bool {
+	return false
+}
@@ -0,0 +1,62 @@
+package exec
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Result describes a finished command execution, see CommandExecutor.Wait.
+type Result struct {
+	// ExitCode is the exit code of the command, or -1 if the command could not
+	// be started or was terminated by a signal.
+	ExitCode int
+
+	// Signal is the signal that terminated the command, if any.
+	Signal os.Signal
+
+	// Start and End are the times the command was started and exited at.
+	Start time.Time
+	End   time.Time
+
+	// Duration is the wall clock time the command ran for.
+	Duration time.Duration
+
+	// UserTime and SystemTime are the CPU time used by the command.
+	UserTime   time.Duration
+	SystemTime time.Duration
+
+	// MaxRSS is the maximum resident set size of the command in bytes, or 0
+	// if the platform does not report it.
+	MaxRSS int64
+
+	// Err is the same error sent on CommandExecutor.Done.
+	Err error
+}
+
+// newResult returns the result of the command, which has exited.
+func newResult(cmd *exec.Cmd, start, end time.Time) *Result {
+	result := &Result{
+		ExitCode: -1,
+		Start:    start,
+		End:      end,
+		Duration: end.Sub(start),
+	}
+	state := cmd.ProcessState
+	if state == nil {
+		// the command could not be started.
+		return result
+	}
+	// ProcessState.ExitCode requires Go 1.12, the wait status is used instead.
+	if status, ok := state.Sys().(syscall.WaitStatus); ok {
+		result.ExitCode = status.ExitStatus()
+		if status.Signaled() {
+			result.Signal = status.Signal()
+		}
+	}
+	result.UserTime = state.UserTime()
+	result.SystemTime = state.SystemTime()
+	result.MaxRSS = maxRSS(state)
+	return result
+}
@@ -0,0 +1,148 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Spec describes a command to run, see RunUntilSuccess and Check.
+type Spec struct {
+	// Command and Args are the command to run and its arguments.
+	Command string
+	Args    []string
+
+	// Timeout limits the duration of every attempt to run the command. Zero
+	// means no limit.
+	Timeout time.Duration
+
+	// Options configure how the command is run.
+	Options []Option
+}
+
+// Attempt is the outcome of running a command once.
+type Attempt struct {
+	*Result
+
+	// Stdout and Stderr are the output of the command.
+	Stdout []byte
+	Stderr []byte
+}
+
+// BackoffPolicy configures how RunUntilSuccess retries a failing command.
+// The backoff doubles after every failed attempt, starting at InitialBackoff,
+// up to MaxBackoff.
+type BackoffPolicy struct {
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the backoff. Zero means no cap, the backoff keeps
+	// doubling until MaxAttempts or the context of RunUntilSuccess is done.
+	MaxBackoff time.Duration
+
+	// MaxAttempts is the number of attempts after which RunUntilSuccess gives
+	// up. Zero means that it retries until its context is done.
+	MaxAttempts int
+}
+
+// DefaultBackoffPolicy is a reasonable policy for health checks.
+var DefaultBackoffPolicy = BackoffPolicy{
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	MaxAttempts:    5,
+}
+
+// backoff returns how long to wait after the given number of failed attempts.
+func (p BackoffPolicy) backoff(attempts int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempts && d > 0 && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// AttemptsError is returned by RunUntilSuccess and Check if the command did
+// not succeed. It holds all attempts to run it.
+type AttemptsError struct {
+	Command  string
+	Attempts []*Attempt
+}
+
+func (e *AttemptsError) Error() string {
+	attempts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		attempts[i] = fmt.Sprintf("attempt %d: %s", i+1, a.Err)
+	}
+	return fmt.Sprintf("%s failed after %d attempt(s): %s", e.Command, len(e.Attempts), strings.Join(attempts, "; "))
+}
+
+// Last returns the last attempt.
+func (e *AttemptsError) Last() *Attempt {
+	return e.Attempts[len(e.Attempts)-1]
+}
+
+// RunUntilSuccess runs the command until it exits with code 0, waiting
+// between attempts as configured by the policy. It gives up once the policy
+// allows no further attempts or ctx is done, in which case it returns an
+// *AttemptsError. Otherwise it returns the successful attempt.
+func RunUntilSuccess(ctx context.Context, spec Spec, policy BackoffPolicy) (*Attempt, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attemptsErr := &AttemptsError{Command: spec.Command}
+	for {
+		attempt, err := runAttempt(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		if attempt.Err == nil {
+			return attempt, nil
+		}
+		attemptsErr.Attempts = append(attemptsErr.Attempts, attempt)
+		n := len(attemptsErr.Attempts)
+		if policy.MaxAttempts > 0 && n >= policy.MaxAttempts {
+			return nil, attemptsErr
+		}
+		timer := time.NewTimer(policy.backoff(n))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attemptsErr
+		}
+	}
+}
+
+// Check runs the command once, and returns an *AttemptsError if it did not
+// exit with code 0.
+func Check(spec Spec) (*Attempt, error) {
+	return RunUntilSuccess(context.Background(), spec, BackoffPolicy{MaxAttempts: 1})
+}
+
+// runAttempt runs the command once. It only returns an error if the command
+// could not be configured.
+func runAttempt(ctx context.Context, spec Spec) (*Attempt, error) {
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	var stdout, stderr bytes.Buffer
+	ce, err := start(ctx, spec.Command, spec.Args, spec.Options, func(*CommandExecutor) (io.Writer, io.Writer, func()) {
+		return &stdout, &stderr, func() {}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Attempt{Result: ce.Wait(), Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}, nil
+}
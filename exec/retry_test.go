@@ -0,0 +1,113 @@
+package exec
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoffPolicy(t *testing.T) {
+	p := BackoffPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempts, expected := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		3:  4 * time.Second,
+		4:  5 * time.Second,
+		50: 5 * time.Second,
+	} {
+		if d := p.backoff(attempts); d != expected {
+			t.Fatalf("expect a backoff of %s after %d attempts. Got %s", expected, attempts, d)
+		}
+	}
+
+	// zero MaxBackoff does not cap the backoff.
+	p = BackoffPolicy{InitialBackoff: time.Second}
+	for attempts, expected := range map[int]time.Duration{
+		1:    time.Second,
+		4:    8 * time.Second,
+		1000: math.MaxInt64,
+	} {
+		if d := p.backoff(attempts); d != expected {
+			t.Fatalf("expect an uncapped backoff of %s after %d attempts. Got %s", expected, attempts, d)
+		}
+	}
+}
+
+func TestRunUntilSuccess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses bash")
+	}
+	dir, err := ioutil.TempDir("", "exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// succeeds on the third attempt.
+	counter := filepath.Join(dir, "counter")
+	script := `echo -n x >> ` + counter + `; n=$(wc -c < ` + counter + `); echo "attempt $n"; [ $n -ge 3 ]`
+	policy := BackoffPolicy{InitialBackoff: time.Millisecond, MaxAttempts: 5}
+	attempt, err := RunUntilSuccess(context.Background(), Spec{Command: "/bin/bash", Args: []string{"-c", script}}, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(attempt.Stdout) != "attempt 3\n" || attempt.ExitCode != 0 {
+		t.Fatalf("expect the third attempt to succeed. Got %+v", attempt)
+	}
+
+	policy.MaxAttempts = 2
+	_, err = RunUntilSuccess(context.Background(), Spec{Command: "/bin/bash", Args: []string{"-c", "echo failed >&2; exit 1"}}, policy)
+	attemptsErr, ok := err.(*AttemptsError)
+	if !ok {
+		t.Fatalf("expect an *AttemptsError. Got %v", err)
+	}
+	if len(attemptsErr.Attempts) != 2 || string(attemptsErr.Last().Stderr) != "failed\n" {
+		t.Fatalf("expect 2 failed attempts. Got %+v", attemptsErr.Attempts)
+	}
+	if !strings.Contains(err.Error(), "attempt 2: exit status 1") {
+		t.Fatalf("expect the attempts to be listed. Got %s", err)
+	}
+}
+
+func TestRunUntilSuccessTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	spec := Spec{
+		Command: getDefaultShellPath(),
+		Args:    []string{getFixture("infinite")},
+		Timeout: 100 * time.Millisecond,
+	}
+	_, err := RunUntilSuccess(ctx, spec, BackoffPolicy{InitialBackoff: 10 * time.Millisecond})
+	attemptsErr, ok := err.(*AttemptsError)
+	if !ok {
+		t.Fatalf("expect an *AttemptsError. Got %v", err)
+	}
+	if attemptsErr.Last().Err != context.DeadlineExceeded {
+		t.Fatalf("expect the attempts to time out. Got %v", attemptsErr.Last().Err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	attempt, err := Check(Spec{Command: getEchoCommand(), Args: []string{getEchoCommandParameters()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(attempt.Stdout) != "hello\n" {
+		t.Fatalf("expect output hello. Got %s", attempt.Stdout)
+	}
+
+	_, err = Check(Spec{Command: getDefaultShellPath(), Args: []string{getFixture("return-err")}})
+	if attemptsErr, ok := err.(*AttemptsError); !ok || attemptsErr.Last().ExitCode != 10 {
+		t.Fatalf("expect exit code 10. Got %v", err)
+	}
+
+	if _, err := Check(Spec{Command: "ls", Options: []Option{WithKillGracePeriod(-1)}}); err == nil {
+		t.Fatal("expect invalid options to be rejected")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package exec
+
+import "os"
+
+// maxRSS returns 0, as the maximum resident set size is not reported on
+// windows.
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -1,27 +1,53 @@
 module github.com/dcos/dcos-go
 
+go 1.27.1
+
+require (
+	github.com/docker/docker v0.7.3-0.20181129155816-baab736a3649
+	github.com/docker/go-connections v0.3.0
+	github.com/fortytw2/leaktest v1.2.0
+	github.com/pkg/errors v0.0.0-20171216070316-e881fd58d78e
+	github.com/samuel/go-zookeeper v0.0.0-20171117190445-471cd4e61d7a
+	github.com/stretchr/testify v1.2.2
+	golang.org/x/net v0.0.0-20180826012351-8a410e7b638d
+	gopkg.in/square/go-jose.v2 v2.1.3
+)
+
 require (
+	cloud.google.com/go v0.26.0 // indirect
 	github.com/Azure/go-ansiterm v0.0.0-20170929234023-d6e3b3328b78 // indirect
 	github.com/Microsoft/go-winio v0.4.7 // indirect
 	github.com/Nvveen/Gotty v0.0.0-20120604004816-cd527374f1e5 // indirect
+	github.com/client9/misspell v0.3.4 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/docker/distribution v2.7.0-rc.0.0.20181129231500-d9e12182359e+incompatible // indirect
-	github.com/docker/docker v0.7.3-0.20181129155816-baab736a3649
-	github.com/docker/go-connections v0.3.0
 	github.com/docker/go-units v0.3.3 // indirect
-	github.com/fortytw2/leaktest v1.2.0
 	github.com/gogo/protobuf v1.1.1 // indirect
+	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b // indirect
+	github.com/golang/lint v0.0.0-20180702182130-06c8688daad7 // indirect
+	github.com/golang/mock v1.1.1 // indirect
+	github.com/golang/protobuf v1.2.0 // indirect
 	github.com/google/go-cmp v0.2.0 // indirect
 	github.com/gorilla/context v1.1.1 // indirect
 	github.com/gorilla/mux v1.6.2 // indirect
+	github.com/kisielk/gotool v1.0.0 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
 	github.com/opencontainers/go-digest v1.0.0-rc1 // indirect
 	github.com/opencontainers/image-spec v1.0.1 // indirect
-	github.com/pkg/errors v0.0.0-20171216070316-e881fd58d78e
-	github.com/samuel/go-zookeeper v0.0.0-20171117190445-471cd4e61d7a
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/sirupsen/logrus v1.2.0 // indirect
-	github.com/stretchr/testify v1.2.2
-	golang.org/x/net v0.0.0-20180826012351-8a410e7b638d
+	github.com/stretchr/objx v0.1.1 // indirect
+	golang.org/x/crypto v0.0.0-20180904163835-0709b304e793 // indirect
+	golang.org/x/lint v0.0.0-20180702182130-06c8688daad7 // indirect
+	golang.org/x/oauth2 v0.0.0-20180821212333-d2e6202438be // indirect
+	golang.org/x/sync v0.0.0-20180314180146-1d60e4601c6f // indirect
+	golang.org/x/sys v0.0.0-20180905080454-ebe1bf3edb33 // indirect
+	golang.org/x/text v0.3.0 // indirect
 	golang.org/x/time v0.0.0-20181108054448-85acf8d2951c // indirect
+	golang.org/x/tools v0.0.0-20180828015842-6cd1fcedba52 // indirect
+	google.golang.org/appengine v1.1.0 // indirect
+	google.golang.org/genproto v0.0.0-20180817151627-c66870c02cf8 // indirect
 	google.golang.org/grpc v1.16.0 // indirect
-	gopkg.in/square/go-jose.v2 v2.1.3
 	gotest.tools v2.2.0+incompatible // indirect
+	honnef.co/go/tools v0.0.0-20180728063816-88497007e858 // indirect
 )
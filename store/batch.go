@@ -0,0 +1,153 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import "time"
+
+// AtomicStore is a Store that can update objects atomically.
+type AtomicStore interface {
+	Store
+	BatchUpdate(func(MutableView))
+	CompareAndSwap(string, interface{}, interface{}) bool
+	SetIfAbsent(string, interface{}) bool
+}
+
+// MutableView gives access to the objects of a store within BatchUpdate().
+type MutableView interface {
+	Delete(string)
+	Get(string) (interface{}, bool)
+	Set(string, interface{})
+	SetWithTTL(string, interface{}, time.Duration)
+}
+
+// CompareAndSwap sets the object to new if it currently holds old, and
+// returns whether it did. A missing or expired object never matches. The
+// values are compared with ==, which panics if old is not comparable.
+func (s *storeImpl) CompareAndSwap(key string, old, new interface{}) bool {
+	swapped := false
+	s.BatchUpdate(func(v MutableView) {
+		if cur, ok := v.Get(key); ok && cur == old {
+			v.Set(key, new)
+			swapped = true
+		}
+	})
+	return swapped
+}
+
+// SetIfAbsent creates an object in the store unless it already exists, and
+// returns whether it did. Expired objects are considered absent.
+func (s *storeImpl) SetIfAbsent(key string, val interface{}) bool {
+	set := false
+	s.BatchUpdate(func(v MutableView) {
+		if _, ok := v.Get(key); !ok {
+			v.Set(key, val)
+			set = true
+		}
+	})
+	return set
+}
+
+// BatchUpdate calls f with a view of the store while holding the write lock,
+// so that all changes f makes are atomic with regard to other goroutines. f
+// must not use the store itself, only the view, or it deadlocks.
+func (s *storeImpl) BatchUpdate(f func(MutableView)) {
+	s.mutex.Lock()
+	v := &storeView{store: s}
+	defer func() {
+		s.mutex.Unlock()
+		s.notify(v.evicted)
+	}()
+	f(v)
+}
+
+// storeView is the MutableView of a single store, whose write lock is held.
+type storeView struct {
+	store   *storeImpl
+	evicted []eviction
+}
+
+func (v *storeView) Delete(key string) {
+	v.store.deleteLocked(key)
+}
+
+func (v *storeView) Get(key string) (interface{}, bool) {
+	return v.store.getLocked(key)
+}
+
+func (v *storeView) Set(key string, val interface{}) {
+	v.SetWithTTL(key, val, v.store.ttl)
+}
+
+func (v *storeView) SetWithTTL(key string, val interface{}, ttl time.Duration) {
+	v.evicted = append(v.evicted, v.store.setLocked(key, val, ttl)...)
+}
+
+// CompareAndSwap sets the object to new if it currently holds old, see
+// storeImpl.CompareAndSwap().
+func (s *shardedStore) CompareAndSwap(key string, old, new interface{}) bool {
+	return s.shard(key).CompareAndSwap(key, old, new)
+}
+
+// SetIfAbsent creates an object in the store unless it already exists, see
+// storeImpl.SetIfAbsent().
+func (s *shardedStore) SetIfAbsent(key string, val interface{}) bool {
+	return s.shard(key).SetIfAbsent(key, val)
+}
+
+// BatchUpdate calls f with a view of the store while holding the write locks
+// of all shards, see storeImpl.BatchUpdate().
+func (s *shardedStore) BatchUpdate(f func(MutableView)) {
+	v := &shardedView{store: s, views: make(map[*storeImpl]*storeView, len(s.shards))}
+	for _, shard := range s.shards {
+		shard.mutex.Lock()
+		v.views[shard] = &storeView{store: shard}
+	}
+	defer func() {
+		for _, shard := range s.shards {
+			shard.mutex.Unlock()
+		}
+		for _, shard := range s.shards {
+			shard.notify(v.views[shard].evicted)
+		}
+	}()
+	f(v)
+}
+
+// shardedView is the MutableView of a sharded store, whose shards' write
+// locks are all held.
+type shardedView struct {
+	store *shardedStore
+	views map[*storeImpl]*storeView
+}
+
+func (v *shardedView) view(key string) *storeView {
+	return v.views[v.store.shard(key)]
+}
+
+func (v *shardedView) Delete(key string) {
+	v.view(key).Delete(key)
+}
+
+func (v *shardedView) Get(key string) (interface{}, bool) {
+	return v.view(key).Get(key)
+}
+
+func (v *shardedView) Set(key string, val interface{}) {
+	v.view(key).Set(key, val)
+}
+
+func (v *shardedView) SetWithTTL(key string, val interface{}, ttl time.Duration) {
+	v.view(key).SetWithTTL(key, val, ttl)
+}
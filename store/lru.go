@@ -0,0 +1,117 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+// EvictionFunc is called with the key and value of every object that is
+// evicted from the store, see WithEvictionCallback.
+type EvictionFunc func(key string, val interface{})
+
+// WithMaxEntries bounds the store to the given number of objects. Once the
+// store is full, setting a new object evicts the least recently used one,
+// where both getting and setting an object count as a use. Zero means that
+// the store is unbounded.
+func WithMaxEntries(n int) Option {
+	return func(s *storeImpl) {
+		s.maxEntries = n
+	}
+}
+
+// WithEvictionCallback configures a function that is called for every object
+// that is evicted from the store, either because the store is full, see
+// WithMaxEntries, or because it expired and was removed by the janitor, see
+// WithJanitor. It is not called for objects removed by Delete(), Purge() or
+// Supplant(). The function is called without holding any lock, so it may use
+// the store.
+func WithEvictionCallback(f EvictionFunc) Option {
+	return func(s *storeImpl) {
+		s.onEvict = f
+	}
+}
+
+// eviction is an object that was evicted from the store.
+type eviction struct {
+	key string
+	val interface{}
+}
+
+// bounded returns whether the store tracks the recency of its objects.
+func (s *storeImpl) bounded() bool {
+	return s.maxEntries > 0
+}
+
+// touch marks the object as the most recently used one. The caller must hold
+// the write lock.
+func (s *storeImpl) touch(key string) {
+	if !s.bounded() {
+		return
+	}
+	if s.recency == nil {
+		s.recency = list.New()
+		s.elements = make(map[string]*list.Element)
+	}
+	if e, ok := s.elements[key]; ok {
+		s.recency.MoveToFront(e)
+		return
+	}
+	s.elements[key] = s.recency.PushFront(key)
+}
+
+// untrack forgets the recency of the object. The caller must hold the write
+// lock.
+func (s *storeImpl) untrack(key string) {
+	if e, ok := s.elements[key]; ok {
+		s.recency.Remove(e)
+		delete(s.elements, key)
+	}
+}
+
+// resetRecency forgets the recency of all objects. The caller must hold the
+// write lock.
+func (s *storeImpl) resetRecency() {
+	s.recency = nil
+	s.elements = nil
+}
+
+// evictOverflow removes the least recently used objects until the store is
+// within its bounds, and returns them. The caller must hold the write lock.
+func (s *storeImpl) evictOverflow() (evicted []eviction) {
+	if !s.bounded() {
+		return nil
+	}
+	for len(s.objects) > s.maxEntries && s.recency.Len() > 0 {
+		key := s.recency.Remove(s.recency.Back()).(string)
+		delete(s.elements, key)
+		evicted = append(evicted, eviction{key: key, val: s.objects[key].contents})
+		delete(s.objects, key)
+	}
+	return evicted
+}
+
+// notify counts the evicted objects and calls the eviction callback, if any,
+// for them. The caller must not hold any lock.
+func (s *storeImpl) notify(evicted []eviction) {
+	atomic.AddUint64(&s.counters.evictions, uint64(len(evicted)))
+	if s.onEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		s.onEvict(e.key, e.val)
+	}
+}
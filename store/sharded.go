@@ -0,0 +1,215 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"io"
+	"time"
+)
+
+// DefaultShards is the number of shards used by NewSharded() if the given
+// number is not positive.
+const DefaultShards = 32
+
+// shardedStore is a store that spreads its objects across several stores,
+// each with its own lock, to reduce lock contention between goroutines
+// writing to different keys.
+type shardedStore struct {
+	shards []*storeImpl
+}
+
+// NewSharded creates a new in-memory store that spreads its objects across
+// the given number of shards by the FNV-1a hash of their keys. It behaves
+// like a store created by New(), but scales better with many goroutines
+// writing concurrently. The options apply to every shard, except that the
+// bound set by WithMaxEntries is split across shards, so objects may be
+// evicted before the store as a whole is full. There are never more shards
+// than the bound.
+//
+// Operations that involve all objects, like Objects() or Supplant(), are
+// not atomic across shards.
+func NewSharded(shards int, options ...Option) Store {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	// the options are applied to a bare store to read the bound, New would
+	// start a janitor.
+	probe := &storeImpl{}
+	for _, option := range options {
+		option(probe)
+	}
+	maxEntries := probe.maxEntries
+	if maxEntries > 0 && shards > maxEntries {
+		shards = maxEntries
+	}
+	s := &shardedStore{shards: make([]*storeImpl, shards)}
+	for i := range s.shards {
+		shard := New(options...).(*storeImpl)
+		if maxEntries > 0 {
+			// the bounds of the shards add up to maxEntries.
+			shard.maxEntries = maxEntries / shards
+			if i < maxEntries%shards {
+				shard.maxEntries++
+			}
+		}
+		s.shards[i] = shard
+	}
+	return s
+}
+
+// FNV-1a parameters, see hash/fnv. The hash is computed inline to avoid
+// allocating a hash.Hash32 for every operation.
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+)
+
+// shard returns the shard holding the object with the given key.
+func (s *shardedStore) shard(key string) *storeImpl {
+	h := uint32(fnvOffset32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= fnvPrime32
+	}
+	return s.shards[h%uint32(len(s.shards))]
+}
+
+// Delete removes a single object from the store.
+func (s *shardedStore) Delete(key string) {
+	s.shard(key).Delete(key)
+}
+
+// Get returns a single key-value pair from the store based on its name.
+func (s *shardedStore) Get(key string) (interface{}, bool) {
+	return s.shard(key).Get(key)
+}
+
+// GetByRegex returns a map of key-value pairs from the store based on a regexp search.
+func (s *shardedStore) GetByRegex(expr string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for _, shard := range s.shards {
+		sm, err := shard.GetByRegex(expr)
+		if err != nil {
+			return m, err
+		}
+		for k, v := range sm {
+			m[k] = v
+		}
+	}
+	return m, nil
+}
+
+// LoadSnapshot replaces all objects in the store with those in the snapshot
+// read from r, see storeImpl.LoadSnapshot(). Snapshots are interchangeable
+// between sharded and unsharded stores.
+func (s *shardedStore) LoadSnapshot(r io.Reader) error {
+	objects, err := loadSnapshot(r)
+	if err != nil {
+		return err
+	}
+	parts := make(map[*storeImpl][]snapshotObject, len(s.shards))
+	for _, o := range objects {
+		shard := s.shard(o.Key)
+		parts[shard] = append(parts[shard], o)
+	}
+	for _, shard := range s.shards {
+		shard.loadObjects(parts[shard])
+	}
+	return nil
+}
+
+// Objects returns all objects in the store.
+func (s *shardedStore) Objects() (m map[string]interface{}) {
+	for _, shard := range s.shards {
+		for k, v := range shard.Objects() {
+			if m == nil {
+				m = make(map[string]interface{})
+			}
+			m[k] = v
+		}
+	}
+	return
+}
+
+// Purge removes ALL objects from the store.
+func (s *shardedStore) Purge() {
+	for _, shard := range s.shards {
+		shard.Purge()
+	}
+}
+
+// SaveSnapshot writes all objects in the store to w, see
+// storeImpl.SaveSnapshot().
+func (s *shardedStore) SaveSnapshot(w io.Writer) error {
+	var objects []snapshotObject
+	for _, shard := range s.shards {
+		objects = append(objects, shard.snapshotObjects()...)
+	}
+	return saveSnapshot(w, objects)
+}
+
+// Set creates an object in the store. If the object already exists, it is overwritten.
+func (s *shardedStore) Set(key string, val interface{}) {
+	s.shard(key).Set(key, val)
+}
+
+// SetWithTTL creates an object in the store that expires after the given
+// duration, see storeImpl.SetWithTTL().
+func (s *shardedStore) SetWithTTL(key string, val interface{}, ttl time.Duration) {
+	s.shard(key).SetWithTTL(key, val, ttl)
+}
+
+// Size returns the number of objects in the store as an integer.
+func (s *shardedStore) Size() (size int) {
+	for _, shard := range s.shards {
+		size += shard.Size()
+	}
+	return
+}
+
+// Stats returns statistics about the usage of the store, summed over all
+// shards.
+func (s *shardedStore) Stats() (stats Stats) {
+	for _, shard := range s.shards {
+		ss := shard.Stats()
+		stats.Hits += ss.Hits
+		stats.Misses += ss.Misses
+		stats.Evictions += ss.Evictions
+		stats.Size += ss.Size
+	}
+	return
+}
+
+// Stop stops the janitors of all shards, if any.
+func (s *shardedStore) Stop() {
+	for _, shard := range s.shards {
+		shard.Stop()
+	}
+}
+
+// Supplant replaces all objects in the store based on a given map.
+func (s *shardedStore) Supplant(m map[string]interface{}) {
+	parts := make(map[*storeImpl]map[string]interface{}, len(s.shards))
+	for k, v := range m {
+		shard := s.shard(k)
+		if parts[shard] == nil {
+			parts[shard] = make(map[string]interface{})
+		}
+		parts[shard][k] = v
+	}
+	for _, shard := range s.shards {
+		shard.Supplant(parts[shard])
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strconv"
+	"testing"
+)
+
+// Smoketest that the sharded store works at a high level
+func TestShardedStore(t *testing.T) {
+	s := NewSharded(4).(*shardedStore)
+
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), i)
+	}
+	if l := s.Size(); l != 100 {
+		t.Fatalf("Expected 100 objects in the store. Got: %d", l)
+	}
+	if oc, ok := s.Get("42"); !ok || oc != 42 {
+		t.Fatalf("Expected key '42' to contain value 42. Got: %v", oc)
+	}
+
+	m, err := s.GetByRegex("^1.$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(m); l != 10 {
+		t.Fatalf("Expected 10 objects to match. Got: %d", l)
+	}
+
+	s.Delete("42")
+	if _, ok := s.Get("42"); ok {
+		t.Fatal("Expected key '42' to have been deleted")
+	}
+	if l := len(s.Objects()); l != 99 {
+		t.Fatalf("Expected 99 objects to be returned. Got: %d", l)
+	}
+
+	s.Supplant(map[string]interface{}{"foo": "fooval", "bar": "barval"})
+	if l := s.Size(); l != 2 {
+		t.Fatalf("Expected the store to contain 2 objects. Got: %d", l)
+	}
+
+	var buf bytes.Buffer
+	if err := s.SaveSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New()
+	if err := loaded.(Snapshotter).LoadSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if oc, ok := loaded.Get("foo"); !ok || oc != "fooval" {
+		t.Fatalf("Expected key 'foo' to contain value 'fooval'. Got: %v", oc)
+	}
+
+	s.Purge()
+	if o := s.Objects(); o != nil {
+		t.Fatalf("Expected no objects in the store. Got: %v", o)
+	}
+	if stats := s.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("Expected 1 hit and 1 miss. Got: %+v", stats)
+	}
+}
+
+// The bound of a sharded store should be split across its shards.
+func TestShardedStore_WithMaxEntries(t *testing.T) {
+	s := NewSharded(4, WithMaxEntries(40))
+
+	for i := 0; i < 1000; i++ {
+		s.Set(strconv.Itoa(i), i)
+	}
+	if l := s.Size(); l != 40 {
+		t.Fatalf("Expected 40 objects in the store. Got: %d", l)
+	}
+
+	for _, tc := range []struct {
+		shards, maxEntries, expectedShards int
+	}{
+		{4, 10, 4},
+		{0, 10, 10},
+		{3, 1, 1},
+	} {
+		s := NewSharded(tc.shards, WithMaxEntries(tc.maxEntries)).(*shardedStore)
+		if len(s.shards) != tc.expectedShards {
+			t.Fatalf("Expected %d shards for a bound of %d. Got: %d", tc.expectedShards, tc.maxEntries, len(s.shards))
+		}
+		sum := 0
+		for _, shard := range s.shards {
+			sum += shard.maxEntries
+		}
+		if sum != tc.maxEntries {
+			t.Fatalf("Expected the bounds of %d shards to add up to %d. Got: %d", tc.shards, tc.maxEntries, sum)
+		}
+	}
+}
+
+// The inline hash should match FNV-1a.
+func TestShardedStore_shard(t *testing.T) {
+	s := NewSharded(7).(*shardedStore)
+	for _, key := range []string{"", "foo", "bar", "a longer key with spaces"} {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		if expected := s.shards[h.Sum32()%7]; s.shard(key) != expected {
+			t.Fatalf("Expected key '%s' to be stored in shard %d", key, h.Sum32()%7)
+		}
+	}
+}
+
+func benchmarkParallelSet(b *testing.B, s Store) {
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			s.Set(strconv.Itoa(i%1024), i)
+			i++
+		}
+	})
+}
+
+func BenchmarkStore_ParallelSet(b *testing.B) {
+	benchmarkParallelSet(b, New())
+}
+
+func BenchmarkShardedStore_ParallelSet(b *testing.B) {
+	benchmarkParallelSet(b, NewSharded(DefaultShards))
+}
+
+func benchmarkParallelMixed(b *testing.B, s Store) {
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := strconv.Itoa(i % 1024)
+			if i%4 == 0 {
+				s.Set(key, i)
+			} else {
+				s.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkStore_ParallelMixed(b *testing.B) {
+	benchmarkParallelMixed(b, New())
+}
+
+func BenchmarkShardedStore_ParallelMixed(b *testing.B) {
+	benchmarkParallelMixed(b, NewSharded(DefaultShards))
+}
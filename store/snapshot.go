@@ -0,0 +1,130 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Snapshotter saves and restores the objects of a store.
+type Snapshotter interface {
+	SaveSnapshot(io.Writer) error
+	LoadSnapshot(io.Reader) error
+}
+
+// snapshotVersion is the version of the snapshot format written by
+// SaveSnapshot().
+const snapshotVersion = 1
+
+// snapshot is the gob encoded content of a snapshot.
+type snapshot struct {
+	Version int
+	Objects []snapshotObject // least recently used first, if bounded
+}
+
+type snapshotObject struct {
+	Key      string
+	Contents interface{}
+	Expires  time.Time
+}
+
+// SaveSnapshot writes all objects in the store to w, along with their
+// expiration, so that they can be restored with LoadSnapshot(). Objects are
+// encoded with encoding/gob, hence the concrete types of all objects that
+// are not builtin types must be registered with gob.Register().
+func (s *storeImpl) SaveSnapshot(w io.Writer) error {
+	return saveSnapshot(w, s.snapshotObjects())
+}
+
+// LoadSnapshot replaces all objects in the store with those in the snapshot
+// read from r, as written by SaveSnapshot(). Objects that expired since the
+// snapshot was saved are skipped. If the store is bounded and the snapshot
+// holds more objects than fit, the least recently used ones are evicted.
+func (s *storeImpl) LoadSnapshot(r io.Reader) error {
+	objects, err := loadSnapshot(r)
+	if err != nil {
+		return err
+	}
+	s.loadObjects(objects)
+	return nil
+}
+
+func saveSnapshot(w io.Writer, objects []snapshotObject) error {
+	snap := snapshot{Version: snapshotVersion, Objects: objects}
+	if err := gob.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("could not encode snapshot: %s", err)
+	}
+	return nil
+}
+
+func loadSnapshot(r io.Reader) ([]snapshotObject, error) {
+	var snap snapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("could not decode snapshot: %s", err)
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	return snap.Objects, nil
+}
+
+// snapshotObjects returns the objects that have not expired, least recently
+// used first if the store is bounded.
+func (s *storeImpl) snapshotObjects() []snapshotObject {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	objects := make([]snapshotObject, 0, len(s.objects))
+	now := s.clock()
+	add := func(key string) {
+		if o := s.objects[key]; !o.expired(now) {
+			objects = append(objects, snapshotObject{Key: key, Contents: o.contents, Expires: o.expires})
+		}
+	}
+	if s.recency != nil {
+		for e := s.recency.Back(); e != nil; e = e.Prev() {
+			add(e.Value.(string))
+		}
+	} else {
+		for key := range s.objects {
+			add(key)
+		}
+	}
+	return objects
+}
+
+// loadObjects replaces all objects in the store with the given ones, skipping
+// those that expired.
+func (s *storeImpl) loadObjects(objects []snapshotObject) {
+	s.mutex.Lock()
+	n := make(map[string]object, len(objects))
+	s.resetRecency()
+	now := s.clock()
+	for _, so := range objects {
+		o := object{contents: so.Contents, expires: so.Expires}
+		if o.expired(now) {
+			continue
+		}
+		n[so.Key] = o
+		s.touch(so.Key)
+	}
+	s.objects = n
+	evicted := s.evictOverflow()
+	s.mutex.Unlock()
+	s.notify(evicted)
+}
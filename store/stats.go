@@ -0,0 +1,76 @@
+// Copyright 2016 Mesosphere, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import "sync/atomic"
+
+// StatsReporter reports statistics about the usage of a store.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// Stats holds statistics about the usage of a store.
+type Stats struct {
+	// Hits is the number of calls to Get() that found the object.
+	Hits uint64
+
+	// Misses is the number of calls to Get() that did not find the object,
+	// including objects that had expired.
+	Misses uint64
+
+	// Evictions is the number of objects evicted because the store was full
+	// or because they expired and were removed by the janitor.
+	Evictions uint64
+
+	// Size is the number of objects in the store.
+	Size int
+}
+
+// HitRatio returns the fraction of calls to Get() that found the object, or
+// 0 if Get() was never called.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Stats returns statistics about the usage of the store since it was created.
+func (s *storeImpl) Stats() Stats {
+	return Stats{
+		Hits:      atomic.LoadUint64(&s.counters.hits),
+		Misses:    atomic.LoadUint64(&s.counters.misses),
+		Evictions: atomic.LoadUint64(&s.counters.evictions),
+		Size:      s.Size(),
+	}
+}
+
+// counters are the usage counters of a store. They are updated atomically
+// so that they can be updated while holding the read lock only.
+type counters struct {
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// recordGet counts a call to Get().
+func (c *counters) recordGet(hit bool) {
+	if hit {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+}
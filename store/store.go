@@ -16,11 +16,17 @@
 package store
 
 import (
+	"container/list"
 	"regexp"
 	"sync"
+	"time"
 )
 
 // Store represents the interface and available methods of the dcos-go/store package.
+//
+// The stores returned by New() and NewSharded() also implement ExpiringStore,
+// AtomicStore, Snapshotter and StatsReporter, which give access to the
+// features configured by options.
 type Store interface {
 	Delete(string)
 	Get(string) (interface{}, bool)
@@ -32,12 +38,45 @@ type Store interface {
 	Supplant(map[string]interface{})
 }
 
+// ExpiringStore is a Store whose objects can expire, see WithTTL.
+type ExpiringStore interface {
+	Store
+	SetWithTTL(string, interface{}, time.Duration)
+	Stop()
+}
+
+// ensure that the stores implement all interfaces.
+var (
+	_ ExpiringStore = &storeImpl{}
+	_ AtomicStore   = &storeImpl{}
+	_ Snapshotter   = &storeImpl{}
+	_ StatsReporter = &storeImpl{}
+	_ ExpiringStore = &shardedStore{}
+	_ AtomicStore   = &shardedStore{}
+	_ Snapshotter   = &shardedStore{}
+	_ StatsReporter = &shardedStore{}
+)
+
 // storeImpl represents the structure of the store, including the store objects
 // and a single locking mechanism that is shared across a given instance, ensuring
 // some level of goroutine-safety.
 type storeImpl struct {
+	// counters must come first to be 64-bit aligned for atomic operations.
+	counters counters
+
 	objects map[string]object
 	mutex   sync.RWMutex
+
+	ttl             time.Duration    // default TTL of objects, 0 for none
+	janitorInterval time.Duration    // how often expired objects are removed
+	now             func() time.Time // the clock, time.Now if nil
+	stop            chan struct{}    // stops the janitor
+	stopOnce        sync.Once
+
+	maxEntries int                      // maximum number of objects, 0 for none
+	onEvict    EvictionFunc             // called for evicted objects, if set
+	recency    *list.List               // keys, most recently used first
+	elements   map[string]*list.Element // elements of recency by key
 }
 
 // object represents a single object in the store. Although this could be represented
@@ -45,32 +84,148 @@ type storeImpl struct {
 // additional functionality or metadata in the future.
 type object struct {
 	contents interface{}
+	expires  time.Time // zero if the object does not expire
+}
+
+// expired returns whether the object has expired at the given time.
+func (o object) expired(now time.Time) bool {
+	return !o.expires.IsZero() && !now.Before(o.expires)
+}
+
+// Option configures a store.
+type Option func(*storeImpl)
+
+// WithTTL configures the store to expire objects stored with Set() or
+// Supplant() after the given duration. Expired objects are no longer
+// returned, and are removed when they are overwritten or by the janitor, see
+// WithJanitor. A zero duration means that objects do not expire.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *storeImpl) {
+		s.ttl = ttl
+	}
+}
+
+// WithJanitor starts a goroutine that removes expired objects from the store
+// at the given interval, releasing their memory. It runs until Stop() is
+// called.
+func WithJanitor(interval time.Duration) Option {
+	return func(s *storeImpl) {
+		s.janitorInterval = interval
+	}
+}
+
+// New creates a new, basic, in-memory store. By default, the caller must
+// handle all Set() and Delete() operations by itself; that is to say, there
+// is no concept of a maximum size or expiration on stored objects. Options
+// may change this, see WithMaxEntries and WithTTL.
+func New(options ...Option) Store {
+	s := &storeImpl{
+		objects: make(map[string]object),
+		stop:    make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if s.janitorInterval > 0 {
+		go s.janitor()
+	}
+	return s
+}
+
+// janitor periodically removes expired objects until the store is stopped.
+func (s *storeImpl) janitor() {
+	ticker := time.NewTicker(s.janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.removeExpired()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// removeExpired removes all expired objects from the store.
+func (s *storeImpl) removeExpired() {
+	var evicted []eviction
+	s.mutex.Lock()
+	now := s.clock()
+	for k, v := range s.objects {
+		if v.expired(now) {
+			delete(s.objects, k)
+			s.untrack(k)
+			evicted = append(evicted, eviction{key: k, val: v.contents})
+		}
+	}
+	s.mutex.Unlock()
+	s.notify(evicted)
+}
+
+// Stop stops the janitor, if any. The store remains usable.
+func (s *storeImpl) Stop() {
+	s.stopOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+		}
+	})
 }
 
-// New creates a new, basic, in-memory store. The caller must handle
-// all Set() and Delete() operations by itself; that is to say, there is no
-// concept of a maximum size or expiration on stored objects.
-func New() Store {
-	return &storeImpl{objects: make(map[string]object)}
+func (s *storeImpl) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// newObject wraps the value in an object that expires after the given TTL.
+func (s *storeImpl) newObject(val interface{}, ttl time.Duration) object {
+	o := object{contents: val}
+	if ttl > 0 {
+		o.expires = s.clock().Add(ttl)
+	}
+	return o
 }
 
 // Delete removes a single object from the store.
 func (s *storeImpl) Delete(key string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	s.deleteLocked(key)
+}
+
+// deleteLocked removes a single object from the store. The caller must hold
+// the write lock.
+func (s *storeImpl) deleteLocked(key string) {
 	delete(s.objects, key)
+	s.untrack(key)
 }
 
 // Get returns a single key-value pair from the store based on its name.
 func (s *storeImpl) Get(key string) (interface{}, bool) {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	if s.bounded() {
+		// getting an object updates its recency.
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+	} else {
+		s.mutex.RLock()
+		defer s.mutex.RUnlock()
+	}
 
+	val, ok := s.getLocked(key)
+	s.counters.recordGet(ok)
+	return val, ok
+}
+
+// getLocked returns the contents of the object if it exists and has not
+// expired, updating its recency. The caller must hold the write lock if the
+// store is bounded, and the read lock otherwise.
+func (s *storeImpl) getLocked(key string) (interface{}, bool) {
 	object, ok := s.objects[key]
-	if !ok {
+	if !ok || object.expired(s.clock()) {
 		return nil, false
 	}
-
+	s.touch(key)
 	return object.contents, true
 }
 
@@ -80,7 +235,11 @@ func (s *storeImpl) GetByRegex(expr string) (map[string]interface{}, error) {
 	defer s.mutex.RUnlock()
 
 	m := make(map[string]interface{})
+	now := s.clock()
 	for k, v := range s.objects {
+		if v.expired(now) {
+			continue
+		}
 		matched, err := regexp.MatchString(expr, k)
 		if err != nil {
 			return m, err
@@ -103,8 +262,11 @@ func (s *storeImpl) Objects() (m map[string]interface{}) {
 	}
 
 	m = make(map[string]interface{}, sz)
+	now := s.clock()
 	for k, v := range s.objects {
-		m[k] = v.contents
+		if !v.expired(now) {
+			m[k] = v.contents
+		}
 	}
 	return
 }
@@ -114,31 +276,62 @@ func (s *storeImpl) Purge() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.objects = map[string]object{}
+	s.resetRecency()
 }
 
 // Set creates an object in the store. If the object already exists, it is overwritten.
 func (s *storeImpl) Set(key string, val interface{}) {
+	s.SetWithTTL(key, val, s.ttl)
+}
+
+// SetWithTTL creates an object in the store that expires after the given
+// duration, overriding the default TTL of the store. A zero duration means
+// that the object does not expire. If the object already exists, it is
+// overwritten.
+func (s *storeImpl) SetWithTTL(key string, val interface{}, ttl time.Duration) {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	s.objects[key] = object{contents: val}
+	evicted := s.setLocked(key, val, ttl)
+	s.mutex.Unlock()
+	s.notify(evicted)
+}
+
+// setLocked creates an object in the store and returns the objects that were
+// evicted to make room for it. The caller must hold the write lock.
+func (s *storeImpl) setLocked(key string, val interface{}, ttl time.Duration) []eviction {
+	s.objects[key] = s.newObject(val, ttl)
+	s.touch(key)
+	return s.evictOverflow()
 }
 
 // Size returns the number of objects in the store as an integer.
 func (s *storeImpl) Size() int {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	return len(s.objects)
+	size := 0
+	now := s.clock()
+	for _, v := range s.objects {
+		if !v.expired(now) {
+			size++
+		}
+	}
+	return size
 }
 
-// Supplant replaces all objects in the store based on a given map.
+// Supplant replaces all objects in the store based on a given map. If the
+// store is bounded and the map holds more objects than fit, arbitrary ones are
+// evicted.
 func (s *storeImpl) Supplant(m map[string]interface{}) {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
 
 	n := make(map[string]object, len(m))
+	s.resetRecency()
 	for k, v := range m {
-		n[k] = object{contents: v}
+		n[k] = s.newObject(v, s.ttl)
+		s.touch(k)
 	}
 
 	s.objects = n
+	evicted := s.evictOverflow()
+	s.mutex.Unlock()
+	s.notify(evicted)
 }
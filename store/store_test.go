@@ -14,7 +14,12 @@
 
 package store
 
-import "testing"
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
 
 // Smoketest that the store works at a high level
 func TestStore(t *testing.T) {
@@ -75,7 +80,7 @@ func TestStore_Delete(t *testing.T) {
 	}
 
 	if oc, ok := s.objects["foo"]; ok != false {
-		t.Fatalf("Expected 'foo' to not be found (but it was!). Got: %s", oc)
+		t.Fatalf("Expected 'foo' to not be found (but it was!). Got: %v", oc)
 	}
 }
 
@@ -301,3 +306,313 @@ func TestStore_Supplant(t *testing.T) {
 		}
 	}
 }
+
+// When objects are given a TTL, they should no longer be returned once it has
+// passed, while objects without a TTL should remain.
+func TestStore_SetWithTTL(t *testing.T) {
+	now := time.Now()
+	s := New().(*storeImpl)
+	s.now = func() time.Time { return now }
+
+	s.SetWithTTL("foo", "fooval", time.Minute)
+	s.Set("bar", "barval")
+
+	if _, ok := s.Get("foo"); !ok {
+		t.Fatal("Expected 'foo' to be found before it expired")
+	}
+
+	now = now.Add(time.Minute)
+
+	if oc, ok := s.Get("foo"); ok {
+		t.Fatalf("Expected 'foo' to have expired. Got: %v", oc)
+	}
+	if _, ok := s.Get("bar"); !ok {
+		t.Fatal("Expected 'bar' to never expire")
+	}
+	if l := s.Size(); l != 1 {
+		t.Fatalf("Expected 1 object in the store. Got: %d", l)
+	}
+	if l := len(s.Objects()); l != 1 {
+		t.Fatalf("Expected 1 object to be returned. Got: %d", l)
+	}
+	if m, err := s.GetByRegex("^foo$"); err != nil || len(m) != 0 {
+		t.Fatalf("Expected no objects to match. Got: %v, %v", m, err)
+	}
+}
+
+// When the store is created with a default TTL, it should apply to all
+// objects stored with Set() and Supplant().
+func TestStore_WithTTL(t *testing.T) {
+	now := time.Now()
+	s := New(WithTTL(time.Minute)).(*storeImpl)
+	s.now = func() time.Time { return now }
+
+	s.Set("foo", "fooval")
+	s.SetWithTTL("bar", "barval", time.Hour)
+
+	now = now.Add(time.Minute)
+
+	if _, ok := s.Get("foo"); ok {
+		t.Fatal("Expected 'foo' to have expired")
+	}
+	if _, ok := s.Get("bar"); !ok {
+		t.Fatal("Expected 'bar' to not have expired")
+	}
+
+	s.Supplant(map[string]interface{}{"baz": "bazval"})
+	now = now.Add(time.Minute)
+
+	if l := s.Size(); l != 0 {
+		t.Fatalf("Expected the store to be empty. Got: %d", l)
+	}
+}
+
+// When the janitor is enabled, expired objects should be removed from the
+// store, and the janitor should stop when the store is stopped.
+func TestStore_WithJanitor(t *testing.T) {
+	s := New(WithJanitor(time.Millisecond)).(*storeImpl)
+	defer s.Stop()
+
+	s.SetWithTTL("foo", "fooval", time.Millisecond)
+	s.Set("bar", "barval")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mutex.RLock()
+		l := len(s.objects)
+		s.mutex.RUnlock()
+		if l == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the janitor to remove 'foo'. Got %d objects", l)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// stopping twice is fine.
+	s.Stop()
+}
+
+// When the store is bounded, setting an object into a full store should evict
+// the least recently used object, and report it to the eviction callback.
+func TestStore_WithMaxEntries(t *testing.T) {
+	var evicted []string
+	s := New(WithMaxEntries(2), WithEvictionCallback(func(key string, val interface{}) {
+		evicted = append(evicted, key)
+	}))
+
+	s.Set("foo", "fooval")
+	s.Set("bar", "barval")
+
+	// using 'foo' makes 'bar' the least recently used object.
+	if _, ok := s.Get("foo"); !ok {
+		t.Fatal("Expected 'foo' to be found")
+	}
+
+	s.Set("baz", "bazval")
+
+	if l := s.Size(); l != 2 {
+		t.Fatalf("Expected 2 objects in the store. Got: %d", l)
+	}
+	if _, ok := s.Get("bar"); ok {
+		t.Fatal("Expected 'bar' to have been evicted")
+	}
+	if len(evicted) != 1 || evicted[0] != "bar" {
+		t.Fatalf("Expected only 'bar' to have been evicted. Got: %v", evicted)
+	}
+
+	// overwriting an object does not evict anything.
+	s.Set("baz", "bazval2")
+	if len(evicted) != 1 {
+		t.Fatalf("Expected no further evictions. Got: %v", evicted)
+	}
+
+	// deleted objects no longer count towards the bound.
+	s.Delete("foo")
+	s.Set("qux", "quxval")
+	if len(evicted) != 1 || s.Size() != 2 {
+		t.Fatalf("Expected no further evictions. Got: %v", evicted)
+	}
+
+	s.Supplant(map[string]interface{}{"a": 1, "b": 2, "c": 3})
+	if l := s.Size(); l != 2 {
+		t.Fatalf("Expected 2 objects in the store. Got: %d", l)
+	}
+	if len(evicted) != 2 {
+		t.Fatalf("Expected one of the supplanted objects to be evicted. Got: %v", evicted)
+	}
+}
+
+// The eviction callback should be called for objects removed by the janitor.
+func TestStore_WithEvictionCallback(t *testing.T) {
+	evicted := make(chan string, 1)
+	s := New(WithJanitor(time.Millisecond), WithEvictionCallback(func(key string, val interface{}) {
+		evicted <- key
+	})).(ExpiringStore)
+	defer s.Stop()
+
+	s.SetWithTTL("foo", "fooval", time.Millisecond)
+
+	select {
+	case key := <-evicted:
+		if key != "foo" {
+			t.Fatalf("Expected 'foo' to be evicted. Got: %s", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected 'foo' to be evicted")
+	}
+}
+
+// The store should count hits, misses and evictions.
+func TestStore_Stats(t *testing.T) {
+	s := New(WithMaxEntries(2)).(*storeImpl)
+
+	if r := s.Stats().HitRatio(); r != 0 {
+		t.Fatalf("Expected a hit ratio of 0 for an unused store. Got: %f", r)
+	}
+
+	s.Set("foo", "fooval")
+	s.Set("bar", "barval")
+	s.Set("baz", "bazval")
+	s.Get("foo")
+	s.Get("bar")
+	s.Get("baz")
+	s.Get("qux")
+
+	expected := Stats{Hits: 2, Misses: 2, Evictions: 1, Size: 2}
+	if stats := s.Stats(); stats != expected {
+		t.Fatalf("Expected stats %+v. Got: %+v", expected, stats)
+	}
+	if r := s.Stats().HitRatio(); r != 0.5 {
+		t.Fatalf("Expected a hit ratio of 0.5. Got: %f", r)
+	}
+}
+
+// When loading a snapshot, the store should contain the objects of the store
+// the snapshot was saved from, except for expired ones.
+func TestStore_Snapshot(t *testing.T) {
+	now := time.Now()
+	s := New(WithMaxEntries(3)).(*storeImpl)
+	s.now = func() time.Time { return now }
+
+	s.Set("foo", "fooval")
+	s.Set("bar", 42)
+	s.SetWithTTL("baz", "bazval", time.Minute)
+	s.Get("foo")
+
+	var buf bytes.Buffer
+	if err := s.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("Expected the snapshot to be saved. Got: %s", err)
+	}
+
+	now = now.Add(time.Minute)
+	loaded := New(WithMaxEntries(2)).(*storeImpl)
+	loaded.now = s.now
+	if err := loaded.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("Expected the snapshot to be loaded. Got: %s", err)
+	}
+
+	// 'baz' expired, and 'bar' was used less recently than 'foo'.
+	expected := map[string]interface{}{"foo": "fooval", "bar": 42}
+	objects := loaded.Objects()
+	if len(objects) != len(expected) {
+		t.Fatalf("Expected objects %v. Got: %v", expected, objects)
+	}
+	for k, v := range expected {
+		if objects[k] != v {
+			t.Fatalf("Expected key '%s' to contain value '%v'. Got: %v", k, v, objects[k])
+		}
+	}
+
+	loaded.Set("qux", "quxval")
+	if _, ok := loaded.Get("bar"); ok {
+		t.Fatal("Expected 'bar' to have been evicted as the least recently used object")
+	}
+
+	if err := loaded.LoadSnapshot(bytes.NewBufferString("garbage")); err == nil {
+		t.Fatal("Expected loading an invalid snapshot to fail")
+	}
+}
+
+// CompareAndSwap should only replace an object holding the expected value.
+func TestStore_CompareAndSwap(t *testing.T) {
+	s := New().(AtomicStore)
+	s.Set("foo", "fooval")
+
+	if s.CompareAndSwap("foo", "barval", "bazval") {
+		t.Fatal("Expected the swap of a different value to fail")
+	}
+	if s.CompareAndSwap("bar", nil, "bazval") {
+		t.Fatal("Expected the swap of a missing object to fail")
+	}
+	if !s.CompareAndSwap("foo", "fooval", "bazval") {
+		t.Fatal("Expected the swap to succeed")
+	}
+	if oc, _ := s.Get("foo"); oc != "bazval" {
+		t.Fatalf("Expected key 'foo' to contain value 'bazval'. Got: %v", oc)
+	}
+}
+
+// SetIfAbsent should only create objects that do not exist or expired.
+func TestStore_SetIfAbsent(t *testing.T) {
+	now := time.Now()
+	s := New().(*storeImpl)
+	s.now = func() time.Time { return now }
+
+	if !s.SetIfAbsent("foo", "fooval") {
+		t.Fatal("Expected 'foo' to be set")
+	}
+	if s.SetIfAbsent("foo", "barval") {
+		t.Fatal("Expected 'foo' to not be overwritten")
+	}
+	if oc, _ := s.Get("foo"); oc != "fooval" {
+		t.Fatalf("Expected key 'foo' to contain value 'fooval'. Got: %v", oc)
+	}
+
+	s.SetWithTTL("bar", "barval", time.Second)
+	now = now.Add(time.Second)
+	if !s.SetIfAbsent("bar", "barval2") {
+		t.Fatal("Expected the expired 'bar' to be overwritten")
+	}
+}
+
+// Updates made with BatchUpdate should not race with each other.
+func TestStore_BatchUpdate(t *testing.T) {
+	for name, s := range map[string]AtomicStore{"store": New().(AtomicStore), "sharded": NewSharded(4).(AtomicStore)} {
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.BatchUpdate(func(v MutableView) {
+					// move one unit from 'foo' to 'bar'.
+					foo, _ := v.Get("foo")
+					bar, _ := v.Get("bar")
+					if foo == nil {
+						foo = 0
+					}
+					if bar == nil {
+						bar = 0
+					}
+					v.Set("foo", foo.(int)-1)
+					v.Set("bar", bar.(int)+1)
+				})
+			}()
+		}
+		wg.Wait()
+
+		foo, _ := s.Get("foo")
+		bar, _ := s.Get("bar")
+		if foo != -50 || bar != 50 {
+			t.Fatalf("%s: Expected 'foo' and 'bar' to be -50 and 50. Got: %v and %v", name, foo, bar)
+		}
+
+		s.BatchUpdate(func(v MutableView) {
+			v.Delete("foo")
+		})
+		if _, ok := s.Get("foo"); ok {
+			t.Fatalf("%s: Expected 'foo' to have been deleted", name)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package zkstore
+
+import (
+	"sync"
+)
+
+// DefaultParallelism is the number of ZK requests that bulk operations issue
+// concurrently unless overridden with OptParallelism.
+const DefaultParallelism = 16
+
+// Result is the outcome of a single operation of a bulk request.
+type Result struct {
+	// Item is the item the operation was performed on. For PutAll its Ident
+	// reflects the updated Version, for GetAll it holds the data read.
+	Item Item
+
+	// Err is the error of the operation, if any.
+	Err error
+}
+
+// PutAll puts the given items concurrently, and returns the result of each
+// Put in the same order as the items. See Put.
+func (s *Store) PutAll(items []Item) []Result {
+	results := make([]Result, len(items))
+	s.parallel(len(items), func(i int) {
+		ident, err := s.Put(items[i])
+		results[i] = Result{Item: Item{Ident: ident, Data: items[i].Data}, Err: err}
+	})
+	return results
+}
+
+// GetAll gets the identified items concurrently, and returns the result of
+// each Get in the same order as the idents. See Get.
+func (s *Store) GetAll(idents []Ident) []Result {
+	results := make([]Result, len(idents))
+	s.parallel(len(idents), func(i int) {
+		item, err := s.Get(idents[i])
+		if err != nil {
+			item.Ident = idents[i]
+		}
+		results[i] = Result{Item: item, Err: err}
+	})
+	return results
+}
+
+// DeleteAll deletes the identified items concurrently, and returns the result
+// of each Delete in the same order as the idents. See Delete.
+func (s *Store) DeleteAll(idents []Ident) []Result {
+	results := make([]Result, len(idents))
+	s.parallel(len(idents), func(i int) {
+		results[i] = Result{Item: Item{Ident: idents[i]}, Err: s.Delete(idents[i])}
+	})
+	return results
+}
+
+// parallel invokes fn for 0 <= i < n with at most s.parallelism invocations
+// running at the same time, and returns once all of them have returned.
+func (s *Store) parallel(n int, fn func(i int)) {
+	workers := s.parallelism
+	if workers <= 0 {
+		workers = DefaultParallelism
+	}
+	if workers > n {
+		workers = n
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
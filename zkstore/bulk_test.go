@@ -0,0 +1,37 @@
+package zkstore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallel(t *testing.T) {
+	require := require.New(t)
+	store := &Store{parallelism: 3}
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	seen := make([]bool, 20)
+	store.parallel(len(seen), func(i int) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		seen[i] = true
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	require.True(maxRunning <= 3, "ran %d at once", maxRunning)
+	for i, ok := range seen {
+		require.True(ok, "index %d not visited", i)
+	}
+
+	store.parallel(0, func(int) { t.Fatal("unexpected invocation") })
+}
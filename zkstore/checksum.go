@@ -0,0 +1,36 @@
+package zkstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// checksumMagic starts the data of an item written with OptChecksum. It is
+// followed by the SHA-256 digest of the rest of the data.
+var checksumMagic = []byte{0, 'z', 'k', 's'}
+
+// addChecksum prepends the checksum header to data.
+func addChecksum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	buf := make([]byte, 0, len(checksumMagic)+len(sum)+len(data))
+	buf = append(buf, checksumMagic...)
+	buf = append(buf, sum[:]...)
+	return append(buf, data...)
+}
+
+// verifyChecksum strips the checksum header from data and verifies the data
+// against it. Data without a header is returned unaltered.
+func verifyChecksum(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, checksumMagic) {
+		return data, nil
+	}
+	data = data[len(checksumMagic):]
+	if len(data) < sha256.Size {
+		return nil, ErrChecksumMismatch
+	}
+	sum := sha256.Sum256(data[sha256.Size:])
+	if !bytes.Equal(sum[:], data[:sha256.Size]) {
+		return nil, ErrChecksumMismatch
+	}
+	return data[sha256.Size:], nil
+}
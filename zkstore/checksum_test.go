@@ -0,0 +1,34 @@
+package zkstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	require := require.New(t)
+
+	for _, data := range [][]byte{{}, []byte("hello")} {
+		sealed := addChecksum(data)
+		verified, err := verifyChecksum(sealed)
+		require.NoError(err)
+		require.Equal(data, verified)
+
+		// flip a bit in the checksum, and in the data if there is any
+		for _, i := range []int{len(checksumMagic), len(sealed) - 1} {
+			corrupt := append([]byte{}, sealed...)
+			corrupt[i] ^= 1
+			_, err = verifyChecksum(corrupt)
+			require.Equal(ErrChecksumMismatch, err)
+		}
+	}
+
+	// data without a checksum is returned as is
+	verified, err := verifyChecksum([]byte("legacy"))
+	require.NoError(err)
+	require.Equal("legacy", string(verified))
+
+	_, err = verifyChecksum(append(append([]byte{}, checksumMagic...), 1, 2, 3))
+	require.Equal(ErrChecksumMismatch, err)
+}
@@ -0,0 +1,302 @@
+package zkstore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// ChunksZnodeName is the name of the znode, living next to the buckets znode
+// of a category, under which the chunks of large items are stored. It cannot
+// collide with a category name since categories may not contain dots.
+const ChunksZnodeName = ".chunks"
+
+// chunkSize is the size of the individual chunks that large items are split
+// into. It leaves ample room for the rest of the request below the default
+// ZK jute.maxbuffer.
+const chunkSize = 512 * 1024
+
+// chunkGracePeriod is how old an unreferenced chunk generation must be before
+// it is garbage collected. It protects generations that are still being
+// written by a concurrent Put from being collected before they are referenced.
+var chunkGracePeriod = time.Minute
+
+// chunkManifestMagic starts the data of an item whose data is stored in
+// chunks. The rest of the data is the JSON encoded chunkManifest.
+var chunkManifestMagic = []byte{0, 'z', 'k', 'm'}
+
+// chunkManifest describes a generation of chunks holding the data of an item.
+// The chunks of a generation are stored under
+// <category>/.chunks/<bucket>/<name>/<id>/<index>.
+type chunkManifest struct {
+	ID     string `json:"id"`
+	Chunks int    `json:"chunks"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func newChunkManifest(data []byte) (chunkManifest, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return chunkManifest{}, err
+	}
+	sum := sha256.Sum256(data)
+	return chunkManifest{
+		ID:     hex.EncodeToString(id),
+		Chunks: (len(data) + chunkSize - 1) / chunkSize,
+		Size:   len(data),
+		SHA256: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+func (m chunkManifest) encode() ([]byte, error) {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, chunkManifestMagic...), buf...), nil
+}
+
+// decodeChunkManifest returns the manifest stored in data, and false if the
+// data is not a manifest.
+func decodeChunkManifest(data []byte) (m chunkManifest, ok bool, err error) {
+	if !bytes.HasPrefix(data, chunkManifestMagic) {
+		return m, false, nil
+	}
+	if err := json.Unmarshal(data[len(chunkManifestMagic):], &m); err != nil {
+		return m, true, ErrCorruptData
+	}
+	if ValidateNamed(m.ID, true) != nil || m.Chunks <= 0 || m.Size < 0 || m.Size > m.Chunks*chunkSize {
+		return m, true, ErrCorruptData
+	}
+	return m, true, nil
+}
+
+// verify checks that the reassembled data matches the manifest.
+func (m chunkManifest) verify(data []byte) error {
+	sum := sha256.Sum256(data)
+	if len(data) != m.Size || hex.EncodeToString(sum[:]) != m.SHA256 {
+		return ErrCorruptData
+	}
+	return nil
+}
+
+// splitChunks splits data into chunks of at most size bytes.
+func splitChunks(data []byte, size int) (chunks [][]byte) {
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+// chunksPath returns the path of the znode holding all chunk generations of
+// the item at the given location.
+func (s *Store) chunksPath(location Location) (string, error) {
+	bucket, err := s.bucketFunc(location.Name)
+	if err != nil {
+		return "", err
+	}
+	bucketsPath, err := s.bucketsPath(location.Category)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(
+		path.Dir(bucketsPath),
+		ChunksZnodeName,
+		strconv.Itoa(bucket),
+		location.Name,
+	), nil
+}
+
+// putChunked writes the item data as a new generation of chunks and then
+// points the item at it by storing the manifest as the item data. Writing the
+// chunks first ensures that readers never see a manifest without its chunks.
+func (s *Store) putChunked(item Item) (*zk.Stat, error) {
+	m, err := newChunkManifest(item.Data)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := m.encode()
+	if err != nil {
+		return nil, err
+	}
+	chunksPath, err := s.chunksPath(item.Location)
+	if err != nil {
+		return nil, err
+	}
+	genPath := path.Join(chunksPath, m.ID)
+	if err := s.writeChunks(genPath, item.Data); err != nil {
+		s.removeChunks(genPath)
+		return nil, errors.Wrapf(err, "could not write chunks of %v", item.Ident)
+	}
+	s.logger.Debugf("zkstore: wrote %d chunk(s) of %v to %v", m.Chunks, item.Ident, genPath)
+	item.Data = manifest
+	stat, err := s.put(item)
+	if err != nil {
+		s.removeChunks(genPath)
+		return nil, err
+	}
+	return stat, nil
+}
+
+func (s *Store) writeChunks(genPath string, data []byte) error {
+	if err := s.createPath(genPath); err != nil {
+		return err
+	}
+	for i, chunk := range splitChunks(data, chunkSize) {
+		if _, err := s.conn.Create(path.Join(genPath, strconv.Itoa(i)), chunk, 0, s.acls); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readChunks returns the data of the item if data is a chunk manifest, and
+// data unaltered otherwise.
+func (s *Store) readChunks(location Location, data []byte) ([]byte, error) {
+	m, ok, err := decodeChunkManifest(data)
+	if !ok || err != nil {
+		return data, err
+	}
+	chunksPath, err := s.chunksPath(location)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, m.Size)
+	for i := 0; i < m.Chunks; i++ {
+		chunk, _, err := s.conn.Get(path.Join(chunksPath, m.ID, strconv.Itoa(i)))
+		switch {
+		case err == zk.ErrNoNode:
+			return nil, errors.Wrapf(ErrCorruptData, "chunk %d of %s is missing", i, m.ID)
+		case err != nil:
+			return nil, err
+		}
+		buf = append(buf, chunk...)
+	}
+	if err := m.verify(buf); err != nil {
+		return nil, errors.Wrapf(err, "chunks of %s do not match the manifest", m.ID)
+	}
+	return buf, nil
+}
+
+// collectChunks removes the chunk generations of the item at the location
+// that are no longer referenced by the item or any of its variants. stat is
+// the stat of the znode that was just written; generations created less than
+// chunkGracePeriod before it was modified are kept, since they may belong to
+// a concurrent Put. Failures are logged rather than returned since the item
+// itself has been written successfully at this point.
+func (s *Store) collectChunks(location Location, stat *zk.Stat) {
+	chunksPath, err := s.chunksPath(location)
+	if err != nil {
+		return
+	}
+	gens, _, err := s.conn.Children(chunksPath)
+	switch {
+	case err == zk.ErrNoNode || len(gens) == 0:
+		return
+	case err != nil:
+		s.logger.Warnf("zkstore: could not list chunks of %v: %v", location, err)
+		return
+	}
+	referenced, err := s.referencedChunks(location)
+	if err != nil {
+		s.logger.Warnf("zkstore: could not collect chunks of %v: %v", location, err)
+		return
+	}
+	cutoff := stat.Mtime - int64(chunkGracePeriod/time.Millisecond)
+	for _, gen := range gens {
+		if referenced[gen] {
+			continue
+		}
+		genPath := path.Join(chunksPath, gen)
+		exists, genStat, err := s.conn.Exists(genPath)
+		if err != nil || !exists || genStat.Ctime > cutoff {
+			continue
+		}
+		s.logger.Debugf("zkstore: removing unreferenced chunks %v", genPath)
+		s.removeChunks(genPath)
+	}
+}
+
+// referencedChunks returns the chunk generations referenced by the item at
+// the location and its variants.
+func (s *Store) referencedChunks(location Location) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	identPath, err := s.identPath(Ident{Location: location})
+	if err != nil {
+		return nil, err
+	}
+	variants, err := s.Variants(location)
+	switch {
+	case err == ErrNotFound:
+		return referenced, nil
+	case err != nil:
+		return nil, err
+	}
+	for _, p := range append([]string{""}, variants...) {
+		data, _, err := s.conn.Get(path.Join(identPath, p))
+		switch {
+		case err == zk.ErrNoNode:
+			continue
+		case err != nil:
+			return nil, err
+		}
+		if m, ok, err := decodeChunkManifest(data); ok && err == nil {
+			referenced[m.ID] = true
+		}
+	}
+	return referenced, nil
+}
+
+// removeChunks deletes the znode at p along with all of its children. Failures
+// are logged, as leftover chunks are collected later on.
+func (s *Store) removeChunks(p string) {
+	if err := s.deleteTree(p); err != nil {
+		s.logger.Warnf("zkstore: could not remove chunks %v: %v", p, err)
+	}
+}
+
+// deleteTree deletes the znode at p along with all of its descendants. It is
+// not an error if the znode does not exist.
+func (s *Store) deleteTree(p string) error {
+	children, _, err := s.conn.Children(p)
+	switch {
+	case err == zk.ErrNoNode:
+		return nil
+	case err != nil:
+		return err
+	}
+	for _, child := range children {
+		if err := s.deleteTree(path.Join(p, child)); err != nil {
+			return err
+		}
+	}
+	if err := s.conn.Delete(p, -1); err != nil && err != zk.ErrNoNode {
+		return err
+	}
+	return nil
+}
+
+// createPath creates the znode at p and any missing ancestors, all without
+// data.
+func (s *Store) createPath(p string) error {
+	current := "/"
+	for _, segment := range strings.Split(strings.TrimPrefix(p, "/"), "/") {
+		current = path.Join(current, segment)
+		_, err := s.conn.Create(current, nil, 0, s.acls)
+		if err != nil && err != zk.ErrNodeExists {
+			return errors.Wrapf(err, "could not create %v", current)
+		}
+	}
+	return nil
+}
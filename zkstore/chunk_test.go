@@ -0,0 +1,49 @@
+package zkstore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitChunks(t *testing.T) {
+	require := require.New(t)
+	require.Equal([][]byte{{}}, splitChunks([]byte{}, 2))
+	require.Equal([][]byte{{1, 2}}, splitChunks([]byte{1, 2}, 2))
+	require.Equal([][]byte{{1, 2}, {3}}, splitChunks([]byte{1, 2, 3}, 2))
+}
+
+func TestChunkManifest(t *testing.T) {
+	require := require.New(t)
+
+	data := bytes.Repeat([]byte("x"), 2*chunkSize+1)
+	m, err := newChunkManifest(data)
+	require.NoError(err)
+	require.Equal(3, m.Chunks)
+	require.Equal(len(data), m.Size)
+	require.NoError(m.verify(data))
+	require.Equal(ErrCorruptData, m.verify(data[1:]))
+
+	encoded, err := m.encode()
+	require.NoError(err)
+	decoded, ok, err := decodeChunkManifest(encoded)
+	require.NoError(err)
+	require.True(ok)
+	require.Equal(m, decoded)
+
+	other, err := newChunkManifest(data)
+	require.NoError(err)
+	require.NotEqual(m.ID, other.ID, "expected every manifest to get its own generation")
+
+	// regular data is not a manifest
+	_, ok, err = decodeChunkManifest(data)
+	require.NoError(err)
+	require.False(ok)
+
+	for _, bad := range []string{`{`, `{"id":"../x","chunks":1}`, `{"id":"a","chunks":0}`, `{"id":"a","chunks":1,"size":600000}`} {
+		_, ok, err = decodeChunkManifest(append(append([]byte{}, chunkManifestMagic...), bad...))
+		require.True(ok, bad)
+		require.Equal(ErrCorruptData, err, bad)
+	}
+}
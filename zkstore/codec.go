@@ -0,0 +1,197 @@
+package zkstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Codec transforms item data on its way into and out of ZK, e.g. to compress
+// or encrypt it. Codecs are configured with OptCodec.
+type Codec interface {
+	// Name identifies the codec. It is recorded in the header of every
+	// encoded znode so that the matching codec can be picked when reading.
+	// Names must be shorter than 256 bytes.
+	Name() string
+
+	// Encode transforms data before it is written.
+	Encode(data []byte) ([]byte, error)
+
+	// Decode reverses Encode.
+	Decode(data []byte) ([]byte, error)
+}
+
+// codecMagic starts the header of every encoded znode, followed by the header
+// version, the length of the codec name, and the codec name. Data that does
+// not start with the magic was written without a codec and is read as is.
+var codecMagic = []byte{0, 'z', 'k', 'c'}
+
+const codecHeaderVersion = 1
+
+// encodeData encodes the data with the codec and prepends the header.
+func encodeData(codec Codec, data []byte) ([]byte, error) {
+	name := codec.Name()
+	if len(name) == 0 || len(name) > 255 {
+		return nil, errors.Errorf("invalid codec name %q", name)
+	}
+	encoded, err := codec.Encode(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s codec could not encode data", name)
+	}
+	buf := make([]byte, 0, len(codecMagic)+2+len(name)+len(encoded))
+	buf = append(buf, codecMagic...)
+	buf = append(buf, codecHeaderVersion, byte(len(name)))
+	buf = append(buf, name...)
+	return append(buf, encoded...), nil
+}
+
+// decodeData strips the header and decodes the data with the codec named in
+// it. Data without a header is returned unaltered.
+func decodeData(codecs []Codec, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, codecMagic) {
+		return data, nil
+	}
+	header := data[len(codecMagic):]
+	if len(header) < 2 || header[0] != codecHeaderVersion || len(header) < 2+int(header[1]) {
+		return nil, ErrCorruptData
+	}
+	name := string(header[2 : 2+int(header[1])])
+	for _, codec := range codecs {
+		if codec.Name() == name {
+			decoded, err := codec.Decode(header[2+len(name):])
+			return decoded, errors.Wrapf(err, "%s codec could not decode data", name)
+		}
+	}
+	return nil, errors.Wrapf(ErrUnknownCodec, "codec %q", name)
+}
+
+// GzipCodec returns a Codec that compresses data with gzip at the given
+// compression level (see the compress/gzip constants).
+func GzipCodec(level int) (Codec, error) {
+	// validate the level up front rather than on every write
+	if _, err := gzip.NewWriterLevel(ioutil.Discard, level); err != nil {
+		return nil, err
+	}
+	return gzipCodec{level: level}, nil
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (gzipCodec) Name() string { return "gzip" }
+
+func (g gzipCodec) Encode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, g.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	// zk cannot hold more than MaxDataSize, but compressed data may expand
+	// well beyond that. cap it to avoid decompression bombs.
+	decoded, err := ioutil.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > maxDecodedSize {
+		return nil, errors.New("decompressed data is too large")
+	}
+	return decoded, nil
+}
+
+// maxDecodedSize caps the size of decompressed data.
+const maxDecodedSize = 64 * MaxDataSize
+
+// AESGCMCodec returns a Codec that encrypts data with AES-GCM. The key must be
+// 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256. A random
+// nonce is generated for every write and stored with the data.
+func AESGCMCodec(key []byte) (Codec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aesGCMCodec{aead: aead}, nil
+}
+
+type aesGCMCodec struct {
+	aead cipher.AEAD
+}
+
+func (aesGCMCodec) Name() string { return "aes-gcm" }
+
+func (a aesGCMCodec) Encode(data []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (a aesGCMCodec) Decode(data []byte) ([]byte, error) {
+	if len(data) < a.aead.NonceSize() {
+		return nil, ErrCorruptData
+	}
+	nonce, ciphertext := data[:a.aead.NonceSize()], data[a.aead.NonceSize():]
+	return a.aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// ChainCodec returns a Codec that applies the given codecs in order when
+// encoding and in reverse order when decoding. For example
+// ChainCodec(gzip, aes) compresses data before encrypting it.
+func ChainCodec(codecs ...Codec) Codec {
+	return chainCodec(codecs)
+}
+
+type chainCodec []Codec
+
+func (c chainCodec) Name() string {
+	names := make([]string, len(c))
+	for i, codec := range c {
+		names[i] = codec.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+func (c chainCodec) Encode(data []byte) (_ []byte, err error) {
+	for _, codec := range c {
+		if data, err = codec.Encode(data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (c chainCodec) Decode(data []byte) (_ []byte, err error) {
+	for i := len(c) - 1; i >= 0; i-- {
+		if data, err = c[i].Decode(data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
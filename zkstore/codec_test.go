@@ -0,0 +1,84 @@
+package zkstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	require := require.New(t)
+
+	gz, err := GzipCodec(gzip.BestCompression)
+	require.NoError(err)
+	aes, err := AESGCMCodec(bytes.Repeat([]byte("k"), 32))
+	require.NoError(err)
+
+	data := bytes.Repeat([]byte(`{"key": "value"}`), 1000)
+	for _, codec := range []Codec{gz, aes, ChainCodec(gz, aes)} {
+		encoded, err := encodeData(codec, data)
+		require.NoError(err, codec.Name())
+		require.True(bytes.HasPrefix(encoded, codecMagic), codec.Name())
+
+		decoded, err := decodeData([]Codec{codec}, encoded)
+		require.NoError(err, codec.Name())
+		require.Equal(data, decoded, codec.Name())
+	}
+
+	encoded, err := encodeData(gz, data)
+	require.NoError(err)
+	require.True(len(encoded) < len(data)/10, "expected gzip to compress repetitive data")
+	require.Equal("gzip+aes-gcm", ChainCodec(gz, aes).Name())
+}
+
+func TestCodecMixedData(t *testing.T) {
+	require := require.New(t)
+
+	gz, err := GzipCodec(gzip.DefaultCompression)
+	require.NoError(err)
+	aes, err := AESGCMCodec(bytes.Repeat([]byte("k"), 16))
+	require.NoError(err)
+
+	// data written before any codec was configured is read as is
+	decoded, err := decodeData([]Codec{aes, gz}, []byte("legacy"))
+	require.NoError(err)
+	require.Equal([]byte("legacy"), decoded)
+
+	// data written with a previous codec is read with that codec
+	encoded, err := encodeData(gz, []byte("old"))
+	require.NoError(err)
+	decoded, err = decodeData([]Codec{aes, gz}, encoded)
+	require.NoError(err)
+	require.Equal([]byte("old"), decoded)
+
+	// unless the previous codec is no longer configured
+	_, err = decodeData([]Codec{aes}, encoded)
+	require.Error(err)
+	require.Contains(err.Error(), ErrUnknownCodec.Error())
+}
+
+func TestCodecErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := GzipCodec(42)
+	require.Error(err)
+	_, err = AESGCMCodec([]byte("short"))
+	require.Error(err)
+
+	aes, err := AESGCMCodec(bytes.Repeat([]byte("k"), 16))
+	require.NoError(err)
+	otherAES, err := AESGCMCodec(bytes.Repeat([]byte("x"), 16))
+	require.NoError(err)
+
+	encoded, err := encodeData(aes, []byte("secret"))
+	require.NoError(err)
+	_, err = decodeData([]Codec{otherAES}, encoded)
+	require.Error(err, "expected decryption with the wrong key to fail")
+
+	_, err = decodeData([]Codec{aes}, append(append([]byte{}, codecMagic...), codecHeaderVersion))
+	require.Equal(ErrCorruptData, err)
+	_, err = decodeData([]Codec{aes}, append(append([]byte{}, codecMagic...), 42, 0))
+	require.Equal(ErrCorruptData, err)
+}
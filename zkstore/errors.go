@@ -18,6 +18,21 @@ const (
 	// ErrNotFound is returned when an attempting to read a znode that does not exist.
 	ErrNotFound = internalError("znode not found")
 
+	// ErrUnknownCodec is returned when reading data that was encoded with a
+	// Codec that is not configured on the Store.
+	ErrUnknownCodec = internalError("data encoded with unknown codec")
+
+	// ErrCorruptData is returned when stored data cannot be decoded.
+	ErrCorruptData = internalError("corrupt data")
+
+	// ErrChecksumMismatch is returned when the data of an item written with
+	// OptChecksum does not match its checksum.
+	ErrChecksumMismatch = internalError("checksum mismatch")
+
+	// ErrReadOnly is returned by mutating operations of a Store configured
+	// with OptReadOnly.
+	ErrReadOnly = internalError("store is read-only")
+
 	errHashOverflow = internalError("hash value larger than 64 bits")
 
 	errBadCategory = internalError("bad category name")
@@ -16,6 +16,10 @@ type Item struct {
 
 	// Data represents the bytes to be stored within the znode.
 	Data []byte
+
+	// Meta describes the znode the item was read from. It is set by Get and
+	// ignored by Put.
+	Meta *Metadata
 }
 
 // Validate performs validation on the Item
@@ -0,0 +1,236 @@
+package zkstore
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+const (
+	// LocksZnodeName is the name of the znode under the base path that holds
+	// the znodes of all Mutexes. It cannot collide with a category name since
+	// categories may not contain dots.
+	LocksZnodeName = ".locks"
+
+	// ElectionsZnodeName is the name of the znode under the base path that
+	// holds the znodes of all Elections.
+	ElectionsZnodeName = ".elections"
+)
+
+// ErrNotHeld is returned when releasing a Mutex or Election that is not held.
+const ErrNotHeld = internalError("not held")
+
+// Mutex is a distributed lock. Mutexes with the same name, created from
+// Stores sharing a base path, exclude each other.
+//
+// The lock is tied to the ZK session of the Store: if the session expires,
+// the lock is released without the holder being notified.
+type Mutex struct {
+	c *contender
+}
+
+// Mutex returns the Mutex with the given name. The name must be a valid item
+// name.
+func (s *Store) Mutex(name string) (*Mutex, error) {
+	c, err := s.newContender(LocksZnodeName, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Mutex{c: c}, nil
+}
+
+// Lock blocks until the lock is acquired or ctx is done, in which case the
+// ctx error is returned.
+func (m *Mutex) Lock(ctx context.Context) error {
+	return m.c.acquire(ctx, nil)
+}
+
+// Unlock releases the lock. Returns ErrNotHeld if the lock is not held.
+func (m *Mutex) Unlock() error {
+	return m.c.release()
+}
+
+// Election elects a single leader among all candidates of the same name,
+// created from Stores sharing a base path.
+//
+// Leadership is tied to the ZK session of the Store: if the session expires,
+// leadership is lost without the leader being notified.
+type Election struct {
+	c *contender
+}
+
+// Election returns the Election with the given name. The name must be a valid
+// item name.
+func (s *Store) Election(name string) (*Election, error) {
+	c, err := s.newContender(ElectionsZnodeName, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Election{c: c}, nil
+}
+
+// Campaign blocks until the candidate is elected leader or ctx is done, in
+// which case the ctx error is returned. ident identifies the candidate to
+// other candidates, see Leader.
+func (e *Election) Campaign(ctx context.Context, ident []byte) error {
+	return e.c.acquire(ctx, ident)
+}
+
+// Resign gives up leadership. Returns ErrNotHeld if the candidate is not the
+// leader.
+func (e *Election) Resign() error {
+	return e.c.release()
+}
+
+// Leader returns the ident of the current leader, or ErrNotFound if there is
+// no leader.
+func (e *Election) Leader() ([]byte, error) {
+	for {
+		nodes, err := e.c.nodes()
+		switch {
+		case err != nil:
+			return nil, err
+		case len(nodes) == 0:
+			return nil, ErrNotFound
+		}
+		data, _, err := e.c.store.conn.Get(path.Join(e.c.path, nodes[0]))
+		if err == zk.ErrNoNode {
+			// the leader just resigned. look again.
+			continue
+		}
+		return data, err
+	}
+}
+
+// contender implements the ZK lock recipe: every contender creates an
+// ephemeral sequential znode, and the contender with the lowest sequence
+// number holds the lock. All others watch the znode just before their own
+// so that releasing the lock wakes up a single contender only.
+type contender struct {
+	store *Store
+	path  string
+
+	mu   sync.Mutex
+	node string // our znode, if held
+}
+
+func (s *Store) newContender(parent, name string) (*contender, error) {
+	if err := ValidateNamed(name, true); err != nil {
+		return nil, errors.Wrap(err, "invalid name")
+	}
+	return &contender{
+		store: s,
+		path:  path.Join("/", s.basePath, parent, name),
+	}, nil
+}
+
+func (c *contender) acquire(ctx context.Context, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.node != "" {
+		return errors.Errorf("%v is already held", c.path)
+	}
+	if err := c.store.createPath(c.path); err != nil {
+		return err
+	}
+	conn := c.store.conn
+	node, err := conn.CreateProtectedEphemeralSequential(c.path+"/lock-", data, c.store.acls)
+	if err != nil {
+		return errors.Wrapf(err, "could not create lock node in %v", c.path)
+	}
+	node = path.Base(node)
+	abandon := func(err error) error {
+		if derr := conn.Delete(path.Join(c.path, node), -1); derr != nil && derr != zk.ErrNoNode {
+			c.store.logger.Warnf("zkstore: could not delete lock node %v: %v", node, derr)
+		}
+		return err
+	}
+	for {
+		nodes, err := c.nodes()
+		if err != nil {
+			return abandon(err)
+		}
+		prev, err := predecessor(node, nodes)
+		if err != nil {
+			return abandon(err)
+		}
+		if prev == "" {
+			c.node = node
+			return nil
+		}
+		exists, _, ch, err := conn.ExistsW(path.Join(c.path, prev))
+		switch {
+		case err != nil:
+			return abandon(err)
+		case !exists:
+			continue
+		}
+		c.store.logger.Debugf("zkstore: waiting for %v in %v", prev, c.path)
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return abandon(ctx.Err())
+		}
+	}
+}
+
+func (c *contender) release() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.node == "" {
+		return ErrNotHeld
+	}
+	err := c.store.conn.Delete(path.Join(c.path, c.node), -1)
+	if err != nil && err != zk.ErrNoNode {
+		return err
+	}
+	c.node = ""
+	return nil
+}
+
+// nodes returns the contending znodes ordered by sequence number.
+func (c *contender) nodes() ([]string, error) {
+	children, _, err := c.store.conn.Children(c.path)
+	switch {
+	case err == zk.ErrNoNode:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return sortBySequence(children)
+}
+
+// predecessor returns the node sorted right before node, or "" if node is
+// the first one. nodes must be sorted by sequence number.
+func predecessor(node string, nodes []string) (string, error) {
+	for i, n := range nodes {
+		if n == node {
+			if i == 0 {
+				return "", nil
+			}
+			return nodes[i-1], nil
+		}
+	}
+	return "", errors.Errorf("lock node %v disappeared", node)
+}
+
+// sortBySequence sorts sequential znode names by their sequence number.
+func sortBySequence(nodes []string) ([]string, error) {
+	sequences := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		i := strings.LastIndex(node, "-")
+		seq, err := strconv.Atoi(node[i+1:])
+		if err != nil {
+			return nil, errors.Errorf("invalid lock node %v", node)
+		}
+		sequences[node] = seq
+	}
+	sort.Slice(nodes, func(i, j int) bool { return sequences[nodes[i]] < sequences[nodes[j]] })
+	return nodes, nil
+}
@@ -0,0 +1,41 @@
+package zkstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortBySequence(t *testing.T) {
+	require := require.New(t)
+	nodes, err := sortBySequence([]string{
+		"_c_bb-lock-0000000010",
+		"_c_aa-lock-0000000002",
+		"_c_cc-lock-0000000009",
+	})
+	require.NoError(err)
+	require.Equal([]string{
+		"_c_aa-lock-0000000002",
+		"_c_cc-lock-0000000009",
+		"_c_bb-lock-0000000010",
+	}, nodes)
+
+	_, err = sortBySequence([]string{"garbage"})
+	require.Error(err)
+}
+
+func TestPredecessor(t *testing.T) {
+	require := require.New(t)
+	nodes := []string{"a-1", "b-2", "c-3"}
+
+	prev, err := predecessor("a-1", nodes)
+	require.NoError(err)
+	require.Equal("", prev)
+
+	prev, err = predecessor("c-3", nodes)
+	require.NoError(err)
+	require.Equal("b-2", prev)
+
+	_, err = predecessor("d-4", nodes)
+	require.Error(err)
+}
@@ -0,0 +1,14 @@
+// Package memstore provides an in-memory implementation of zkstore.IStore for
+// use in tests.
+//
+// It mirrors the semantics of zkstore.Store, including versions, variants,
+// ErrVersionConflict and ErrNotFound, so that code written against
+// zkstore.IStore can be unit tested without a ZooKeeper instance:
+//
+//	func NewService(store zkstore.IStore) *Service { ... }
+//
+//	func TestService(t *testing.T) {
+//		svc := NewService(memstore.New())
+//		...
+//	}
+package memstore
@@ -0,0 +1,232 @@
+package memstore
+
+import (
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-go/zkstore"
+	"github.com/pkg/errors"
+)
+
+// Store is an in-memory zkstore.IStore. It is safe for concurrent use.
+type Store struct {
+	mu         sync.Mutex
+	categories map[string]map[string]*node // category -> item name -> item
+}
+
+// ensure that Store confirms to the IStore interface.
+var _ zkstore.IStore = &Store{}
+
+// node is an item or a variant.
+type node struct {
+	data     []byte
+	version  int32
+	created  time.Time
+	modified time.Time
+	variants map[string]*node // nil for variants
+}
+
+func newNode(data []byte, variants bool) *node {
+	now := time.Now()
+	n := &node{data: clone(data), created: now, modified: now}
+	if variants {
+		n.variants = make(map[string]*node)
+	}
+	return n
+}
+
+func (n *node) set(data []byte) {
+	n.data = clone(data)
+	n.version++
+	n.modified = time.Now()
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{categories: make(map[string]map[string]*node)}
+}
+
+// Put stores the specified item, see zkstore.Store.Put.
+func (s *Store) Put(item zkstore.Item) (zkstore.Ident, error) {
+	if err := item.Validate(); err != nil {
+		return item.Ident, err
+	}
+	if err := validateCategory(item.Location.Category); err != nil {
+		return item.Ident, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, hasVersion := item.Version.Value()
+	creating := hasVersion && version == zkstore.NoPriorVersion
+	n := s.lookup(item.Ident)
+	switch {
+	case n != nil && creating:
+		return item.Ident, zkstore.ErrVersionConflict
+	case n != nil:
+		if hasVersion && version != n.version {
+			return item.Ident, zkstore.ErrVersionConflict
+		}
+		n.set(item.Data)
+	default:
+		// like zkstore.Store, create the parent item of a variant with the
+		// same data, even if the variant itself cannot be created.
+		items, ok := s.categories[item.Location.Category]
+		if !ok {
+			items = make(map[string]*node)
+			s.categories[item.Location.Category] = items
+		}
+		parent, ok := items[item.Location.Name]
+		if !ok {
+			parent = newNode(item.Data, true)
+			if item.Variant == "" && hasVersion && !creating {
+				return item.Ident, zkstore.ErrVersionConflict
+			}
+			items[item.Location.Name] = parent
+		}
+		n = parent
+		if item.Variant != "" {
+			if hasVersion && !creating {
+				return item.Ident, zkstore.ErrVersionConflict
+			}
+			n = newNode(item.Data, false)
+			parent.variants[item.Variant] = n
+		}
+	}
+	item.Ident.Version = zkstore.NewVersion(n.version)
+	return item.Ident, nil
+}
+
+// Get fetches the data for a particular item, see zkstore.Store.Get.
+func (s *Store) Get(ident zkstore.Ident) (item zkstore.Item, err error) {
+	if err := ident.Validate(); err != nil {
+		return item, err
+	}
+	if err := validateCategory(ident.Location.Category); err != nil {
+		return item, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.lookup(ident)
+	if n == nil {
+		return item, zkstore.ErrNotFound
+	}
+	item.Ident = ident
+	item.Data = clone(n.data)
+	item.Ident.Version = zkstore.NewVersion(n.version)
+	item.Meta = &zkstore.Metadata{
+		Created:     n.created,
+		Modified:    n.modified,
+		DataLength:  len(n.data),
+		NumVariants: len(n.variants),
+	}
+	return item, nil
+}
+
+// List lists the locations of all items in the category, see
+// zkstore.Store.List.
+func (s *Store) List(category string) (locations []zkstore.Location, err error) {
+	if err := zkstore.ValidateCategory(category); err != nil {
+		return nil, errors.Wrap(err, "invalid category")
+	}
+	if err := validateCategory(category); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, ok := s.categories[category]
+	if !ok {
+		return nil, zkstore.ErrNotFound
+	}
+	for name := range items {
+		locations = append(locations, zkstore.Location{Category: category, Name: name})
+	}
+	sort.Slice(zkstore.LocationsByName(locations))
+	return locations, nil
+}
+
+// Variants returns the sorted variants of the item at the location, see
+// zkstore.Store.Variants.
+func (s *Store) Variants(location zkstore.Location) (variants []string, err error) {
+	if err := location.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.lookup(zkstore.Ident{Location: location})
+	if n == nil {
+		return nil, zkstore.ErrNotFound
+	}
+	variants = []string{}
+	for v := range n.variants {
+		variants = append(variants, v)
+	}
+	sort.Strings(variants)
+	return variants, nil
+}
+
+// Delete deletes the identified item or variant, see zkstore.Store.Delete.
+func (s *Store) Delete(ident zkstore.Ident) error {
+	if err := ident.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.lookup(ident)
+	if n == nil {
+		return nil
+	}
+	version, hasVersion := ident.Version.Value()
+	if ident.Variant != "" {
+		if hasVersion && version != n.version {
+			return zkstore.ErrVersionConflict
+		}
+		delete(s.categories[ident.Location.Category][ident.Location.Name].variants, ident.Variant)
+		return nil
+	}
+	// like zkstore.Store, variants are deleted before the item version is
+	// checked.
+	n.variants = make(map[string]*node)
+	if hasVersion && version != n.version {
+		return zkstore.ErrVersionConflict
+	}
+	// the category remains, as the buckets znode does in ZK.
+	delete(s.categories[ident.Location.Category], ident.Location.Name)
+	return nil
+}
+
+// Close is a no-op.
+func (s *Store) Close() error {
+	return nil
+}
+
+// lookup returns the identified item or variant, or nil if it does not exist.
+func (s *Store) lookup(ident zkstore.Ident) *node {
+	n := s.categories[ident.Location.Category][ident.Location.Name]
+	if n == nil || ident.Variant == "" {
+		return n
+	}
+	return n.variants[ident.Variant]
+}
+
+// validateCategory rejects the categories that zkstore.Store rejects on top
+// of zkstore.ValidateCategory.
+func validateCategory(category string) error {
+	if path.Base(category) == zkstore.DefaultBucketsZnodeName {
+		return errors.New("bad category name")
+	}
+	return nil
+}
+
+func clone(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append([]byte{}, data...)
+}
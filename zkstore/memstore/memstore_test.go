@@ -0,0 +1,145 @@
+package memstore
+
+import (
+	"testing"
+
+	"github.com/dcos/dcos-go/zkstore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutGet(t *testing.T) {
+	require := require.New(t)
+	store := New()
+
+	ident := zkstore.Ident{Location: zkstore.Location{Category: "/widgets", Name: "foo"}}
+	_, err := store.Get(ident)
+	require.Equal(zkstore.ErrNotFound, err)
+
+	data := []byte("hello")
+	returned, err := store.Put(zkstore.Item{Ident: ident, Data: data})
+	require.NoError(err)
+	require.Equal(zkstore.NewVersion(0), returned.Version)
+	data[0] = 'j' // the store must not share the slice
+
+	item, err := store.Get(ident)
+	require.NoError(err)
+	require.Equal("hello", string(item.Data))
+	require.Equal(zkstore.NewVersion(0), item.Version)
+	require.Equal(5, item.Meta.DataLength)
+
+	returned, err = store.Put(zkstore.Item{Ident: ident, Data: []byte("world")})
+	require.NoError(err)
+	require.Equal(zkstore.NewVersion(1), returned.Version)
+
+	_, err = store.Put(zkstore.Item{Ident: zkstore.Ident{Location: zkstore.Location{Category: "/widgets/buckets", Name: "x"}}})
+	require.Error(err)
+	_, err = store.Put(zkstore.Item{Ident: ident, Data: make([]byte, zkstore.MaxDataSize+1)})
+	require.Error(err)
+}
+
+func TestVersion(t *testing.T) {
+	require := require.New(t)
+	store := New()
+
+	newItem := func(version ...int32) zkstore.Item {
+		item := zkstore.Item{
+			Ident: zkstore.Ident{
+				Location: zkstore.Location{Category: "/widgets", Name: "foo"},
+				Variant:  "my-version",
+			},
+			Data: []byte("hello"),
+		}
+		if len(version) > 0 {
+			item.Version = zkstore.NewVersion(version[0])
+		}
+		return item
+	}
+
+	// the item does not exist yet
+	_, err := store.Put(newItem(42))
+	require.Equal(zkstore.ErrVersionConflict, err)
+
+	ident, err := store.Put(newItem(zkstore.NoPriorVersion))
+	require.NoError(err)
+	require.Equal(zkstore.NewVersion(0), ident.Version)
+	_, err = store.Put(newItem(zkstore.NoPriorVersion))
+	require.Equal(zkstore.ErrVersionConflict, err)
+
+	ident, err = store.Put(newItem())
+	require.NoError(err)
+	require.Equal(zkstore.NewVersion(1), ident.Version)
+	ident, err = store.Put(newItem(1))
+	require.NoError(err)
+	require.Equal(zkstore.NewVersion(2), ident.Version)
+	_, err = store.Put(newItem(1))
+	require.Equal(zkstore.ErrVersionConflict, err)
+
+	require.Equal(zkstore.ErrVersionConflict, store.Delete(newItem(1).Ident))
+	require.NoError(store.Delete(newItem(2).Ident))
+	_, err = store.Get(newItem().Ident)
+	require.Equal(zkstore.ErrNotFound, err)
+}
+
+func TestVariants(t *testing.T) {
+	require := require.New(t)
+	store := New()
+	location := zkstore.Location{Category: "widgets", Name: "foo"}
+
+	_, err := store.Variants(location)
+	require.Equal(zkstore.ErrNotFound, err)
+
+	// putting a variant creates the item with the same data
+	_, err = store.Put(zkstore.Item{Ident: zkstore.Ident{Location: location, Variant: "v2"}, Data: []byte("v2")})
+	require.NoError(err)
+	_, err = store.Put(zkstore.Item{Ident: zkstore.Ident{Location: location, Variant: "v1"}, Data: []byte("v1")})
+	require.NoError(err)
+	item, err := store.Get(zkstore.Ident{Location: location})
+	require.NoError(err)
+	require.Equal("v2", string(item.Data))
+	require.Equal(2, item.Meta.NumVariants)
+
+	variants, err := store.Variants(location)
+	require.NoError(err)
+	require.Equal([]string{"v1", "v2"}, variants)
+
+	require.NoError(store.Delete(zkstore.Ident{Location: location, Variant: "v1"}))
+	variants, err = store.Variants(location)
+	require.NoError(err)
+	require.Equal([]string{"v2"}, variants)
+
+	// deleting the item deletes its variants
+	require.NoError(store.Delete(zkstore.Ident{Location: location}))
+	_, err = store.Get(zkstore.Ident{Location: location, Variant: "v2"})
+	require.Equal(zkstore.ErrNotFound, err)
+	require.NoError(store.Delete(zkstore.Ident{Location: location}))
+}
+
+func TestList(t *testing.T) {
+	require := require.New(t)
+	store := New()
+
+	_, err := store.List("widgets")
+	require.Equal(zkstore.ErrNotFound, err)
+	_, err = store.List("")
+	require.Error(err)
+
+	for _, name := range []string{"c", "a", "b"} {
+		_, err := store.Put(zkstore.Item{Ident: zkstore.Ident{Location: zkstore.Location{Category: "widgets", Name: name}}})
+		require.NoError(err)
+	}
+	locations, err := store.List("widgets")
+	require.NoError(err)
+	require.Equal([]zkstore.Location{
+		{Category: "widgets", Name: "a"},
+		{Category: "widgets", Name: "b"},
+		{Category: "widgets", Name: "c"},
+	}, locations)
+
+	// the category outlives its items
+	for _, l := range locations {
+		require.NoError(store.Delete(zkstore.Ident{Location: l}))
+	}
+	locations, err = store.List("widgets")
+	require.NoError(err)
+	require.Empty(locations)
+}
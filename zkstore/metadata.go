@@ -0,0 +1,61 @@
+package zkstore
+
+import (
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Metadata describes the znode backing an item.
+type Metadata struct {
+	// Created is when the item was created.
+	Created time.Time
+
+	// Modified is when the item data was last written.
+	Modified time.Time
+
+	// DataLength is the size of the data as stored in ZK. It differs from the
+	// length of the item data for items written with a Codec or in chunks.
+	DataLength int
+
+	// NumVariants is the number of variants of the item. It is always zero
+	// for a variant.
+	NumVariants int
+}
+
+func newMetadata(stat *zk.Stat) *Metadata {
+	return &Metadata{
+		Created:     msToTime(stat.Ctime),
+		Modified:    msToTime(stat.Mtime),
+		DataLength:  int(stat.DataLength),
+		NumVariants: int(stat.NumChildren),
+	}
+}
+
+func msToTime(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// Stat fetches the metadata of a particular item without fetching its data.
+// Returns ErrNotFound if no such item exists.
+func (s *Store) Stat(ident Ident) (meta *Metadata, err error) {
+	if err := ident.Validate(); err != nil {
+		return nil, err
+	}
+	identPath, err := s.identPath(ident)
+	if err != nil {
+		return nil, err
+	}
+	err = s.retry("stat", func() error {
+		exists, stat, err := s.conn.Exists(identPath)
+		switch {
+		case err != nil:
+			return err
+		case !exists:
+			return ErrNotFound
+		}
+		meta = newMetadata(stat)
+		return nil
+	})
+	return meta, err
+}
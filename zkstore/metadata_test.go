@@ -0,0 +1,25 @@
+package zkstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMetadata(t *testing.T) {
+	require := require.New(t)
+	meta := newMetadata(&zk.Stat{
+		Ctime:       1500000000000,
+		Mtime:       1500000001500,
+		DataLength:  42,
+		NumChildren: 3,
+	})
+	require.Equal(&Metadata{
+		Created:     time.Unix(1500000000, 0),
+		Modified:    time.Unix(1500000001, int64(500*time.Millisecond)),
+		DataLength:  42,
+		NumVariants: 3,
+	}, meta)
+}
@@ -0,0 +1,31 @@
+package zkstore
+
+import (
+	"time"
+)
+
+// Metrics receives measurements of Store operations, and may be used to feed
+// them into a metrics library of choice. Implementations must be safe for
+// concurrent use, and should not block. Metrics are configured with
+// OptMetrics.
+type Metrics interface {
+	// Operation is called after every Put, Get, List and Delete, with the
+	// operation name ("put", "get", "list", "delete"), its duration, the size
+	// of the item data that was written or read (0 for list and delete) and
+	// the error returned by the operation, if any.
+	Operation(op string, d time.Duration, size int, err error)
+
+	// Retry is called whenever an operation is retried, e.g. when Update
+	// starts over after a version conflict.
+	Retry(op string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Operation(string, time.Duration, int, error) {}
+func (nopMetrics) Retry(string)                                {}
+
+// observe reports an operation that was started at the given time.
+func (s *Store) observe(op string, start time.Time, size int, err error) {
+	s.metrics.Operation(op, time.Since(start), size, err)
+}
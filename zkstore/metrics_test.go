@@ -0,0 +1,56 @@
+package zkstore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordedOp struct {
+	op   string
+	size int
+	err  bool
+}
+
+type testMetrics struct {
+	sync.Mutex
+	ops     []recordedOp
+	retries []string
+}
+
+func (m *testMetrics) Operation(op string, d time.Duration, size int, err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.ops = append(m.ops, recordedOp{op: op, size: size, err: err != nil})
+}
+
+func (m *testMetrics) Retry(op string) {
+	m.Lock()
+	defer m.Unlock()
+	m.retries = append(m.retries, op)
+}
+
+func TestMetrics(t *testing.T) {
+	require := require.New(t)
+	metrics := &testMetrics{}
+	store := &Store{maxDataSize: MaxDataSize}
+	require.NoError(OptMetrics(metrics).Apply(store))
+
+	// invalid arguments fail before ZK is contacted, but are still recorded.
+	_, err := store.Put(Item{Data: []byte("abc")})
+	require.Error(err)
+	_, err = store.Get(Ident{})
+	require.Error(err)
+	_, err = store.List("")
+	require.Error(err)
+	require.Error(store.Delete(Ident{}))
+
+	require.Equal([]recordedOp{
+		{op: "put", size: 3, err: true},
+		{op: "get", err: true},
+		{op: "list", err: true},
+		{op: "delete", err: true},
+	}, metrics.ops)
+}
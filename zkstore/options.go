@@ -3,7 +3,9 @@ package zkstore
 import (
 	"path"
 	"strings"
+	"time"
 
+	"github.com/dcos/dcos-go/dcoslog"
 	"github.com/samuel/go-zookeeper/zk"
 )
 
@@ -99,6 +101,173 @@ func OptBucketsZnodeName(name string) StoreOpt {
 	}
 }
 
+// OptLogger configures the store to send diagnostic messages to the given logger.
+// A nil logger does not alter the store configuration.
+func OptLogger(logger dcoslog.Logger) StoreOpt {
+	if logger == nil {
+		return nil // use default instead
+	}
+	return func(store *Store) error {
+		store.logger = logger
+		return nil
+	}
+}
+
+// OptCodec configures the codecs used to transform item data. The first codec
+// encodes all data written by Put. When reading, data is decoded with whichever
+// of the codecs it was encoded with, so codecs that were used previously should
+// be listed after the current one. Data written without a codec is always
+// read as is.
+// No codecs do not alter the store configuration; a nil codec returns
+// ErrIllegalOption.
+func OptCodec(codecs ...Codec) StoreOpt {
+	if len(codecs) == 0 {
+		return nil
+	}
+	for _, codec := range codecs {
+		if codec == nil {
+			return optError
+		}
+	}
+	return func(store *Store) error {
+		store.codecs = codecs
+		return nil
+	}
+}
+
+// OptChunking allows items of up to maxDataSize bytes to be stored by
+// transparently splitting data larger than MaxDataSize across several chunk
+// znodes. Items written this way can only be read by Stores of a version that
+// supports chunking, but their chunks can be read regardless of OptChunking.
+// A zero size does not alter the store configuration; a size less than
+// MaxDataSize returns ErrIllegalOption.
+func OptChunking(maxDataSize int) StoreOpt {
+	if maxDataSize == 0 {
+		return nil
+	}
+	if maxDataSize < MaxDataSize {
+		return optError
+	}
+	return func(store *Store) error {
+		store.maxDataSize = maxDataSize
+		return nil
+	}
+}
+
+// OptVariantRetention configures the store to keep only the n most recently
+// modified variants of an item, deleting older ones whenever a variant is
+// written. See also Store.PruneVariants.
+// A zero count does not alter the store configuration; a negative count
+// returns ErrIllegalOption.
+func OptVariantRetention(n int) StoreOpt {
+	if n == 0 {
+		return nil
+	}
+	if n < 0 {
+		return optError
+	}
+	return func(store *Store) error {
+		store.variantRetention = n
+		return nil
+	}
+}
+
+// OptVariantTTL configures the store to delete variants of an item that have
+// not been modified for the given duration whenever a variant is written.
+// The variant that was just written is never deleted.
+// A zero duration does not alter the store configuration; a negative
+// duration returns ErrIllegalOption.
+func OptVariantTTL(d time.Duration) StoreOpt {
+	if d == 0 {
+		return nil
+	}
+	if d < 0 {
+		return optError
+	}
+	return func(store *Store) error {
+		store.variantTTL = d
+		return nil
+	}
+}
+
+// OptParallelism configures the number of ZK requests that bulk operations
+// such as PutAll issue concurrently.
+// A zero value does not alter the store configuration; a negative value
+// returns ErrIllegalOption.
+func OptParallelism(n int) StoreOpt {
+	if n == 0 {
+		return nil
+	}
+	if n < 0 {
+		return optError
+	}
+	return func(store *Store) error {
+		store.parallelism = n
+		return nil
+	}
+}
+
+// OptMetrics configures the store to report measurements of its operations
+// to the given Metrics.
+// A nil Metrics does not alter the store configuration.
+func OptMetrics(metrics Metrics) StoreOpt {
+	if metrics == nil {
+		return nil // use default instead
+	}
+	return func(store *Store) error {
+		store.metrics = metrics
+		return nil
+	}
+}
+
+// OptRetryPolicy configures the store to retry operations that fail with
+// transient ZK errors according to the given policy, see RetryPolicy.
+// A zero policy does not alter the store configuration; a policy without an
+// InitialBackoff or MaxElapsedTime, or with negative durations, returns
+// ErrIllegalOption.
+func OptRetryPolicy(policy RetryPolicy) StoreOpt {
+	if policy == (RetryPolicy{}) {
+		return nil
+	}
+	if policy.InitialBackoff <= 0 || policy.MaxElapsedTime <= 0 || policy.MaxBackoff < 0 {
+		return optError
+	}
+	return func(store *Store) error {
+		store.retryPolicy = &policy
+		return nil
+	}
+}
+
+// OptReadOnly configures the store to reject Put, Delete and any other
+// operation that would modify items with ErrReadOnly, without contacting ZK.
+func OptReadOnly() StoreOpt {
+	return func(store *Store) error {
+		store.writeMode = writeModeReadOnly
+		return nil
+	}
+}
+
+// OptDryRun configures the store to log operations that would modify items
+// at the info level instead of performing them, which is useful for
+// rehearsing migrations. Such operations report success.
+func OptDryRun() StoreOpt {
+	return func(store *Store) error {
+		store.writeMode = writeModeDryRun
+		return nil
+	}
+}
+
+// OptChecksum configures the store to write a SHA-256 checksum along with
+// the data of every item. Get verifies the checksum of items that have one
+// regardless of this option, and returns ErrChecksumMismatch if the data
+// does not match it.
+func OptChecksum() StoreOpt {
+	return func(store *Store) error {
+		store.checksum = true
+		return nil
+	}
+}
+
 func optBucketFunc(f func(string) (int, error)) StoreOpt {
 	if f == nil {
 		return nil
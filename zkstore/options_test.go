@@ -1,10 +1,13 @@
 package zkstore
 
 import (
+	"compress/gzip"
 	"crypto/md5"
 	"crypto/sha1"
 	"testing"
+	"time"
 
+	"github.com/dcos/dcos-go/dcoslog"
 	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/require"
 )
@@ -39,3 +42,89 @@ func TestOptHashProviderFunc(t *testing.T) {
 	require.NoError(OptHashProviderFunc(HashProvider(md5.New)).Apply(store))
 	require.NoError(OptHashProviderFunc(HashProvider(sha1.New)).Apply(store))
 }
+
+func TestOptLogger(t *testing.T) {
+	require := require.New(t)
+	store := &Store{}
+	require.NoError(OptLogger(nil).Apply(store))
+	require.Nil(store.logger)
+	logger := dcoslog.Nop()
+	require.NoError(OptLogger(logger).Apply(store))
+	require.Equal(logger, store.logger)
+}
+
+func TestOptCodec(t *testing.T) {
+	require := require.New(t)
+	store := &Store{}
+	require.NoError(OptCodec().Apply(store))
+	require.EqualError(OptCodec(nil).Apply(store), ErrIllegalOption.Error())
+	codec, err := GzipCodec(gzip.DefaultCompression)
+	require.NoError(err)
+	require.NoError(OptCodec(codec).Apply(store))
+	require.Equal([]Codec{codec}, store.codecs)
+}
+
+func TestOptChunking(t *testing.T) {
+	require := require.New(t)
+	store := &Store{maxDataSize: MaxDataSize}
+	require.NoError(OptChunking(0).Apply(store))
+	require.Equal(MaxDataSize, store.maxDataSize)
+	require.EqualError(OptChunking(MaxDataSize-1).Apply(store), ErrIllegalOption.Error())
+	require.NoError(OptChunking(10 * MaxDataSize).Apply(store))
+	require.Equal(10*MaxDataSize, store.maxDataSize)
+}
+
+func TestOptVariantRetention(t *testing.T) {
+	require := require.New(t)
+	store := &Store{}
+	require.NoError(OptVariantRetention(0).Apply(store))
+	require.EqualError(OptVariantRetention(-1).Apply(store), ErrIllegalOption.Error())
+	require.NoError(OptVariantRetention(3).Apply(store))
+	require.Equal(3, store.variantRetention)
+
+	require.NoError(OptVariantTTL(0).Apply(store))
+	require.EqualError(OptVariantTTL(-time.Second).Apply(store), ErrIllegalOption.Error())
+	require.NoError(OptVariantTTL(time.Hour).Apply(store))
+	require.Equal(time.Hour, store.variantTTL)
+}
+
+func TestOptParallelism(t *testing.T) {
+	require := require.New(t)
+	store := &Store{}
+	require.NoError(OptParallelism(0).Apply(store))
+	require.EqualError(OptParallelism(-1).Apply(store), ErrIllegalOption.Error())
+	require.NoError(OptParallelism(4).Apply(store))
+	require.Equal(4, store.parallelism)
+}
+
+func TestOptMetrics(t *testing.T) {
+	require := require.New(t)
+	store := &Store{metrics: nopMetrics{}}
+	require.NoError(OptMetrics(nil).Apply(store))
+	require.Equal(nopMetrics{}, store.metrics)
+	metrics := &testMetrics{}
+	require.NoError(OptMetrics(metrics).Apply(store))
+	require.Equal(metrics, store.metrics)
+}
+
+func TestOptRetryPolicy(t *testing.T) {
+	require := require.New(t)
+	store := &Store{}
+	require.NoError(OptRetryPolicy(RetryPolicy{}).Apply(store))
+	require.Nil(store.retryPolicy)
+	require.EqualError(OptRetryPolicy(RetryPolicy{InitialBackoff: time.Second}).Apply(store), ErrIllegalOption.Error())
+	require.EqualError(OptRetryPolicy(RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     -time.Second,
+		MaxElapsedTime: time.Second,
+	}).Apply(store), ErrIllegalOption.Error())
+	require.NoError(OptRetryPolicy(DefaultRetryPolicy).Apply(store))
+	require.Equal(DefaultRetryPolicy, *store.retryPolicy)
+}
+
+func TestOptChecksum(t *testing.T) {
+	require := require.New(t)
+	store := &Store{}
+	require.NoError(OptChecksum().Apply(store))
+	require.True(store.checksum)
+}
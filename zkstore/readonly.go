@@ -0,0 +1,28 @@
+package zkstore
+
+// writeMode controls whether the Store performs mutations.
+type writeMode int
+
+const (
+	// writeModeNormal performs mutations.
+	writeModeNormal writeMode = iota
+
+	// writeModeReadOnly rejects mutations with ErrReadOnly.
+	writeModeReadOnly
+
+	// writeModeDryRun logs mutations instead of performing them.
+	writeModeDryRun
+)
+
+// skipWrite returns true if the described mutation must not be performed, and
+// the error to return in that case.
+func (s *Store) skipWrite(format string, args ...interface{}) (bool, error) {
+	switch s.writeMode {
+	case writeModeReadOnly:
+		return true, ErrReadOnly
+	case writeModeDryRun:
+		s.logger.Infof("zkstore: dry run: would "+format, args...)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,44 @@
+package zkstore
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/dcos/dcos-go/dcoslog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnly(t *testing.T) {
+	require := require.New(t)
+	store := &Store{logger: dcoslog.Nop(), metrics: nopMetrics{}, maxDataSize: MaxDataSize}
+	require.NoError(OptReadOnly().Apply(store))
+
+	// the store has no connection, so any attempt to reach ZK would panic
+	ident := Ident{Location: Location{Category: "widgets", Name: "w"}}
+	_, err := store.Put(Item{Ident: ident, Data: []byte("data")})
+	require.Equal(ErrReadOnly, err)
+	require.Equal(ErrReadOnly, store.Delete(ident))
+
+	// invalid items are still rejected as such
+	_, err = store.Put(Item{Data: []byte("data")})
+	require.Error(err)
+	require.NotEqual(ErrReadOnly, err)
+}
+
+func TestDryRun(t *testing.T) {
+	require := require.New(t)
+	var buf bytes.Buffer
+	store := &Store{metrics: nopMetrics{}, maxDataSize: MaxDataSize}
+	require.NoError(OptLogger(dcoslog.NewStdLogger(log.New(&buf, "", 0), false)).Apply(store))
+	require.NoError(OptDryRun().Apply(store))
+
+	ident := Ident{Location: Location{Category: "widgets", Name: "w"}, Version: NewVersion(3)}
+	returned, err := store.Put(Item{Ident: ident, Data: []byte("data")})
+	require.NoError(err)
+	require.Equal(ident, returned)
+	require.NoError(store.Delete(ident))
+
+	require.Contains(buf.String(), "dry run: would put {ident={loc=")
+	require.Contains(buf.String(), "dry run: would delete {loc=")
+}
@@ -0,0 +1,375 @@
+package zkstore
+
+import (
+	"bytes"
+	"path"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// rebalanceMaxAttempts is the number of times Rebalance tries to move an item
+// that is being modified concurrently.
+const rebalanceMaxAttempts = 5
+
+// Rebalance moves all items of the category, along with their variants and
+// chunks, into the buckets they belong to when using newBuckets hash
+// buckets. Once it returns, the category must be accessed by Stores
+// configured with OptNumHashBuckets(newBuckets). Rebalance does not change
+// the bucket count of this Store.
+//
+// Items are moved with a single multi request unless they are too large for
+// one, in which case they are copied to their new bucket before they are
+// deleted from their old one. Either way, the deletion fails if the item was
+// modified in the meantime, in which case the move is retried. While
+// Rebalance runs, Stores using either bucket count may therefore not find
+// some of the items, but no data is lost. An identical copy left behind by an
+// interrupted Rebalance counts as moved. A verification pass checks that all
+// items ended up in their new buckets.
+func (s *Store) Rebalance(category string, newBuckets int) error {
+	if err := ValidateCategory(category); err != nil {
+		return errors.Wrap(err, "invalid category")
+	}
+	if newBuckets <= 0 {
+		return errors.Errorf("invalid bucket count %d", newBuckets)
+	}
+	if s.writeMode == writeModeReadOnly {
+		return ErrReadOnly
+	}
+	bucketsPath, err := s.bucketsPath(category)
+	if err != nil {
+		return err
+	}
+	newBucketFunc := bucketFunc(newBuckets, s.hashProviderFunc)
+	buckets, _, err := s.conn.Children(bucketsPath)
+	switch {
+	case err == zk.ErrNoNode:
+		return ErrNotFound
+	case err != nil:
+		return err
+	}
+	moved := 0
+	for _, bucket := range buckets {
+		names, _, err := s.conn.Children(path.Join(bucketsPath, bucket))
+		switch {
+		case err == zk.ErrNoNode:
+			continue
+		case err != nil:
+			return err
+		}
+		for _, name := range names {
+			newBucket, err := newBucketFunc(name)
+			if err != nil {
+				return err
+			}
+			if strconv.Itoa(newBucket) == bucket {
+				continue
+			}
+			location := Location{Category: category, Name: name}
+			if skip, err := s.skipWrite("move %v from bucket %v to %d", location, bucket, newBucket); skip {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := s.moveItem(location, bucket, strconv.Itoa(newBucket)); err != nil {
+				return errors.Wrapf(err, "could not move %v", location)
+			}
+			moved++
+		}
+	}
+	s.logger.Infof("zkstore: moved %d item(s) of %v into %d buckets", moved, category, newBuckets)
+	if s.writeMode == writeModeDryRun {
+		return nil
+	}
+	s.removeStaleBuckets(category, newBuckets)
+	return s.verifyBuckets(category, newBucketFunc)
+}
+
+// rebalanceNode is a snapshot of an item or variant znode.
+type rebalanceNode struct {
+	name    string // empty for the item itself
+	data    []byte
+	version int32
+}
+
+// rebalanceMultiSize is the maximum size of the data of an item and its
+// variants for it to be moved with a single multi request. Like chunkSize, it
+// leaves ample room for the rest of the request below the default ZK
+// jute.maxbuffer.
+const rebalanceMultiSize = chunkSize
+
+// moveItem moves the item at the location from one bucket to the other.
+func (s *Store) moveItem(location Location, from, to string) error {
+	bucketsPath, err := s.bucketsPath(location.Category)
+	if err != nil {
+		return err
+	}
+	oldPath := path.Join(bucketsPath, from, location.Name)
+	newPath := path.Join(bucketsPath, to, location.Name)
+	chunksPath := path.Join(path.Dir(bucketsPath), ChunksZnodeName)
+	oldChunks := path.Join(chunksPath, from, location.Name)
+	newChunks := path.Join(chunksPath, to, location.Name)
+
+	for attempt := 1; ; attempt++ {
+		nodes, err := s.snapshotItem(oldPath)
+		switch {
+		case err == zk.ErrNoNode:
+			// deleted in the meantime, nothing to move.
+			return nil
+		case err != nil:
+			return err
+		}
+		// a previous Rebalance may have been interrupted after copying the
+		// item, in which case only the originals are left to delete.
+		copied := false
+		existing, err := s.snapshotItem(newPath)
+		switch {
+		case err == nil && sameNodes(nodes, existing):
+			copied = true
+		case err == nil:
+			return errors.Errorf("%v already exists", newPath)
+		case err != zk.ErrNoNode:
+			return err
+		}
+		// chunks are copied first, so that the moved item never points at
+		// chunks that are missing.
+		createdChunks, err := s.copyTree(oldChunks, newChunks)
+		createdItem := false
+		undo := func() {
+			if createdItem {
+				if err := s.deleteTree(newPath); err != nil {
+					s.logger.Warnf("zkstore: could not remove partial copy %v: %v", newPath, err)
+				}
+			}
+			// only the chunks created by this attempt are removed, chunks
+			// that existed before may be referenced by the existing copy.
+			for i := len(createdChunks) - 1; i >= 0; i-- {
+				if err := s.conn.Delete(createdChunks[i], -1); err != nil && err != zk.ErrNoNode {
+					s.logger.Warnf("zkstore: could not remove copied chunk %v: %v", createdChunks[i], err)
+				}
+			}
+		}
+		if err == nil {
+			err = s.createPath(path.Dir(newPath))
+		}
+		if err != nil {
+			undo()
+			return err
+		}
+
+		// the originals are deleted atomically, variants before their item,
+		// failing if any of them was modified since it was read.
+		var ops []interface{}
+		if !copied && nodesSize(nodes) <= rebalanceMultiSize {
+			// the copies are created by the same request, so that the item
+			// is never found in both buckets.
+			for _, n := range nodes {
+				ops = append(ops, &zk.CreateRequest{Path: path.Join(newPath, n.name), Data: n.data, Acl: s.acls})
+			}
+		} else if !copied {
+			// the copies are created one by one, since a multi request
+			// cannot exceed the size of a single znode.
+			createdItem, err = s.createNodes(newPath, nodes)
+			if err != nil {
+				undo()
+				if err == zk.ErrNodeExists && attempt < rebalanceMaxAttempts {
+					// created concurrently, the copy is compared with the
+					// original on the next attempt.
+					continue
+				}
+				return err
+			}
+		}
+		for i := len(nodes) - 1; i >= 0; i-- {
+			ops = append(ops, &zk.DeleteRequest{Path: path.Join(oldPath, nodes[i].name), Version: nodes[i].version})
+		}
+		err = multiError(s.conn.Multi(ops...))
+		switch {
+		case err == nil:
+			s.removeChunks(oldChunks)
+			return nil
+		case attempt < rebalanceMaxAttempts && (err == zk.ErrBadVersion || err == zk.ErrNoNode || err == zk.ErrNotEmpty || err == zk.ErrNodeExists):
+			s.logger.Debugf("zkstore: %v changed while moving it, retrying", location)
+			undo()
+		default:
+			undo()
+			return err
+		}
+	}
+}
+
+// createNodes creates the item znode at p along with its variants from the
+// snapshot. created reports whether the item znode was created, so that it
+// can be removed again if creating a variant fails.
+func (s *Store) createNodes(p string, nodes []rebalanceNode) (created bool, err error) {
+	for _, n := range nodes {
+		if _, err := s.conn.Create(path.Join(p, n.name), n.data, 0, s.acls); err != nil {
+			return created, err
+		}
+		created = true
+	}
+	return created, nil
+}
+
+// snapshotItem reads the item znode at p along with its variants.
+func (s *Store) snapshotItem(p string) ([]rebalanceNode, error) {
+	data, stat, err := s.conn.Get(p)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []rebalanceNode{{data: data, version: stat.Version}}
+	variants, _, err := s.conn.Children(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		data, stat, err := s.conn.Get(path.Join(p, v))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, rebalanceNode{name: v, data: data, version: stat.Version})
+	}
+	return nodes, nil
+}
+
+// sameNodes returns true if both snapshots hold the same znodes with the same
+// data, regardless of their versions.
+func sameNodes(a, b []rebalanceNode) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	data := make(map[string][]byte, len(a))
+	for _, n := range a {
+		data[n.name] = n.data
+	}
+	for _, n := range b {
+		d, ok := data[n.name]
+		if !ok || !bytes.Equal(d, n.data) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodesSize returns the total size of the data of the snapshot.
+func nodesSize(nodes []rebalanceNode) int {
+	size := 0
+	for _, n := range nodes {
+		size += len(n.data)
+	}
+	return size
+}
+
+// copyTree copies the znode at from and all of its descendants to to. Znodes
+// that already exist at the destination are left alone. It returns the paths
+// of the znodes it created, parents first. It is not an error if from does
+// not exist.
+func (s *Store) copyTree(from, to string) ([]string, error) {
+	data, _, err := s.conn.Get(from)
+	switch {
+	case err == zk.ErrNoNode:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := s.createPath(path.Dir(to)); err != nil {
+		return nil, err
+	}
+	var created []string
+	_, err = s.conn.Create(to, data, 0, s.acls)
+	switch {
+	case err == nil:
+		created = append(created, to)
+	case err != zk.ErrNodeExists:
+		return nil, err
+	}
+	children, _, err := s.conn.Children(from)
+	if err != nil && err != zk.ErrNoNode {
+		return created, err
+	}
+	for _, child := range children {
+		c, err := s.copyTree(path.Join(from, child), path.Join(to, child))
+		created = append(created, c...)
+		if err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// removeStaleBuckets deletes the buckets that are not used with newBuckets
+// buckets, if they are empty.
+func (s *Store) removeStaleBuckets(category string, newBuckets int) {
+	bucketsPath, err := s.bucketsPath(category)
+	if err != nil {
+		return
+	}
+	chunksPath := path.Join(path.Dir(bucketsPath), ChunksZnodeName)
+	for _, parent := range []string{bucketsPath, chunksPath} {
+		buckets, _, err := s.conn.Children(parent)
+		if err != nil {
+			continue
+		}
+		for _, bucket := range buckets {
+			if n, err := strconv.Atoi(bucket); err == nil && n < newBuckets {
+				continue
+			}
+			// fails if someone wrote to it using the old bucket count.
+			if err := s.conn.Delete(path.Join(parent, bucket), -1); err != nil && err != zk.ErrNoNode {
+				s.logger.Warnf("zkstore: could not remove bucket %v: %v", path.Join(parent, bucket), err)
+			}
+		}
+	}
+}
+
+// verifyBuckets checks that all items of the category are in the bucket
+// assigned to them by the bucket func.
+func (s *Store) verifyBuckets(category string, bucketFunc func(string) (int, error)) error {
+	bucketsPath, err := s.bucketsPath(category)
+	if err != nil {
+		return err
+	}
+	buckets, _, err := s.conn.Children(bucketsPath)
+	if err != nil {
+		return err
+	}
+	var misplaced []string
+	for _, bucket := range buckets {
+		names, _, err := s.conn.Children(path.Join(bucketsPath, bucket))
+		switch {
+		case err == zk.ErrNoNode:
+			continue
+		case err != nil:
+			return err
+		}
+		for _, name := range names {
+			n, err := bucketFunc(name)
+			if err != nil {
+				return err
+			}
+			if strconv.Itoa(n) != bucket {
+				misplaced = append(misplaced, path.Join(bucket, name))
+			}
+		}
+	}
+	if len(misplaced) > 0 {
+		return errors.Errorf("%d item(s) of %v are not in their new bucket: %v", len(misplaced), category, misplaced)
+	}
+	return nil
+}
+
+// multiError returns the error of a multi request, which is either the error
+// of the request itself or of the first operation that failed.
+func multiError(responses []zk.MultiResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, res := range responses {
+		if res.Error != nil {
+			return res.Error
+		}
+	}
+	return nil
+}
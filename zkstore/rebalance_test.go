@@ -0,0 +1,29 @@
+package zkstore
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiError(t *testing.T) {
+	require := require.New(t)
+	require.NoError(multiError([]zk.MultiResponse{{}, {}}, nil))
+	require.Equal(zk.ErrBadVersion, multiError([]zk.MultiResponse{{}, {Error: zk.ErrBadVersion}, {Error: zk.ErrAPIError}}, nil))
+	errBoom := errors.New("boom")
+	require.Equal(errBoom, multiError(nil, errBoom))
+}
+
+func TestSameNodes(t *testing.T) {
+	require := require.New(t)
+	a := []rebalanceNode{{data: []byte("item"), version: 1}, {name: "v1", data: []byte("variant"), version: 2}}
+	b := []rebalanceNode{{name: "v1", data: []byte("variant")}, {data: []byte("item")}}
+	require.True(sameNodes(a, b))
+	require.Equal(11, nodesSize(a))
+	require.False(sameNodes(a, b[:1]))
+	b[0].data = []byte("changed")
+	require.False(sameNodes(a, b))
+	require.False(sameNodes(a, []rebalanceNode{{data: []byte("item")}, {name: "v2", data: []byte("variant")}}))
+}
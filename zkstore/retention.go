@@ -0,0 +1,96 @@
+package zkstore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// PruneVariants deletes all but the keep most recently modified variants of
+// the item at the given location. The item itself is never deleted.
+// Returns ErrNotFound if no item exists at the given location.
+func (s *Store) PruneVariants(location Location, keep int) error {
+	if keep < 0 {
+		return errors.Errorf("cannot keep %d variants", keep)
+	}
+	return s.pruneVariants(location, keep, 0)
+}
+
+// pruneAfterPut applies the configured variant retention after a variant of
+// the item at the location was written. stat is the stat of the written
+// variant; its modification time serves as the current time so that variant
+// ages are measured against the ZK clock only.
+func (s *Store) pruneAfterPut(location Location, stat *zk.Stat) {
+	keep, cutoff := s.variantRetention, int64(0)
+	if keep == 0 {
+		keep = -1
+	}
+	if s.variantTTL > 0 {
+		cutoff = stat.Mtime - int64(s.variantTTL/time.Millisecond)
+	}
+	if err := s.pruneVariants(location, keep, cutoff); err != nil {
+		s.logger.Warnf("zkstore: could not prune variants of %v: %v", location, err)
+	}
+}
+
+// pruneVariants deletes the variants of the item at the location that are
+// not among the keep most recently modified ones, or that were last modified
+// before cutoff (in ms since the epoch). A negative keep or a zero cutoff
+// disables the respective check.
+func (s *Store) pruneVariants(location Location, keep int, cutoff int64) error {
+	variants, err := s.Variants(location)
+	if err != nil {
+		return err
+	}
+	stats := make([]variantStat, 0, len(variants))
+	for _, v := range variants {
+		identPath, err := s.identPath(Ident{Location: location, Variant: v})
+		if err != nil {
+			return err
+		}
+		exists, stat, err := s.conn.Exists(identPath)
+		switch {
+		case err != nil:
+			return err
+		case !exists:
+			// someone else deleted it already.
+			continue
+		}
+		stats = append(stats, variantStat{name: v, mzxid: stat.Mzxid, mtime: stat.Mtime})
+	}
+	for _, v := range variantsToPrune(stats, keep, cutoff) {
+		if skip, err := s.skipWrite("prune variant %v of %v", v, location); skip {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		s.logger.Debugf("zkstore: pruning variant %v of %v", v, location)
+		if err := s.deleteVariant(Ident{Location: location, Variant: v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type variantStat struct {
+	name  string
+	mzxid int64 // orders modifications
+	mtime int64 // ms since the epoch
+}
+
+// variantsToPrune returns the names of the variants that are to be deleted,
+// see pruneVariants.
+func variantsToPrune(stats []variantStat, keep int, cutoff int64) (prune []string) {
+	sort.Slice(stats, func(i, j int) bool { return stats[i].mzxid > stats[j].mzxid })
+	for i, v := range stats {
+		tooMany := keep >= 0 && i >= keep
+		tooOld := cutoff > 0 && v.mtime < cutoff
+		if tooMany || tooOld {
+			prune = append(prune, v.name)
+		}
+	}
+	return prune
+}
@@ -0,0 +1,25 @@
+package zkstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantsToPrune(t *testing.T) {
+	require := require.New(t)
+	stats := func() []variantStat {
+		return []variantStat{
+			{name: "b", mzxid: 2, mtime: 200},
+			{name: "d", mzxid: 4, mtime: 400},
+			{name: "a", mzxid: 1, mtime: 100},
+			{name: "c", mzxid: 3, mtime: 300},
+		}
+	}
+	require.Equal([]string{"b", "a"}, variantsToPrune(stats(), 2, 0))
+	require.Equal([]string{"d", "c", "b", "a"}, variantsToPrune(stats(), 0, 0))
+	require.Empty(variantsToPrune(stats(), 10, 0))
+	require.Empty(variantsToPrune(stats(), -1, 0))
+	require.Equal([]string{"b", "a"}, variantsToPrune(stats(), -1, 250))
+	require.Equal([]string{"c", "b", "a"}, variantsToPrune(stats(), 3, 350))
+}
@@ -0,0 +1,110 @@
+package zkstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// RetryPolicy configures how Store operations retry transient ZK errors, i.e.
+// a closed connection or an expired session. Retries are configured with
+// OptRetryPolicy; without it, operations fail on the first error.
+//
+// Note that a mutating operation may have been applied even though it failed
+// with a transient error. Replaying a Put or Delete with a Version, including
+// NoPriorVersion, could then fail with ErrVersionConflict or overwrite a
+// concurrent change, so those are never retried.
+type RetryPolicy struct {
+	// InitialBackoff is the delay before the first retry. It doubles with
+	// every subsequent retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between retries. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// MaxElapsedTime is the time after which an operation is no longer
+	// retried.
+	MaxElapsedTime time.Duration
+}
+
+// DefaultRetryPolicy is a reasonable RetryPolicy for most clients.
+var DefaultRetryPolicy = RetryPolicy{
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	MaxElapsedTime: 30 * time.Second,
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// ErrRetriesExhausted is returned when an operation still failed with a
+// transient error after retrying for the MaxElapsedTime of the RetryPolicy.
+type ErrRetriesExhausted struct {
+	// Attempts is the number of times the operation was attempted.
+	Attempts int
+
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e ErrRetriesExhausted) Error() string {
+	return fmt.Sprintf("retries exhausted after %d attempts: %v", e.Attempts, e.Err)
+}
+
+// Cause returns the error of the last attempt, see github.com/pkg/errors.
+func (e ErrRetriesExhausted) Cause() error { return e.Err }
+
+// isTransient returns whether the error may go away by retrying.
+func isTransient(err error) bool {
+	switch errors.Cause(err) {
+	case zk.ErrConnectionClosed, zk.ErrSessionExpired:
+		return true
+	}
+	return false
+}
+
+// retry invokes fn until it returns a non-transient error or the retry
+// policy is exhausted. fn must be safe to invoke several times.
+func (s *Store) retry(op string, fn func() error) error {
+	if s.retryPolicy == nil {
+		return fn()
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !isTransient(err) {
+			return err
+		}
+		backoff := s.retryPolicy.backoff(attempt)
+		if time.Since(start)+backoff > s.retryPolicy.MaxElapsedTime {
+			return ErrRetriesExhausted{Attempts: attempt, Err: err}
+		}
+		s.logger.Debugf("zkstore: %s failed: %v, retrying in %v", op, err, backoff)
+		s.metrics.Retry(op)
+		time.Sleep(backoff)
+	}
+}
+
+// retryWrite is retry for mutating operations. Only writes without a version
+// are retried, since replaying them has the same effect as applying them
+// once.
+func (s *Store) retryWrite(op string, versioned bool, fn func() error) error {
+	if versioned {
+		return fn()
+	}
+	return s.retry(op, fn)
+}
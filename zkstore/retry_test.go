@@ -0,0 +1,95 @@
+package zkstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-go/dcoslog"
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	require := require.New(t)
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	require.Equal(time.Second, p.backoff(1))
+	require.Equal(2*time.Second, p.backoff(2))
+	require.Equal(4*time.Second, p.backoff(3))
+	require.Equal(5*time.Second, p.backoff(4))
+	require.Equal(5*time.Second, p.backoff(100))
+
+	p.MaxBackoff = 0
+	require.Equal(8*time.Second, p.backoff(4))
+}
+
+func TestRetry(t *testing.T) {
+	require := require.New(t)
+	metrics := &testMetrics{}
+	store := &Store{logger: dcoslog.Nop(), metrics: metrics}
+
+	// without a policy, errors are returned right away
+	attempts := 0
+	err := store.retry("get", func() error { attempts++; return zk.ErrConnectionClosed })
+	require.Equal(zk.ErrConnectionClosed, err)
+	require.Equal(1, attempts)
+
+	require.NoError(OptRetryPolicy(RetryPolicy{
+		InitialBackoff: time.Millisecond,
+		MaxElapsedTime: time.Second,
+	}).Apply(store))
+
+	// transient errors are retried
+	attempts = 0
+	err = store.retry("get", func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.Wrap(zk.ErrSessionExpired, "oops")
+		}
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(3, attempts)
+	require.Equal([]string{"get", "get"}, metrics.retries)
+
+	// others are not
+	attempts = 0
+	err = store.retry("get", func() error { attempts++; return zk.ErrNoNode })
+	require.Equal(zk.ErrNoNode, err)
+	require.Equal(1, attempts)
+
+	// until the policy is exhausted
+	store.retryPolicy.MaxElapsedTime = 10 * time.Millisecond
+	err = store.retry("get", func() error { return zk.ErrConnectionClosed })
+	exhausted, ok := err.(ErrRetriesExhausted)
+	require.True(ok, "unexpected error %v", err)
+	require.True(exhausted.Attempts > 1)
+	require.Equal(zk.ErrConnectionClosed, errors.Cause(err))
+}
+
+func TestRetryWrite(t *testing.T) {
+	require := require.New(t)
+	store := &Store{logger: dcoslog.Nop(), metrics: &testMetrics{}}
+	require.NoError(OptRetryPolicy(RetryPolicy{
+		InitialBackoff: time.Millisecond,
+		MaxElapsedTime: time.Second,
+	}).Apply(store))
+
+	// versioned writes may have been applied, they are not replayed
+	attempts := 0
+	err := store.retryWrite("put", true, func() error { attempts++; return zk.ErrConnectionClosed })
+	require.Equal(zk.ErrConnectionClosed, err)
+	require.Equal(1, attempts)
+
+	// others are
+	attempts = 0
+	err = store.retryWrite("put", false, func() error {
+		attempts++
+		if attempts < 2 {
+			return zk.ErrConnectionClosed
+		}
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(2, attempts)
+}
@@ -4,7 +4,9 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/dcos/dcos-go/dcoslog"
 	"github.com/pkg/errors"
 	"github.com/samuel/go-zookeeper/zk"
 )
@@ -20,6 +22,16 @@ type Store struct {
 	hashProviderFunc HashProviderFunc          // configures bucketFunc
 	hashBuckets      int                       // configures bucketFunc
 	closeFunc        func() error              // closes zk resources
+	logger           dcoslog.Logger            // receives diagnostic messages
+	codecs           []Codec                   // encode and decode item data
+	maxDataSize      int                       // larger items are rejected
+	variantRetention int                       // number of variants kept on Put
+	variantTTL       time.Duration             // age of variants kept on Put
+	parallelism      int                       // concurrent requests of bulk operations
+	metrics          Metrics                   // receives operation measurements
+	retryPolicy      *RetryPolicy              // retries transient errors if set
+	writeMode        writeMode                 // whether to perform mutations
+	checksum         bool                      // store checksums with item data
 }
 
 const (
@@ -59,6 +71,10 @@ func NewStore(connector Connector, opts ...StoreOpt) (*Store, error) {
 		// MUST match what's passed to bucketFunc() above
 		hashBuckets:      DefaultNumHashBuckets,
 		hashProviderFunc: DefaultHashProviderFunc,
+		logger:           dcoslog.Nop(),
+		maxDataSize:      MaxDataSize,
+		parallelism:      DefaultParallelism,
+		metrics:          nopMetrics{},
 	}
 	for _, opt := range opts {
 		if err := opt.Apply(store); err != nil {
@@ -79,11 +95,53 @@ func NewStore(connector Connector, opts ...StoreOpt) (*Store, error) {
 // Returns ErrVersionConflict if there is a Version mismatch between the item given
 // and the version of the data currently stored. This check is not performed
 // if there is no Version set for the given item.
+//
+// Returns ErrReadOnly if the Store was configured with OptReadOnly. If it was
+// configured with OptDryRun, the item is only logged and the given Ident is
+// returned as is.
+//
+// Items larger than MaxDataSize are split into chunks if the Store was
+// configured with OptChunking. Writing a variant prunes older variants if
+// the Store was configured with OptVariantRetention or OptVariantTTL.
 func (s *Store) Put(item Item) (Ident, error) {
+	start, size := time.Now(), len(item.Data)
 	err := func() error {
-		if err := item.Validate(); err != nil {
+		if err := s.encodeItem(&item); err != nil {
+			return err
+		}
+		if skip, err := s.skipWrite("put %v", item); skip {
 			return err
 		}
+		var stat *zk.Stat
+		_, versioned := item.Ident.Version.Value()
+		err := s.retryWrite("put", versioned, func() (err error) {
+			if len(item.Data) > MaxDataSize {
+				stat, err = s.putChunked(item)
+			} else {
+				stat, err = s.put(item)
+			}
+			return
+		})
+		if err != nil {
+			return err
+		}
+		item.Ident.Version = NewVersion(stat.Version)
+		if item.Variant != "" && (s.variantRetention > 0 || s.variantTTL > 0) {
+			s.pruneAfterPut(item.Location, stat)
+		}
+		if s.maxDataSize > MaxDataSize {
+			// previously written chunks may have been replaced.
+			s.collectChunks(item.Location, stat)
+		}
+		return nil
+	}()
+	s.observe("put", start, size, err)
+	return item.Ident, err
+}
+
+// put writes the item data to the item znode as is.
+func (s *Store) put(item Item) (stat *zk.Stat, err error) {
+	err = func() error {
 		identPath, err := s.identPath(item.Ident)
 		if err != nil {
 			return err
@@ -100,22 +158,19 @@ func (s *Store) Put(item Item) (Ident, error) {
 				return ErrVersionConflict
 			}
 			// The node does not exist yet, so we create it.
-			stat, err := s.setFully(item)
-			if err != nil {
-				return err
-			}
-			item.Ident.Version = NewVersion(stat.Version)
-			return nil
+			stat, err = s.setFully(item)
+			return err
 		}
 		// We aren't explicitly creating a new item (although we may
 		// end up doing so if it doesn't already exist.) We try and set
 		// the item in the database in case it already exists. If it
 		// doesn't exist yet we respond to the zk.ErrNoNode error by
 		// creating it along with its ancestors.
-		stat, err := s.conn.Set(identPath, item.Data, item.Ident.actualVersion())
+		stat, err = s.conn.Set(identPath, item.Data, item.Ident.actualVersion())
 		switch {
 		case err == zk.ErrNoNode:
 			// it didn't exist, so take the more expensive path
+			s.logger.Debugf("zkstore: %v does not exist, creating it", identPath)
 			if stat, err = s.setFully(item); err != nil {
 				return err
 			}
@@ -126,10 +181,9 @@ func (s *Store) Put(item Item) (Ident, error) {
 		case stat == nil:
 			return errors.Errorf("could not stat %v", identPath)
 		}
-		item.Ident.Version = NewVersion(stat.Version)
 		return nil
 	}()
-	return item.Ident, err
+	return
 }
 
 // NoPriorVersion tells Put that we're expecting to create a new znode for a particular item,
@@ -170,6 +224,7 @@ func (s *Store) setFully(item Item) (stat *zk.Stat, err error) {
 				return ErrVersionConflict
 			}
 			// this node does not exist. try to create it.
+			s.logger.Debugf("zkstore: creating znode %v", current)
 			var nodeData []byte
 
 			// if the item has a variant, and its parent node does
@@ -192,9 +247,11 @@ func (s *Store) setFully(item Item) (stat *zk.Stat, err error) {
 
 // Get fetches the data for a particuar item. If a particluar version is
 // desired, it must be set on the ident.
-// Returns ErrNotFound if no such item exists.
+// Returns ErrNotFound if no such item exists, and ErrChecksumMismatch if the
+// item was written with a checksum that its data does not match.
 func (s *Store) Get(ident Ident) (item Item, err error) {
-	err = func() error {
+	defer func(start time.Time) { s.observe("get", start, len(item.Data), err) }(time.Now())
+	err = s.retry("get", func() error {
 		if err := ident.Validate(); err != nil {
 			return err
 		}
@@ -209,55 +266,109 @@ func (s *Store) Get(ident Ident) (item Item, err error) {
 		case err != nil:
 			return err
 		}
+		if data, err = s.readChunks(ident.Location, data); err != nil {
+			return errors.Wrapf(err, "could not read %v", ident)
+		}
+		if data, err = verifyChecksum(data); err != nil {
+			return err
+		}
+		if data, err = s.decode(data); err != nil {
+			return errors.Wrapf(err, "could not decode %v", ident)
+		}
 		item.Ident = ident
 		item.Data = data
 		item.Ident.Version = NewVersion(stat.Version)
+		item.Meta = newMetadata(stat)
 		return nil
-	}()
+	})
 	return
 }
 
-// Variants fetches all of the variants for a particular item.
-// Returns ErrNotFound if no item exists at the given location.
-func (s *Store) Variants(location Location) (variants []string, err error) {
-	err = func() error {
-		if err := location.Validate(); err != nil {
-			return err
-		}
-		// create an ident in order to get the full path for the item node
-		ident := Ident{Location: location}
-		identPath, err := s.identPath(ident)
+// encodeItem validates the item and replaces its data with the data that is
+// to be written to ZK. With a codec configured the size limit applies to the
+// encoded data, so compressed items may exceed MaxDataSize before encoding.
+func (s *Store) encodeItem(item *Item) error {
+	if len(s.codecs) == 0 && s.maxDataSize == MaxDataSize && !s.checksum {
+		return item.Validate()
+	}
+	if err := item.Ident.Validate(); err != nil {
+		return err
+	}
+	if len(s.codecs) > 0 {
+		data, err := encodeData(s.codecs[0], item.Data)
 		if err != nil {
 			return err
 		}
-		variants, _, err = s.conn.Children(identPath)
-		switch {
-		case err == zk.ErrNoNode:
-			return ErrNotFound
-		case err != nil:
-			return err
-		}
-		return nil
-	}()
+		item.Data = data
+	}
+	if s.checksum {
+		item.Data = addChecksum(item.Data)
+	}
+	if len(item.Data) > s.maxDataSize {
+		return errors.Errorf("data is greater than %dB", s.maxDataSize)
+	}
+	return nil
+}
+
+// decode reverses the encoding applied by encodeItem.
+func (s *Store) decode(data []byte) ([]byte, error) {
+	return decodeData(s.codecs, data)
+}
+
+// Variants fetches all of the variants for a particular item.
+// Returns ErrNotFound if no item exists at the given location.
+func (s *Store) Variants(location Location) (variants []string, err error) {
+	err = s.retry("variants", func() (err error) {
+		variants, err = s.variants(location)
+		return
+	})
 	return
 }
 
+// variants is Variants without retries.
+func (s *Store) variants(location Location) ([]string, error) {
+	if err := location.Validate(); err != nil {
+		return nil, err
+	}
+	// create an ident in order to get the full path for the item node
+	ident := Ident{Location: location}
+	identPath, err := s.identPath(ident)
+	if err != nil {
+		return nil, err
+	}
+	variants, _, err := s.conn.Children(identPath)
+	switch {
+	case err == zk.ErrNoNode:
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	}
+	return variants, nil
+}
+
 // Delete deletes the identified item.
 // An error is NOT returned in the case where the item does not already exist in the store.
 func (s *Store) Delete(ident Ident) (err error) {
+	defer func(start time.Time) { s.observe("delete", start, 0, err) }(time.Now())
 	if err = ident.Validate(); err != nil {
 		return
 	}
-	if ident.Variant != "" {
-		return s.deleteVariant(ident)
+	if skip, err := s.skipWrite("delete %v", ident); skip {
+		return err
 	}
-	return s.deleteItem(ident)
+	_, versioned := ident.Version.Value()
+	return s.retryWrite("delete", versioned, func() error {
+		if ident.Variant != "" {
+			return s.deleteVariant(ident)
+		}
+		return s.deleteItem(ident)
+	})
 }
 
 // deleteItem deletes the item and all versions within it
 func (s *Store) deleteItem(ident Ident) (err error) {
 	var variants []string
-	variants, err = s.Variants(ident.Location)
+	variants, err = s.variants(ident.Location)
 	switch {
 	case err == ErrNotFound:
 		return nil
@@ -265,6 +376,7 @@ func (s *Store) deleteItem(ident Ident) (err error) {
 		return
 	}
 	// delete each variant of the specified item
+	s.logger.Debugf("zkstore: deleting %v and its %d variant(s)", ident.Location, len(variants))
 	for _, v := range variants {
 		variant := ident
 		variant.Variant = v
@@ -280,12 +392,18 @@ func (s *Store) deleteItem(ident Ident) (err error) {
 	}
 	err = s.conn.Delete(identPath, ident.actualVersion())
 	switch err {
-	case zk.ErrNoNode:
-		return nil
+	case nil, zk.ErrNoNode:
 	case zk.ErrBadVersion:
 		return ErrVersionConflict
+	default:
+		return
 	}
-	return
+	// and finally any chunks of the item and its variants
+	chunksPath, err := s.chunksPath(ident.Location)
+	if err != nil {
+		return
+	}
+	return s.deleteTree(chunksPath)
 }
 
 // deleteVariant deletes only an item variant
@@ -309,7 +427,9 @@ func (s *Store) deleteVariant(ident Ident) (err error) {
 // the specified category.
 // Returns ErrNotFound if the category cannot be found within the store.
 func (s *Store) List(category string) (locations []Location, err error) {
-	err = func() error {
+	defer func(start time.Time) { s.observe("list", start, 0, err) }(time.Now())
+	err = s.retry("list", func() error {
+		locations = nil
 		if err := ValidateCategory(category); err != nil {
 			return errors.Wrap(err, "invalid category")
 		}
@@ -333,6 +453,7 @@ func (s *Store) List(category string) (locations []Location, err error) {
 			switch {
 			case err == zk.ErrNoNode:
 				// someone else deleted it? keep going.
+				s.logger.Debugf("zkstore: bucket %v disappeared while listing %v", bucket, category)
 				continue
 			case err != nil:
 				return err
@@ -346,7 +467,7 @@ func (s *Store) List(category string) (locations []Location, err error) {
 			}
 		}
 		return nil
-	}()
+	})
 	return
 }
 
@@ -3,15 +3,22 @@
 package zkstore
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha512"
 	"fmt"
 	"io"
+	"path"
 	"sort"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/dcos/dcos-go/testutils"
+	"github.com/pkg/errors"
 	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/require"
 )
@@ -530,6 +537,516 @@ func TestIdentPath(t *testing.T) {
 	}
 }
 
+func TestWatch(t *testing.T) {
+	store, _, teardown := newStoreTest(t)
+	defer teardown()
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	location := Location{Category: "widgets", Name: "item1"}
+	events, err := store.Watch(ctx, location)
+	require.NoError(err)
+
+	expect := func(eventType EventType) {
+		select {
+		case event := <-events:
+			require.NoError(event.Err)
+			require.Equal(eventType, event.Type, "got event %v", event)
+			require.Equal(location, event.Location)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %v event", eventType)
+		}
+	}
+
+	_, err = store.Put(Item{Ident: Ident{Location: location}, Data: []byte("v1")})
+	require.NoError(err)
+	expect(EventCreated)
+
+	_, err = store.Put(Item{Ident: Ident{Location: location}, Data: []byte("v2")})
+	require.NoError(err)
+	expect(EventUpdated)
+
+	_, err = store.Put(Item{Ident: Ident{Location: location, Variant: "var1"}, Data: []byte("var1")})
+	require.NoError(err)
+	expect(EventVariantsChanged)
+
+	// deleting the item removes its variants first, which may or may not be
+	// reported before the deletion itself.
+	require.NoError(store.Delete(Ident{Location: location}))
+	select {
+	case event := <-events:
+		if event.Type == EventVariantsChanged {
+			expect(EventDeleted)
+			break
+		}
+		require.Equal(EventDeleted, event.Type, "got event %v", event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for deleted event")
+	}
+
+	cancel()
+	for range events {
+		// drain until the watcher closes the channel
+	}
+}
+
+func TestWatchCategory(t *testing.T) {
+	store, _, teardown := newStoreTest(t, OptNumHashBuckets(4))
+	defer teardown()
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := store.WatchCategory(ctx, "widgets")
+	require.NoError(err)
+
+	expect := func(eventType EventType, name string) {
+		select {
+		case event := <-events:
+			require.NoError(event.Err)
+			require.Equal(Event{Type: eventType, Location: Location{Category: "widgets", Name: name}}, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %v event for %v", eventType, name)
+		}
+	}
+
+	for _, name := range []string{"item1", "item2", "item3"} {
+		_, err = store.Put(Item{Ident: Ident{Location: Location{Category: "widgets", Name: name}}, Data: []byte(name)})
+		require.NoError(err)
+		expect(EventCreated, name)
+	}
+
+	// updating an item does not change the category
+	_, err = store.Put(Item{Ident: Ident{Location: Location{Category: "widgets", Name: "item1"}}, Data: []byte("x")})
+	require.NoError(err)
+
+	require.NoError(store.Delete(Ident{Location: Location{Category: "widgets", Name: "item2"}}))
+	expect(EventDeleted, "item2")
+
+	cancel()
+	for range events {
+		// drain until the watcher closes the channel
+	}
+}
+
+func TestCodec(t *testing.T) {
+	gz, err := GzipCodec(gzip.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, conn, teardown := newStoreTest(t, OptCodec(gz))
+	defer teardown()
+	require := require.New(t)
+
+	// larger than MaxDataSize, but compresses well below it
+	data := bytes.Repeat([]byte("widget"), MaxDataSize)
+	ident := Ident{Location: Location{Category: "widgets", Name: "big"}}
+	_, err = store.Put(Item{Ident: ident, Data: data})
+	require.NoError(err)
+
+	item, err := store.Get(ident)
+	require.NoError(err)
+	require.Equal(data, item.Data)
+
+	// the znode holds the compressed data
+	identPath, err := store.identPath(ident)
+	require.NoError(err)
+	raw, _, err := conn.Get(identPath)
+	require.NoError(err)
+	require.True(len(raw) < MaxDataSize)
+
+	// data written without a codec is still readable
+	legacy := Ident{Location: Location{Category: "widgets", Name: "legacy"}}
+	plainStore, err := NewStore(ExistingConnection(conn))
+	require.NoError(err)
+	_, err = plainStore.Put(Item{Ident: legacy, Data: []byte("plain")})
+	require.NoError(err)
+	item, err = store.Get(legacy)
+	require.NoError(err)
+	require.Equal("plain", string(item.Data))
+}
+
+func TestChunking(t *testing.T) {
+	store, conn, teardown := newStoreTest(t, OptChunking(4*MaxDataSize))
+	defer teardown()
+	require := require.New(t)
+
+	data := make([]byte, 3*MaxDataSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	ident := Ident{Location: Location{Category: "widgets", Name: "big"}}
+	_, err := store.Put(Item{Ident: ident, Data: data[:MaxDataSize+1]})
+	require.NoError(err)
+	_, err = store.Put(Item{Ident: Ident{Location: ident.Location, Variant: "v1"}, Data: data})
+	require.NoError(err)
+	item, err := store.Get(ident)
+	require.NoError(err)
+	require.Equal(data[:MaxDataSize+1], item.Data)
+	item, err = store.Get(Ident{Location: ident.Location, Variant: "v1"})
+	require.NoError(err)
+	require.Equal(data, item.Data)
+
+	// too large even for chunking
+	_, err = store.Put(Item{Ident: ident, Data: make([]byte, 4*MaxDataSize+1)})
+	require.Error(err)
+
+	// the chunks live outside of the item, so do not show up as variants
+	variants, err := store.Variants(ident.Location)
+	require.NoError(err)
+	require.Equal([]string{"v1"}, variants)
+
+	// replaced chunks are collected once they are older than the grace period
+	chunksPath, err := store.chunksPath(ident.Location)
+	require.NoError(err)
+	defer func(d time.Duration) { chunkGracePeriod = d }(chunkGracePeriod)
+	chunkGracePeriod = 0
+	_, err = store.Put(Item{Ident: ident, Data: []byte("small")})
+	require.NoError(err)
+	gens, _, err := conn.Children(chunksPath)
+	require.NoError(err)
+	require.Len(gens, 1, "expected only the chunks of the variant to remain")
+
+	// corrupted chunks are detected
+	_, err = conn.Set(path.Join(chunksPath, gens[0], "0"), []byte("garbage"), -1)
+	require.NoError(err)
+	_, err = store.Get(Ident{Location: ident.Location, Variant: "v1"})
+	require.Error(err)
+	require.Contains(err.Error(), ErrCorruptData.Error())
+
+	// deleting the item removes all of its chunks
+	require.NoError(store.Delete(ident))
+	exists, _, err := conn.Exists(chunksPath)
+	require.NoError(err)
+	require.False(exists)
+}
+
+func TestPruneVariants(t *testing.T) {
+	store, _, teardown := newStoreTest(t, OptVariantRetention(2))
+	defer teardown()
+	require := require.New(t)
+
+	location := Location{Category: "widgets", Name: "w"}
+	for _, v := range []string{"v1", "v2", "v3", "v4"} {
+		_, err := store.Put(Item{Ident: Ident{Location: location, Variant: v}, Data: []byte(v)})
+		require.NoError(err)
+	}
+	variants, err := store.Variants(location)
+	require.NoError(err)
+	sort.Strings(variants)
+	require.Equal([]string{"v3", "v4"}, variants)
+
+	// updating a variant makes it the most recent one
+	_, err = store.Put(Item{Ident: Ident{Location: location, Variant: "v3"}, Data: []byte("v3.1")})
+	require.NoError(err)
+	require.NoError(store.PruneVariants(location, 1))
+	variants, err = store.Variants(location)
+	require.NoError(err)
+	require.Equal([]string{"v3"}, variants)
+
+	// the item itself is kept
+	require.NoError(store.PruneVariants(location, 0))
+	variants, err = store.Variants(location)
+	require.NoError(err)
+	require.Empty(variants)
+	_, err = store.Get(Ident{Location: location})
+	require.NoError(err)
+
+	require.Equal(ErrNotFound, store.PruneVariants(Location{Category: "widgets", Name: "nope"}, 1))
+	require.Error(store.PruneVariants(location, -1))
+}
+
+func TestUpdate(t *testing.T) {
+	store, _, teardown := newStoreTest(t)
+	defer teardown()
+	require := require.New(t)
+
+	ident := Ident{Location: Location{Category: "counters", Name: "c"}}
+	increment := func(old []byte) ([]byte, error) {
+		n := 0
+		if old != nil {
+			var err error
+			if n, err = strconv.Atoi(string(old)); err != nil {
+				return nil, err
+			}
+		}
+		return []byte(strconv.Itoa(n + 1)), nil
+	}
+
+	const workers, increments = 4, 5
+	errs := make(chan error, workers*increments)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := 0; i < increments; i++ {
+				_, err := store.Update(ident, increment)
+				errs <- err
+			}
+		}()
+	}
+	for i := 0; i < workers*increments; i++ {
+		require.NoError(<-errs)
+	}
+	item, err := store.Get(ident)
+	require.NoError(err)
+	require.Equal(strconv.Itoa(workers*increments), string(item.Data))
+
+	// errors of the update func are returned as is, and nothing is written
+	errBoom := errors.New("boom")
+	_, err = store.Update(ident, func([]byte) ([]byte, error) { return nil, errBoom })
+	require.Equal(errBoom, err)
+	after, err := store.Get(ident)
+	require.NoError(err)
+	require.Equal(item, after)
+}
+
+func TestBulk(t *testing.T) {
+	store, _, teardown := newStoreTest(t, OptParallelism(4))
+	defer teardown()
+	require := require.New(t)
+
+	items := make([]Item, 50)
+	idents := make([]Ident, len(items))
+	for i := range items {
+		idents[i] = Ident{Location: Location{Category: "widgets", Name: fmt.Sprintf("w%d", i)}}
+		items[i] = Item{Ident: idents[i], Data: []byte(strconv.Itoa(i))}
+	}
+	for i, result := range store.PutAll(items) {
+		require.NoError(result.Err)
+		require.Equal(idents[i].Location, result.Item.Location)
+		require.Equal(NewVersion(0), result.Item.Version)
+	}
+
+	missing := Ident{Location: Location{Category: "widgets", Name: "missing"}}
+	results := store.GetAll(append(idents, missing))
+	for i, result := range results[:len(idents)] {
+		require.NoError(result.Err)
+		require.Equal(items[i].Data, result.Item.Data)
+	}
+	require.Equal(ErrNotFound, results[len(idents)].Err)
+	require.Equal(missing, results[len(idents)].Item.Ident)
+
+	for _, result := range store.DeleteAll(idents) {
+		require.NoError(result.Err)
+	}
+	locations, err := store.List("widgets")
+	require.NoError(err)
+	require.Empty(locations)
+}
+
+func TestMutex(t *testing.T) {
+	store, _, teardown := newStoreTest(t)
+	defer teardown()
+	require := require.New(t)
+
+	_, err := store.Mutex("../nope")
+	require.Error(err)
+
+	m1, err := store.Mutex("m")
+	require.NoError(err)
+	m2, err := store.Mutex("m")
+	require.NoError(err)
+	require.Equal(ErrNotHeld, m1.Unlock())
+
+	require.NoError(m1.Lock(context.Background()))
+	require.Error(m1.Lock(context.Background()), "expected mutexes not to be reentrant")
+
+	// m2 cannot acquire the lock while m1 holds it
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.Equal(context.DeadlineExceeded, m2.Lock(ctx))
+
+	locked := make(chan error)
+	go func() { locked <- m2.Lock(context.Background()) }()
+	select {
+	case <-locked:
+		t.Fatal("m2 acquired the lock held by m1")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(m1.Unlock())
+	select {
+	case err := <-locked:
+		require.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("m2 did not acquire the lock released by m1")
+	}
+	require.NoError(m2.Unlock())
+}
+
+func TestElection(t *testing.T) {
+	store, _, teardown := newStoreTest(t)
+	defer teardown()
+	require := require.New(t)
+
+	e1, err := store.Election("e")
+	require.NoError(err)
+	e2, err := store.Election("e")
+	require.NoError(err)
+
+	_, err = e1.Leader()
+	require.Equal(ErrNotFound, err)
+
+	require.NoError(e1.Campaign(context.Background(), []byte("one")))
+	leader, err := e2.Leader()
+	require.NoError(err)
+	require.Equal("one", string(leader))
+
+	elected := make(chan error)
+	go func() { elected <- e2.Campaign(context.Background(), []byte("two")) }()
+	require.NoError(e1.Resign())
+	require.NoError(<-elected)
+	leader, err = e1.Leader()
+	require.NoError(err)
+	require.Equal("two", string(leader))
+	require.NoError(e2.Resign())
+}
+
+func TestStat(t *testing.T) {
+	store, _, teardown := newStoreTest(t)
+	defer teardown()
+	require := require.New(t)
+
+	ident := Ident{Location: Location{Category: "widgets", Name: "w"}}
+	_, err := store.Stat(ident)
+	require.Equal(ErrNotFound, err)
+
+	before := time.Now().Add(-time.Minute)
+	_, err = store.Put(Item{Ident: ident, Data: []byte("hello")})
+	require.NoError(err)
+	_, err = store.Put(Item{Ident: Ident{Location: ident.Location, Variant: "v1"}, Data: []byte("v1")})
+	require.NoError(err)
+
+	meta, err := store.Stat(ident)
+	require.NoError(err)
+	require.Equal(5, meta.DataLength)
+	require.Equal(1, meta.NumVariants)
+	require.True(meta.Created.After(before), "unexpected creation time %v", meta.Created)
+	require.False(meta.Modified.Before(meta.Created))
+
+	item, err := store.Get(ident)
+	require.NoError(err)
+	require.Equal(meta, item.Meta)
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	store, conn, teardown := newStoreTest(t, OptChecksum())
+	defer teardown()
+	require := require.New(t)
+
+	ident := Ident{Location: Location{Category: "widgets", Name: "w"}}
+	_, err := store.Put(Item{Ident: ident, Data: []byte("hello")})
+	require.NoError(err)
+	item, err := store.Get(ident)
+	require.NoError(err)
+	require.Equal("hello", string(item.Data))
+
+	identPath, err := store.identPath(ident)
+	require.NoError(err)
+	raw, _, err := conn.Get(identPath)
+	require.NoError(err)
+	raw[len(raw)-1] ^= 1
+	_, err = conn.Set(identPath, raw, -1)
+	require.NoError(err)
+	_, err = store.Get(ident)
+	require.Equal(ErrChecksumMismatch, err)
+}
+
+func TestRebalance(t *testing.T) {
+	store, conn, teardown := newStoreTest(t, OptNumHashBuckets(4), OptChunking(2*MaxDataSize))
+	defer teardown()
+	require := require.New(t)
+
+	require.Equal(ErrNotFound, store.Rebalance("widgets", 7))
+	require.Error(store.Rebalance("widgets", 0))
+
+	items := map[Ident][]byte{}
+	for i := 0; i < 20; i++ {
+		location := Location{Category: "widgets", Name: fmt.Sprintf("w%d", i)}
+		items[Ident{Location: location}] = []byte(location.Name)
+		items[Ident{Location: location, Variant: "v1"}] = []byte(location.Name + "-v1")
+	}
+	big := Ident{Location: Location{Category: "widgets", Name: "big"}}
+	items[big] = bytes.Repeat([]byte("x"), MaxDataSize+1)
+	for ident, data := range items {
+		_, err := store.Put(Item{Ident: ident, Data: data})
+		require.NoError(err)
+	}
+
+	// an interrupted Rebalance may have left an identical copy of an item in
+	// its new bucket.
+	bucketsPath, err := store.bucketsPath("widgets")
+	require.NoError(err)
+	var name, from, to string
+	for i := 0; name == ""; i++ {
+		name = fmt.Sprintf("w%d", i)
+		oldBucket, err := store.bucketFunc(name)
+		require.NoError(err)
+		newBucket, err := bucketFunc(7, store.hashProviderFunc)(name)
+		require.NoError(err)
+		if oldBucket == newBucket {
+			name = ""
+		}
+		from, to = strconv.Itoa(oldBucket), strconv.Itoa(newBucket)
+	}
+	nodes, err := store.snapshotItem(path.Join(bucketsPath, from, name))
+	require.NoError(err)
+	require.NoError(store.createPath(path.Join(bucketsPath, to)))
+	_, err = store.createNodes(path.Join(bucketsPath, to, name), nodes)
+	require.NoError(err)
+
+	require.NoError(store.Rebalance("widgets", 7))
+
+	rebalanced, err := NewStore(ExistingConnection(conn), OptNumHashBuckets(7))
+	require.NoError(err)
+	for ident, data := range items {
+		item, err := rebalanced.Get(ident)
+		require.NoError(err, "%v", ident)
+		require.Equal(data, item.Data, "%v", ident)
+	}
+	locations, err := rebalanced.List("widgets")
+	require.NoError(err)
+	require.Len(locations, 21)
+
+	// rebalancing again is a no-op
+	require.NoError(rebalanced.Rebalance("widgets", 7))
+}
+
+func TestSubStore(t *testing.T) {
+	store, _, teardown := newStoreTest(t)
+	defer teardown()
+	require := require.New(t)
+
+	a, err := store.Sub("a")
+	require.NoError(err)
+	b, err := store.Sub("b")
+	require.NoError(err)
+
+	location := Location{Category: "widgets", Name: "w"}
+	_, err = a.Put(Item{Ident: Ident{Location: location}, Data: []byte("a")})
+	require.NoError(err)
+	_, err = b.Put(Item{Ident: Ident{Location: location}, Data: []byte("b")})
+	require.NoError(err)
+
+	item, err := a.Get(Ident{Location: location})
+	require.NoError(err)
+	require.Equal("a", string(item.Data))
+	locations, err := b.List("widgets")
+	require.NoError(err)
+	require.Equal([]Location{location}, locations)
+
+	// the parent store sees the prefixed categories
+	locations, err = store.List("a/widgets")
+	require.NoError(err)
+	require.Equal([]Location{{Category: "a/widgets", Name: "w"}}, locations)
+
+	// closing a sub leaves the connection usable
+	require.NoError(a.Close())
+	_, err = b.Get(Ident{Location: location})
+	require.NoError(err)
+}
+
 func newStoreTest(t *testing.T, storeOpts ...StoreOpt) (store *Store, zkConn *zk.Conn, teardown func()) {
 	zkCtl, err := testutils.StartZookeeper()
 	if err != nil {
@@ -0,0 +1,30 @@
+package zkstore
+
+import (
+	"path"
+	"strings"
+)
+
+// Sub returns a view of the Store in which all categories live below the
+// given prefix, so that several components can share a base path and ZK
+// connection without their categories colliding: Sub("a").Put() of an item
+// in category "widgets" stores it in category "a/widgets", and
+// Sub("a").List("widgets") lists the same items. Locks and elections are
+// scoped to the prefix as well.
+//
+// The returned Store shares the connection and configuration of s. Closing it
+// does not close the connection; only closing s does.
+func (s *Store) Sub(prefix string) (*Store, error) {
+	if err := ValidateCategory(prefix); err != nil {
+		return nil, err
+	}
+	for _, segment := range strings.Split(prefix, "/") {
+		if segment == s.bucketsZnodeName {
+			return nil, errBadCategory
+		}
+	}
+	sub := *s
+	sub.basePath = path.Join("/", s.basePath, prefix)
+	sub.closeFunc = func() error { return nil }
+	return &sub, nil
+}
@@ -0,0 +1,38 @@
+package zkstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSub(t *testing.T) {
+	require := require.New(t)
+	closed := false
+	store := &Store{
+		basePath:         "/storage",
+		bucketsZnodeName: DefaultBucketsZnodeName,
+		bucketFunc:       func(string) (int, error) { return 1, nil },
+		closeFunc:        func() error { closed = true; return nil },
+	}
+
+	sub, err := store.Sub("team/service")
+	require.NoError(err)
+	identPath, err := sub.identPath(Ident{Location: Location{Category: "widgets", Name: "w"}})
+	require.NoError(err)
+	require.Equal("/storage/team/service/widgets/buckets/1/w", identPath)
+
+	// subs nest
+	subsub, err := sub.Sub("x")
+	require.NoError(err)
+	require.Equal("/storage/team/service/x", subsub.basePath)
+
+	require.NoError(sub.Close())
+	require.False(closed, "closing a sub must not close the store")
+	require.Equal("/storage", store.basePath)
+
+	for _, prefix := range []string{"", "a/../b", "a/buckets/b", "with space"} {
+		_, err := store.Sub(prefix)
+		require.Error(err, prefix)
+	}
+}
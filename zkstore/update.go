@@ -0,0 +1,61 @@
+package zkstore
+
+import (
+	"time"
+)
+
+// UpdateFunc computes the new data of an item from its current data. old is
+// nil if the item does not exist yet (or holds no data). It may be invoked
+// several times by a single Update, and so should not have side effects.
+type UpdateFunc func(old []byte) ([]byte, error)
+
+var (
+	// updateMaxAttempts is the number of times Update tries to write an item
+	// before giving up on concurrent modifications.
+	updateMaxAttempts = 10
+
+	// updateBackoff is the delay before Update retries after a conflict. It
+	// doubles with every attempt.
+	updateBackoff = 10 * time.Millisecond
+)
+
+// Update performs a read-modify-write of the identified item: it reads the
+// item, passes its data to fn, and writes the result back, guarded by the
+// ZK version that was read. If the item was modified concurrently, Update
+// backs off and starts over. The Version of the given ident is ignored.
+//
+// If fn returns an error, Update returns that error without writing the
+// item. Returns ErrVersionConflict if the item could not be written after
+// several attempts.
+func (s *Store) Update(ident Ident, fn UpdateFunc) (Ident, error) {
+	backoff := updateBackoff
+	for attempt := 1; ; attempt++ {
+		ident, err := s.update(ident, fn)
+		if err != ErrVersionConflict || attempt == updateMaxAttempts {
+			return ident, err
+		}
+		s.logger.Debugf("zkstore: conflict updating %v, retrying in %v", ident, backoff)
+		s.metrics.Retry("update")
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// update makes a single read-modify-write attempt.
+func (s *Store) update(ident Ident, fn UpdateFunc) (Ident, error) {
+	ident.Version = Version{}
+	item, err := s.Get(ident)
+	switch {
+	case err == ErrNotFound:
+		item = Item{Ident: ident}
+		item.Version = NewVersion(NoPriorVersion)
+	case err != nil:
+		return ident, err
+	}
+	data, err := fn(item.Data)
+	if err != nil {
+		return ident, err
+	}
+	item.Data = data
+	return s.Put(item)
+}
@@ -0,0 +1,435 @@
+package zkstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// EventType describes the kind of change reported by an Event.
+type EventType int
+
+const (
+	// EventCreated is sent when an item is created.
+	EventCreated EventType = iota + 1
+
+	// EventUpdated is sent when the data of an item changes.
+	EventUpdated
+
+	// EventDeleted is sent when an item is deleted.
+	EventDeleted
+
+	// EventVariantsChanged is sent when a variant of an item is added or removed.
+	EventVariantsChanged
+)
+
+func (e EventType) String() string {
+	switch e {
+	case EventCreated:
+		return "created"
+	case EventUpdated:
+		return "updated"
+	case EventDeleted:
+		return "deleted"
+	case EventVariantsChanged:
+		return "variants-changed"
+	}
+	return fmt.Sprintf("unknown(%d)", int(e))
+}
+
+// Event describes a change observed by Watch or WatchCategory. Events do not
+// carry item data; clients should Get the item if they need it.
+type Event struct {
+	// Type is the kind of change, unset if Err is set.
+	Type EventType
+
+	// Location is the item that changed.
+	Location Location
+
+	// Err is set on the last event sent before the channel is closed if the
+	// watch could not be continued, e.g. because the Store was closed.
+	Err error
+}
+
+func (e Event) String() string {
+	if e.Err != nil {
+		return fmt.Sprintf("{err=%v}", e.Err)
+	}
+	return fmt.Sprintf("{type=%v loc=%v}", e.Type, e.Location)
+}
+
+// watchRetryInterval is how long a watcher waits before re-arming its ZK
+// watches after a transient error such as a lost connection.
+var watchRetryInterval = time.Second
+
+// Watch reports changes to the item at the given location: its creation,
+// deletion, data updates and changes to its set of variants. The item does not
+// need to exist when Watch is called.
+//
+// ZK watches are re-armed after every event, and re-established after the ZK
+// session expires. Changes that happen while watches are being re-armed are
+// detected by comparing the znode state, so intermediate updates may be
+// coalesced into a single event.
+//
+// The returned channel is closed when ctx is done. If the watch cannot be
+// continued, an Event with Err set is sent before the channel is closed.
+func (s *Store) Watch(ctx context.Context, location Location) (<-chan Event, error) {
+	if err := location.Validate(); err != nil {
+		return nil, err
+	}
+	identPath, err := s.identPath(Ident{Location: location})
+	if err != nil {
+		return nil, err
+	}
+	w := &itemWatcher{
+		store:    s,
+		location: location,
+		path:     identPath,
+		events:   make(chan Event),
+	}
+	if err := w.arm(true, true); err != nil {
+		return nil, err
+	}
+	go w.run(ctx)
+	return w.events, nil
+}
+
+// WatchCategory reports items being created in or deleted from the given
+// category. The category does not need to exist when WatchCategory is called.
+//
+// The re-arming, coalescing and closing behavior is the same as for Watch.
+func (s *Store) WatchCategory(ctx context.Context, category string) (<-chan Event, error) {
+	if err := ValidateCategory(category); err != nil {
+		return nil, err
+	}
+	bucketsPath, err := s.bucketsPath(category)
+	if err != nil {
+		return nil, err
+	}
+	w := &categoryWatcher{
+		store:    s,
+		category: category,
+		path:     bucketsPath,
+		events:   make(chan Event),
+		buckets:  make(map[string]*watchedBucket),
+	}
+	if err := w.rearmAll(); err != nil {
+		return nil, err
+	}
+	w.known = w.locations()
+	go w.run(ctx)
+	return w.events, nil
+}
+
+// send delivers the event unless ctx is done first.
+func send(ctx context.Context, events chan<- Event, event Event) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// isWatchLost returns true if the zk event signals that the watch was dropped
+// rather than triggered, e.g. because the session expired.
+func isWatchLost(event zk.Event) bool {
+	return event.Type == zk.EventNotWatching
+}
+
+// sleepContext waits for the duration, returning false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+type itemWatcher struct {
+	store    *Store
+	location Location
+	path     string
+	events   chan Event
+
+	exists   bool
+	version  int32
+	cversion int32
+	dataCh   <-chan zk.Event
+	childCh  <-chan zk.Event
+}
+
+// arm (re-)establishes the data and/or child watches and returns the events
+// implied by the difference to the previously observed state.
+func (w *itemWatcher) arm(data, children bool) error {
+	_, err := w.update(data, children)
+	return err
+}
+
+func (w *itemWatcher) update(data, children bool) (events []Event, err error) {
+	conn := w.store.conn
+	if data {
+		exists, stat, ch, err := conn.ExistsW(w.path)
+		if err != nil {
+			return nil, err
+		}
+		w.dataCh = ch
+		events = append(events, w.observe(exists, stat)...)
+		// a newly created item has no child watch yet.
+		children = children || (exists && w.childCh == nil)
+	}
+	if children {
+		_, stat, ch, err := conn.ChildrenW(w.path)
+		switch {
+		case err == zk.ErrNoNode:
+			w.childCh = nil
+			events = append(events, w.observe(false, nil)...)
+		case err != nil:
+			return events, err
+		default:
+			w.childCh = ch
+			events = append(events, w.observe(true, stat)...)
+		}
+	}
+	return events, nil
+}
+
+// observe records the znode state and returns the events for any change.
+func (w *itemWatcher) observe(exists bool, stat *zk.Stat) (events []Event) {
+	event := func(t EventType) Event { return Event{Type: t, Location: w.location} }
+	switch {
+	case exists && !w.exists:
+		events = append(events, event(EventCreated))
+	case !exists && w.exists:
+		events = append(events, event(EventDeleted))
+	case exists && stat.Version != w.version:
+		events = append(events, event(EventUpdated))
+	}
+	if exists && w.exists && stat.Cversion != w.cversion {
+		events = append(events, event(EventVariantsChanged))
+	}
+	w.exists = exists
+	if exists {
+		w.version = stat.Version
+		w.cversion = stat.Cversion
+	}
+	return events
+}
+
+func (w *itemWatcher) run(ctx context.Context) {
+	defer close(w.events)
+	for {
+		var data, children bool
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-w.dataCh:
+			data = true
+			children = isWatchLost(e)
+		case e := <-w.childCh:
+			children = true
+			data = isWatchLost(e)
+		}
+		for {
+			events, err := w.update(data, children)
+			for _, event := range events {
+				if !send(ctx, w.events, event) {
+					return
+				}
+			}
+			if err == nil {
+				break
+			}
+			if err == zk.ErrClosing {
+				send(ctx, w.events, Event{Location: w.location, Err: err})
+				return
+			}
+			w.store.logger.Warnf("zkstore: could not re-arm watch on %v: %v", w.path, err)
+			if !sleepContext(ctx, watchRetryInterval) {
+				return
+			}
+			data, children = true, true
+		}
+	}
+}
+
+type watchedBucket struct {
+	names []string
+	ch    <-chan zk.Event
+}
+
+type categoryWatcher struct {
+	store    *Store
+	category string
+	path     string
+	events   chan Event
+
+	rootCh  <-chan zk.Event
+	buckets map[string]*watchedBucket
+	known   map[Location]struct{}
+}
+
+// rearmAll drops all bucket state and watches the category from scratch.
+func (w *categoryWatcher) rearmAll() error {
+	w.buckets = make(map[string]*watchedBucket)
+	return w.armRoot()
+}
+
+// armRoot watches the buckets znode, and arms any bucket not watched yet.
+func (w *categoryWatcher) armRoot() error {
+	conn := w.store.conn
+	buckets, _, ch, err := conn.ChildrenW(w.path)
+	if err == zk.ErrNoNode {
+		// the category does not exist (anymore). wait for it to be created.
+		exists, _, ch, err := conn.ExistsW(w.path)
+		if err != nil {
+			return err
+		}
+		w.rootCh = ch
+		w.buckets = make(map[string]*watchedBucket)
+		if exists {
+			// created in the meantime; the exist watch fires on the
+			// next change, so look again right away.
+			return w.armRoot()
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	w.rootCh = ch
+	current := make(map[string]bool, len(buckets))
+	for _, bucket := range buckets {
+		current[bucket] = true
+		if _, ok := w.buckets[bucket]; ok {
+			continue
+		}
+		if err := w.armBucket(bucket); err != nil {
+			return err
+		}
+	}
+	for bucket := range w.buckets {
+		if !current[bucket] {
+			delete(w.buckets, bucket)
+		}
+	}
+	return nil
+}
+
+// armBucket watches the children of a single bucket.
+func (w *categoryWatcher) armBucket(bucket string) error {
+	names, _, ch, err := w.store.conn.ChildrenW(w.path + "/" + bucket)
+	switch {
+	case err == zk.ErrNoNode:
+		delete(w.buckets, bucket)
+		return nil
+	case err != nil:
+		return err
+	}
+	w.buckets[bucket] = &watchedBucket{names: names, ch: ch}
+	return nil
+}
+
+// locations returns the set of locations in all watched buckets.
+func (w *categoryWatcher) locations() map[Location]struct{} {
+	locations := make(map[Location]struct{})
+	for _, b := range w.buckets {
+		for _, name := range b.names {
+			locations[Location{Category: w.category, Name: name}] = struct{}{}
+		}
+	}
+	return locations
+}
+
+// next blocks until a watch fires or ctx is done, and returns the name of the
+// fired bucket, or "" for the buckets znode itself.
+func (w *categoryWatcher) next(ctx context.Context) (bucket string, event zk.Event, ok bool) {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(w.rootCh)},
+	}
+	names := []string{"", ""}
+	for name, b := range w.buckets {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(b.ch)})
+		names = append(names, name)
+	}
+	chosen, value, _ := reflect.Select(cases)
+	if chosen == 0 {
+		return "", zk.Event{}, false
+	}
+	if value.IsValid() {
+		event = value.Interface().(zk.Event)
+	}
+	return names[chosen], event, true
+}
+
+func (w *categoryWatcher) run(ctx context.Context) {
+	defer close(w.events)
+	for {
+		bucket, event, ok := w.next(ctx)
+		if !ok {
+			return
+		}
+		for {
+			var err error
+			switch {
+			case isWatchLost(event):
+				err = w.rearmAll()
+			case bucket == "":
+				err = w.armRoot()
+			default:
+				err = w.armBucket(bucket)
+			}
+			if err == nil {
+				break
+			}
+			if err == zk.ErrClosing {
+				send(ctx, w.events, Event{Location: Location{Category: w.category}, Err: err})
+				return
+			}
+			w.store.logger.Warnf("zkstore: could not re-arm watch on %v: %v", w.path, err)
+			if !sleepContext(ctx, watchRetryInterval) {
+				return
+			}
+			event = zk.Event{Type: zk.EventNotWatching}
+		}
+		current := w.locations()
+		created, deleted := diffLocations(w.known, current)
+		w.known = current
+		for _, l := range created {
+			if !send(ctx, w.events, Event{Type: EventCreated, Location: l}) {
+				return
+			}
+		}
+		for _, l := range deleted {
+			if !send(ctx, w.events, Event{Type: EventDeleted, Location: l}) {
+				return
+			}
+		}
+	}
+}
+
+// diffLocations returns the locations only found in cur (created) and the
+// locations only found in prev (deleted), each sorted by name.
+func diffLocations(prev, cur map[Location]struct{}) (created, deleted []Location) {
+	for l := range cur {
+		if _, ok := prev[l]; !ok {
+			created = append(created, l)
+		}
+	}
+	for l := range prev {
+		if _, ok := cur[l]; !ok {
+			deleted = append(deleted, l)
+		}
+	}
+	sort.Slice(LocationsByName(created))
+	sort.Slice(LocationsByName(deleted))
+	return created, deleted
+}
@@ -0,0 +1,58 @@
+package zkstore
+
+import (
+	"testing"
+
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLocations(t *testing.T) {
+	require := require.New(t)
+	loc := func(name string) Location { return Location{Category: "widgets", Name: name} }
+	set := func(locations ...Location) map[Location]struct{} {
+		m := make(map[Location]struct{})
+		for _, l := range locations {
+			m[l] = struct{}{}
+		}
+		return m
+	}
+
+	created, deleted := diffLocations(set(), set())
+	require.Empty(created)
+	require.Empty(deleted)
+
+	created, deleted = diffLocations(set(loc("a"), loc("b")), set(loc("b"), loc("d"), loc("c")))
+	require.Equal([]Location{loc("c"), loc("d")}, created)
+	require.Equal([]Location{loc("a")}, deleted)
+}
+
+func TestItemWatcherObserve(t *testing.T) {
+	require := require.New(t)
+	location := Location{Category: "widgets", Name: "a"}
+	w := &itemWatcher{location: location}
+	types := func(events []Event) (types []EventType) {
+		for _, e := range events {
+			require.Equal(location, e.Location)
+			types = append(types, e.Type)
+		}
+		return
+	}
+
+	require.Empty(w.observe(false, nil))
+	require.Equal([]EventType{EventCreated}, types(w.observe(true, &zk.Stat{})))
+	require.Empty(w.observe(true, &zk.Stat{}))
+	require.Equal([]EventType{EventUpdated}, types(w.observe(true, &zk.Stat{Version: 1})))
+	require.Equal([]EventType{EventVariantsChanged}, types(w.observe(true, &zk.Stat{Version: 1, Cversion: 1})))
+	require.Equal([]EventType{EventUpdated, EventVariantsChanged},
+		types(w.observe(true, &zk.Stat{Version: 2, Cversion: 2})))
+	require.Equal([]EventType{EventDeleted}, types(w.observe(false, nil)))
+	require.Equal([]EventType{EventCreated}, types(w.observe(true, &zk.Stat{})))
+}
+
+func TestEventTypeString(t *testing.T) {
+	require := require.New(t)
+	require.Equal("created", EventCreated.String())
+	require.Equal("variants-changed", EventVariantsChanged.String())
+	require.Equal("unknown(0)", EventType(0).String())
+}
@@ -0,0 +1,22 @@
+// Package zkcache caches the items of a zkstore category locally, keeping the
+// cache up to date with ZK watches.
+//
+// It is meant for small blobs, like configuration, that are shared
+// cluster-wide and read far more often than they are written:
+//
+//	cache, err := zkcache.New(store, "/config")
+//	...
+//	defer cache.Close()
+//
+//	data, err := cache.Get("feature-flags") // read from ZK once, then locally
+//
+// Items are read from ZK the first time they are requested, at which point a
+// watch is set on them. Whenever the watch reports a change, the cached copy
+// is dropped and the item is read again on the next Get.
+//
+// A Cache does not implement the store.Store interface of
+// github.com/dcos/dcos-go/store: zkstore items are raw bytes rather than
+// arbitrary values, reads and writes can fail with ZK errors that
+// Store.Get and Store.Set cannot report, and lookups by regexp, Objects or
+// Supplant would have to read or replace a whole category in ZK.
+package zkcache
@@ -0,0 +1,194 @@
+package zkcache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dcos/dcos-go/zkstore"
+	"github.com/pkg/errors"
+)
+
+// Backend is the part of a zkstore.Store used by a Cache.
+type Backend interface {
+	Get(ident zkstore.Ident) (zkstore.Item, error)
+	Put(item zkstore.Item) (zkstore.Ident, error)
+	Delete(ident zkstore.Ident) error
+	Watch(ctx context.Context, location zkstore.Location) (<-chan zkstore.Event, error)
+}
+
+// ensure that zkstore.Store can back a Cache.
+var _ Backend = &zkstore.Store{}
+
+// Cache is a read-through cache of the items of a single category. It is safe
+// for concurrent use.
+type Cache struct {
+	backend  Backend
+	category string
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry is a cached item along with the state of its watch.
+type entry struct {
+	data   []byte
+	loaded bool          // whether data is the current item data
+	gen    uint64        // incremented on every change reported by the watch
+	ready  chan struct{} // closed once the watch is set, or failed
+	err    error         // why the watch could not be set
+}
+
+// New returns a Cache of the items of the given category. Close must be
+// called once the Cache is no longer used to release its watches.
+func New(backend Backend, category string) (*Cache, error) {
+	if err := zkstore.ValidateCategory(category); err != nil {
+		return nil, errors.Wrap(err, "invalid category")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Cache{
+		backend:  backend,
+		category: category,
+		ctx:      ctx,
+		cancel:   cancel,
+		entries:  make(map[string]*entry),
+	}, nil
+}
+
+// Get returns the data of the item with the given name, reading it from ZK
+// unless it is cached. Returns zkstore.ErrNotFound if the item does not exist;
+// missing items are not cached.
+// The returned slice must not be modified.
+func (c *Cache) Get(name string) ([]byte, error) {
+	location := zkstore.Location{Category: c.category, Name: name}
+	if err := location.Validate(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if err := c.ctx.Err(); err != nil {
+		c.mu.Unlock()
+		return nil, errors.New("cache is closed")
+	}
+	e, ok := c.entries[name]
+	if ok && e.loaded {
+		c.mu.Unlock()
+		return e.data, nil
+	}
+	if !ok {
+		// a placeholder entry makes concurrent callers wait for the watch
+		// instead of setting their own.
+		e = &entry{ready: make(chan struct{})}
+		c.entries[name] = e
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.watch(name, location, e)
+	}
+	// the watch is set before the item is read, so that no change between
+	// the read and caching its result goes unnoticed.
+	<-e.ready
+	if e.err != nil {
+		return nil, e.err
+	}
+	c.mu.Lock()
+	gen := e.gen
+	c.mu.Unlock()
+
+	item, err := c.backend.Get(zkstore.Ident{Location: location})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.gen == gen && c.entries[name] == e {
+		e.data, e.loaded = item.Data, true
+	}
+	return item.Data, nil
+}
+
+// Set writes the data of the item with the given name to ZK. The cached copy
+// is refreshed on the next Get.
+func (c *Cache) Set(name string, data []byte) error {
+	item := zkstore.Item{
+		Ident: zkstore.Ident{Location: zkstore.Location{Category: c.category, Name: name}},
+		Data:  data,
+	}
+	if _, err := c.backend.Put(item); err != nil {
+		return err
+	}
+	c.drop(name)
+	return nil
+}
+
+// Delete deletes the item with the given name from ZK.
+func (c *Cache) Delete(name string) error {
+	ident := zkstore.Ident{Location: zkstore.Location{Category: c.category, Name: name}}
+	if err := c.backend.Delete(ident); err != nil {
+		return err
+	}
+	c.drop(name)
+	return nil
+}
+
+// Close releases all watches. The Cache cannot be used afterwards.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancel()
+	c.entries = make(map[string]*entry)
+	return nil
+}
+
+// watch sets the watch of the placeholder entry for the item, which happens
+// without holding the lock since it is a round trip to ZK. If the watch cannot
+// be set, the entry is removed so that the next Get tries again.
+func (c *Cache) watch(name string, location zkstore.Location, e *entry) {
+	events, err := c.backend.Watch(c.ctx, location)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer close(e.ready)
+	if err != nil {
+		e.err = errors.Wrapf(err, "could not watch %v", location)
+		if c.entries[name] == e {
+			delete(c.entries, name)
+		}
+		return
+	}
+	// if the Cache was closed in the meantime, the watch is canceled and
+	// invalidate returns once its channel is closed.
+	go c.invalidate(name, e, events)
+}
+
+// drop discards the cached copy of the item, if any.
+func (c *Cache) drop(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[name]; ok {
+		e.gen++
+		e.loaded = false
+	}
+}
+
+// invalidate discards the cached copy of the item whenever the watch reports
+// a change. If the watch fails, the entry is removed altogether so that the
+// next Get sets a new watch.
+func (c *Cache) invalidate(name string, e *entry, events <-chan zkstore.Event) {
+	for event := range events {
+		c.mu.Lock()
+		e.gen++
+		e.loaded = false
+		if event.Err != nil && c.entries[name] == e {
+			delete(c.entries, name)
+		}
+		c.mu.Unlock()
+	}
+	// the channel is closed once the Cache is closed, or after an error.
+	c.mu.Lock()
+	if c.entries[name] == e {
+		delete(c.entries, name)
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,191 @@
+package zkcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-go/zkstore"
+	"github.com/dcos/dcos-go/zkstore/memstore"
+	"github.com/stretchr/testify/require"
+)
+
+// testBackend is a memstore whose watches are triggered by the test.
+type testBackend struct {
+	*memstore.Store
+
+	mu      sync.Mutex
+	gets    int
+	watches map[string]chan zkstore.Event
+	calls   int           // number of calls to Watch
+	block   chan struct{} // if set, Watch waits until it is closed
+}
+
+func newTestBackend() *testBackend {
+	return &testBackend{Store: memstore.New(), watches: make(map[string]chan zkstore.Event)}
+}
+
+func (b *testBackend) Get(ident zkstore.Ident) (zkstore.Item, error) {
+	b.mu.Lock()
+	b.gets++
+	b.mu.Unlock()
+	return b.Store.Get(ident)
+}
+
+func (b *testBackend) Watch(ctx context.Context, location zkstore.Location) (<-chan zkstore.Event, error) {
+	b.mu.Lock()
+	b.calls++
+	block := b.block
+	b.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan zkstore.Event)
+	b.watches[location.Name] = ch
+	return ch, nil
+}
+
+func (b *testBackend) numGets() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.gets
+}
+
+// trigger sends an event on the watch of the named item.
+func (b *testBackend) trigger(name string, event zkstore.Event) {
+	b.mu.Lock()
+	ch := b.watches[name]
+	b.mu.Unlock()
+	ch <- event
+}
+
+// waitForEntry waits until the cached copy of the item is in the given state.
+func waitForEntry(t *testing.T, c *Cache, name string, loaded bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		e, ok := c.entries[name]
+		done := ok && e.loaded == loaded || !ok && !loaded
+		c.mu.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("entry %s did not become loaded=%v", name, loaded)
+}
+
+func TestCache(t *testing.T) {
+	require := require.New(t)
+	backend := newTestBackend()
+	cache, err := New(backend, "/config")
+	require.NoError(err)
+	defer cache.Close()
+
+	_, err = cache.Get("flags")
+	require.Equal(zkstore.ErrNotFound, err)
+
+	require.NoError(cache.Set("flags", []byte("v1")))
+	gets := backend.numGets()
+	for i := 0; i < 3; i++ {
+		data, err := cache.Get("flags")
+		require.NoError(err)
+		require.Equal("v1", string(data))
+	}
+	require.Equal(gets+1, backend.numGets(), "expected a single read from the backend")
+
+	// a change made by someone else is picked up once the watch fires.
+	_, err = backend.Put(zkstore.Item{
+		Ident: zkstore.Ident{Location: zkstore.Location{Category: "/config", Name: "flags"}},
+		Data:  []byte("v2"),
+	})
+	require.NoError(err)
+	backend.trigger("flags", zkstore.Event{Type: zkstore.EventUpdated})
+	waitForEntry(t, cache, "flags", false)
+	data, err := cache.Get("flags")
+	require.NoError(err)
+	require.Equal("v2", string(data))
+
+	require.NoError(cache.Delete("flags"))
+	_, err = cache.Get("flags")
+	require.Equal(zkstore.ErrNotFound, err)
+
+	// a failed watch drops the entry so that the next Get watches again.
+	require.NoError(cache.Set("flags", []byte("v3")))
+	_, err = cache.Get("flags")
+	require.NoError(err)
+	backend.trigger("flags", zkstore.Event{Err: zkstore.ErrNotFound})
+	waitForEntry(t, cache, "flags", false)
+	data, err = cache.Get("flags")
+	require.NoError(err)
+	require.Equal("v3", string(data))
+
+	_, err = cache.Get("invalid/name")
+	require.Error(err)
+	_, err = New(backend, "")
+	require.Error(err)
+
+	require.NoError(cache.Close())
+	_, err = cache.Get("flags")
+	require.Error(err)
+}
+
+func TestCacheConcurrentWatch(t *testing.T) {
+	require := require.New(t)
+	backend := newTestBackend()
+	cache, err := New(backend, "/config")
+	require.NoError(err)
+	defer cache.Close()
+	require.NoError(cache.Set("a", []byte("a")))
+	require.NoError(cache.Set("b", []byte("b")))
+	_, err = cache.Get("a")
+	require.NoError(err)
+
+	block := make(chan struct{})
+	backend.mu.Lock()
+	backend.block = block
+	backend.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := cache.Get("b")
+			require.NoError(err)
+			require.Equal("b", string(data))
+		}()
+	}
+
+	for {
+		backend.mu.Lock()
+		calls := backend.calls
+		backend.mu.Unlock()
+		if calls == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// cached items can be read while a watch is being set.
+	done := make(chan struct{})
+	go func() {
+		cache.Get("a")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		close(block)
+		t.Fatal("expected Get not to wait for the watch of another item")
+	}
+	close(block)
+	wg.Wait()
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	require.Equal(2, backend.calls, "expected a single watch per item")
+}